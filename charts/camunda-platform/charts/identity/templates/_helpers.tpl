@@ -233,3 +233,53 @@ https://docs.bitnami.com/kubernetes/apps/keycloak/configuration/manage-passwords
 {{- define "identity.keycloak.authExistingSecretKey" -}}
     {{- .Values.global.identity.keycloak.auth.existingSecretKey | default "admin-password" -}}
 {{- end -}}
+
+{{/*
+[identity] Get the database host of the external database.
+*/}}
+{{- define "identity.databaseHost" -}}
+{{- .Values.externalDatabase.host -}}
+{{- end -}}
+
+{{/*
+[identity] Get the database port of the external database.
+*/}}
+{{- define "identity.databasePort" -}}
+{{- .Values.externalDatabase.port -}}
+{{- end -}}
+
+{{/*
+[identity] Get the database name of the external database.
+*/}}
+{{- define "identity.databaseName" -}}
+{{- .Values.externalDatabase.database -}}
+{{- end -}}
+
+{{/*
+[identity] Get the database user of the external database.
+*/}}
+{{- define "identity.databaseUser" -}}
+{{- .Values.externalDatabase.user -}}
+{{- end -}}
+
+{{/*
+[identity] Get the name of the secret that contains the database password.
+*/}}
+{{- define "identity.databaseSecretName" -}}
+{{- .Values.externalDatabase.existingSecret -}}
+{{- end -}}
+
+{{/*
+[identity] Get the name of the database password key in the secret.
+*/}}
+{{- define "identity.databaseSecretKey" -}}
+{{- .Values.externalDatabase.existingSecretPasswordKey | default "password" -}}
+{{- end -}}
+
+{{/*
+[identity] Get the JDBC URL of the external database, including the SSL mode.
+*/}}
+{{- define "identity.databaseURL" -}}
+{{- printf "jdbc:postgresql://%s:%v/%s" (include "identity.databaseHost" .) (include "identity.databasePort" .) (include "identity.databaseName" .) -}}
+{{- with .Values.externalDatabase.sslMode }}?sslmode={{ . }}{{ end -}}
+{{- end -}}
@@ -0,0 +1,103 @@
+{{/*
+A template to handel constraints.
+*/}}
+
+{{/*
+Fail if "zeebe.clusterSize" of an installed release is changed. The brokers don't support changing the cluster size
+of an existing cluster, the partitions wouldn't be redistributed.
+The lookup finds nothing on "helm template" and on install, so only upgrades are checked.
+*/}}
+
+{{- $brokerStatefulSet := lookup "apps/v1" "StatefulSet" .Release.Namespace (include "zeebe.names.broker" . | trimAll "\"") }}
+{{- if and $brokerStatefulSet (ne (int $brokerStatefulSet.spec.replicas) (int .Values.clusterSize)) }}
+{{- $zeebeClusterSizeMessage := printf `
+[zeebe][constraint] The var "zeebe.clusterSize" can't be changed on upgrade, the release has %d brokers but %s are configured.
+Changing the cluster size of an existing Zeebe cluster is not supported.
+For more details, please check Camunda Platform Helm chart documentation.
+` (int $brokerStatefulSet.spec.replicas) (toString .Values.clusterSize) -}}
+    {{ printf "\n%s" $zeebeClusterSizeMessage | trimSuffix "\n"| fail }}
+{{- end }}
+
+{{/*
+Fail if the multi-region configuration is incomplete, the node ids of the release exceed the cluster size of all regions,
+the names of the exporters result in the same exporter id, or the network policies would block the other regions.
+*/}}
+
+{{- if .Values.multiRegion.enabled }}
+{{- if or (not .Values.multiRegion.clusterSize) (not .Values.multiRegion.initialContactPoints) }}
+{{- $zeebeMultiRegionMessage := `
+[zeebe][constraint] The vars "zeebe.multiRegion.clusterSize" and "zeebe.multiRegion.initialContactPoints" have to be set
+when "zeebe.multiRegion.enabled" is true.
+` -}}
+    {{ printf "\n%s" $zeebeMultiRegionMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- if gt (add (int .Values.multiRegion.nodeIdOffset) (int .Values.clusterSize)) (int .Values.multiRegion.clusterSize) }}
+{{- $zeebeNodeIdMessage := printf `
+[zeebe][constraint] The node ids %d to %d of the brokers exceed the var "zeebe.multiRegion.clusterSize" %s.
+The var "zeebe.multiRegion.nodeIdOffset" plus "zeebe.clusterSize" can't be greater than the cluster size of all regions.
+` (int .Values.multiRegion.nodeIdOffset) (sub (add (int .Values.multiRegion.nodeIdOffset) (int .Values.clusterSize)) 1) (toString .Values.multiRegion.clusterSize) -}}
+    {{ printf "\n%s" $zeebeNodeIdMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- $exporterIds := list }}
+{{- range .Values.multiRegion.exporters }}
+{{- $exporterIds = append $exporterIds (printf "elasticsearch%s" (regexReplaceAll "[^A-Z0-9]" (upper .name) "" | lower)) }}
+{{- end }}
+{{- if ne (len $exporterIds) (len (uniq $exporterIds)) }}
+{{- $zeebeExporterIdMessage := printf `
+[zeebe][constraint] The names of the var "zeebe.multiRegion.exporters" have to differ in their alphanumeric characters,
+since they form the exporter ids, but the exporter ids are %s.
+` (join ", " $exporterIds) -}}
+    {{ printf "\n%s" $zeebeExporterIdMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- if .Values.global.networkPolicy.enabled }}
+{{- $zeebeNetworkPolicyMessage := `
+[zeebe][constraint] The var "global.networkPolicy.enabled" can't be true together with "zeebe.multiRegion.enabled",
+since the network policies only allow the traffic within the release and would block the brokers of the other regions.
+` -}}
+    {{ printf "\n%s" $zeebeNetworkPolicyMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- end }}
+
+{{/*
+Fail if the zone awareness of the brokers would bind the permission to read the nodes to the default service account,
+which is shared with all pods of the namespace.
+*/}}
+
+{{- if and .Values.zoneAwareness.enabled (eq (include "zeebe.serviceAccountName" .) "default") }}
+{{- $zeebeZoneAwarenessMessage := `
+[zeebe][constraint] The broker service account has to be enabled or named when "zeebe.zoneAwareness.enabled" is true,
+since the service account gets the permission to read the nodes. Set "zeebe.serviceAccount.enabled: true" or "zeebe.serviceAccount.name".
+` -}}
+    {{ printf "\n%s" $zeebeZoneAwarenessMessage | trimSuffix "\n"| fail }}
+{{- end }}
+
+{{/*
+Fail if the backup store of the brokers is unknown or has no bucket, or if it's the GCS store, which Zeebe supports since 8.2.
+Tags without a version, like "SNAPSHOT", aren't checked.
+*/}}
+
+{{- with .Values.backup }}
+{{- if and .store (not (has .store (list "S3" "GCS"))) }}
+{{- $zeebeBackupStoreMessage := printf `
+[zeebe][constraint] The var "zeebe.backup.store" has to be empty, "S3" or "GCS", but it's "%s".
+` (toString .store) -}}
+    {{ printf "\n%s" $zeebeBackupStoreMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- if and .store (not (index . (lower .store) "bucketName")) }}
+{{- $zeebeBackupBucketMessage := printf `
+[zeebe][constraint] The var "zeebe.backup.%s.bucketName" has to be set when "zeebe.backup.store" is "%s".
+` (lower .store) .store -}}
+    {{ printf "\n%s" $zeebeBackupBucketMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- $zeebeImageTag := toString ($.Values.image.tag | default $.Values.global.image.tag) }}
+{{- $zeebeVersion := regexFind "^[0-9]+\\.[0-9]+" $zeebeImageTag }}
+{{- if and (eq (toString .store) "GCS") $zeebeVersion }}
+{{- if semverCompare "<8.2" (printf "%s.0" $zeebeVersion) }}
+{{- $zeebeBackupGcsMessage := printf `
+[zeebe][constraint] The var "zeebe.backup.store" can only be "GCS" with Zeebe 8.2 or later, but the image tag is "%s".
+Use the "S3" store or set "zeebe.image.tag" to 8.2 or later.
+` $zeebeImageTag -}}
+    {{ printf "\n%s" $zeebeBackupGcsMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- end }}
+{{- end }}
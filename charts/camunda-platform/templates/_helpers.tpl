@@ -125,11 +125,83 @@ Set imagePullSecrets according the values of global, subchart, or empty.
 {{- end -}}
 
 {{/*
-[camunda-platform] Keycloak issuer backend URL which used internally for Camunda apps.
+[camunda-platform] Issuer backend URL which used internally for Camunda apps.
 */}}
 
 {{- define "camundaPlatform.issuerBackendUrl" -}}
-    {{- include "camundaPlatform.keycloakURL" . -}}{{- .Values.global.identity.keycloak.realm -}}
+    {{- if .Values.global.identity.auth.issuerBackendUrl -}}
+        {{- .Values.global.identity.auth.issuerBackendUrl -}}
+    {{- else if eq .Values.global.identity.auth.type "GENERIC" -}}
+        {{- .Values.global.identity.auth.publicIssuerUrl -}}
+    {{- else -}}
+        {{- include "camundaPlatform.keycloakURL" . -}}{{- .Values.global.identity.keycloak.realm -}}
+    {{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] OpenID Connect discovery URL of the issuer, which is used by the Helm tests.
+*/}}
+
+{{- define "camundaPlatform.authDiscoveryUrl" -}}
+    {{- .Values.global.identity.auth.discoveryUrl | default (printf "%s/.well-known/openid-configuration" (include "camundaPlatform.issuerBackendUrl" . | trimSuffix "/")) -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Token endpoint of the issuer, where the Camunda apps request tokens for other apps.
+*/}}
+
+{{- define "camundaPlatform.authTokenUrl" -}}
+    {{- .Values.global.identity.auth.tokenUrl | default (printf "%s/protocol/openid-connect/token" (include "camundaPlatform.issuerBackendUrl" . | trimSuffix "/")) -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] JSON Web Key Set endpoint of the issuer, which is used to verify the tokens.
+*/}}
+
+{{- define "camundaPlatform.authJwksUrl" -}}
+    {{- .Values.global.identity.auth.jwksUrl | default (printf "%s/protocol/openid-connect/certs" (include "camundaPlatform.issuerBackendUrl" . | trimSuffix "/")) -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Client secret of a Camunda app, which is either an existing secret or the secret generated by Identity.
+Other apps need an existing secret object, which is enforced by the constraints.
+Usage: {{ include "camundaPlatform.authClientSecret" (dict "component" "operate" "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.authClientSecret" -}}
+{{- $auth := index .context.Values.global.identity.auth .component -}}
+{{- if and $auth.existingSecret (not (typeIs "string" $auth.existingSecret)) -}}
+valueFrom:
+  secretKeyRef:
+    {{- /*
+        Helper: https://github.com/bitnami/charts/blob/master/bitnami/common/templates/_secrets.tpl
+        Usage in keycloak secrets https://github.com/bitnami/charts/blob/master/bitnami/keycloak/templates/secrets.yaml
+        and in statefulset https://github.com/bitnami/charts/blob/master/bitnami/keycloak/templates/statefulset.yaml
+    */}}
+    name: {{ include "common.secrets.name" (dict "existingSecret" $auth.existingSecret "context" .context) }}
+    key: {{ $auth.existingSecretKey | default (printf "%s-secret" .component) }}
+{{- else if has .component (list "operate" "tasklist" "optimize") -}}
+valueFrom:
+  secretKeyRef:
+    name: {{ include (printf "identity.secretName%sIdentity" (title .component)) .context }}
+    key: {{ printf "%s-secret" .component }}
+{{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Environment of the Zeebe client of a Camunda app, which requests tokens for the Zeebe Gateway.
+Usage: {{ include "camundaPlatform.zeebeClientAuthEnv" (dict "component" "operate" "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.zeebeClientAuthEnv" -}}
+- name: ZEEBE_CLIENT_ID
+  value: {{ (index .context.Values.global.identity.auth .component).clientId | quote }}
+- name: ZEEBE_CLIENT_SECRET
+  {{- include "camundaPlatform.authClientSecret" . | nindent 2 }}
+- name: ZEEBE_AUTHORIZATION_SERVER_URL
+  value: {{ include "camundaPlatform.authTokenUrl" .context | quote }}
+- name: ZEEBE_TOKEN_AUDIENCE
+  value: {{ .context.Values.global.identity.auth.zeebe.audience | quote }}
 {{- end -}}
 
 {{/*
@@ -143,3 +215,295 @@ Set imagePullSecrets according the values of global, subchart, or empty.
         {{ .Values.global.elasticsearch.protocol }}://{{ .Values.global.elasticsearch.host }}:{{ .Values.global.elasticsearch.port }}
     {{- end -}}
 {{- end -}}
+
+{{/*
+[camunda-platform] Environment variables of the Elasticsearch credentials, the password from the existing secret is set
+for every given variable name.
+Usage: {{ include "camundaPlatform.elasticsearchEnv" (dict "passwordNames" (list "CAMUNDA_OPERATE_ELASTICSEARCH_PASSWORD") "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.elasticsearchEnv" -}}
+{{- $auth := .context.Values.global.elasticsearch.auth -}}
+{{- $env := list -}}
+{{- if and $auth.username $auth.existingSecret (not .context.Values.global.opensearch.enabled) -}}
+{{- range .passwordNames -}}
+{{- $env = append $env (dict "name" . "valueFrom" (dict "secretKeyRef" (dict "name" $auth.existingSecret "key" $auth.existingSecretKey))) -}}
+{{- end -}}
+{{- end -}}
+{{- if $env -}}
+{{ toYaml $env }}
+{{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Returns "true" if the CA certificate of Elasticsearch is configured, which is mounted into every component
+accessing Elasticsearch.
+*/}}
+
+{{- define "camundaPlatform.elasticsearchCAEnabled" -}}
+{{- $tls := .Values.global.elasticsearch.tls -}}
+{{- if and (or $tls.existingSecret $tls.existingConfigMap) (not .Values.global.opensearch.enabled) -}}
+true
+{{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Path of the mounted CA certificate of Elasticsearch.
+*/}}
+
+{{- define "camundaPlatform.elasticsearchCAPath" -}}
+/usr/local/share/elasticsearch-ca/ca.crt
+{{- end -}}
+
+{{/*
+[camunda-platform] Path of the Java truststore, which contains the default CA certificates of the JVM and the CA certificate
+of Elasticsearch. It's created by the init container "camundaPlatform.elasticsearchTruststoreInitContainer".
+*/}}
+
+{{- define "camundaPlatform.elasticsearchTruststorePath" -}}
+/usr/local/share/elasticsearch-truststore/cacerts
+{{- end -}}
+
+{{/*
+[camunda-platform] Java options to use the truststore with the CA certificate of Elasticsearch, for components which
+don't support a certificate path in their configuration.
+*/}}
+
+{{- define "camundaPlatform.elasticsearchTruststoreJavaOpts" -}}
+-Djavax.net.ssl.trustStore={{ include "camundaPlatform.elasticsearchTruststorePath" . }} -Djavax.net.ssl.trustStorePassword=changeit
+{{- end -}}
+
+{{/*
+[camunda-platform] Volumes with the CA certificate of Elasticsearch, and the truststore if "truststore" is true.
+Usage: {{ include "camundaPlatform.elasticsearchCAVolumes" (dict "truststore" true "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.elasticsearchCAVolumes" -}}
+{{- if include "camundaPlatform.elasticsearchCAEnabled" .context -}}
+{{- $tls := .context.Values.global.elasticsearch.tls -}}
+- name: elasticsearch-ca
+  {{- if $tls.existingSecret }}
+  secret:
+    secretName: {{ $tls.existingSecret }}
+  {{- else }}
+  configMap:
+    name: {{ $tls.existingConfigMap }}
+  {{- end }}
+    items:
+      - key: {{ $tls.caKey }}
+        path: ca.crt
+{{- if .truststore }}
+- name: elasticsearch-truststore
+  emptyDir: {}
+{{- end }}
+{{- end }}
+{{- end -}}
+
+{{/*
+[camunda-platform] Volume mounts of the CA certificate of Elasticsearch, and the truststore if "truststore" is true.
+Usage: {{ include "camundaPlatform.elasticsearchCAVolumeMounts" (dict "truststore" true "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.elasticsearchCAVolumeMounts" -}}
+{{- if include "camundaPlatform.elasticsearchCAEnabled" .context -}}
+- name: elasticsearch-ca
+  mountPath: {{ include "camundaPlatform.elasticsearchCAPath" .context | dir }}
+  readOnly: true
+{{- if .truststore }}
+- name: elasticsearch-truststore
+  mountPath: {{ include "camundaPlatform.elasticsearchTruststorePath" .context | dir }}
+{{- end }}
+{{- end }}
+{{- end -}}
+
+{{/*
+[camunda-platform] Init container, which copies the default truststore of the JVM of the given image and imports the CA
+certificate of Elasticsearch into it.
+Usage: {{ include "camundaPlatform.elasticsearchTruststoreInitContainer" (dict "image" (include "camundaPlatform.image" .) "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.elasticsearchTruststoreInitContainer" -}}
+{{- if include "camundaPlatform.elasticsearchCAEnabled" .context -}}
+- name: elasticsearch-truststore
+  image: {{ .image | quote }}
+  imagePullPolicy: {{ .context.Values.global.image.pullPolicy }}
+  command: ["sh", "-c"]
+  args:
+    - >-
+      JAVA_HOME="${JAVA_HOME:-$(dirname "$(dirname "$(readlink -f "$(command -v java)")")")}" &&
+      cp "${JAVA_HOME}/lib/security/cacerts" {{ include "camundaPlatform.elasticsearchTruststorePath" .context }} &&
+      chmod u+w {{ include "camundaPlatform.elasticsearchTruststorePath" .context }} &&
+      keytool -importcert -noprompt -alias elasticsearch-ca
+      -file {{ include "camundaPlatform.elasticsearchCAPath" .context }}
+      -keystore {{ include "camundaPlatform.elasticsearchTruststorePath" .context }} -storepass changeit
+  volumeMounts:
+    {{- include "camundaPlatform.elasticsearchCAVolumeMounts" (dict "truststore" true "context" .context) | nindent 4 }}
+{{- end }}
+{{- end -}}
+
+{{/*
+[camunda-platform] Returns "true" if the "helm test" hooks can check the index templates in Elasticsearch. The test runner
+uses neither credentials nor a custom CA, so only an Elasticsearch without authentication and custom CA is checked.
+*/}}
+
+{{- define "camundaPlatform.elasticsearchTestable" -}}
+{{- if not (or .Values.global.opensearch.enabled .Values.global.elasticsearch.auth.username (include "camundaPlatform.elasticsearchCAEnabled" .)) -}}
+true
+{{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] OpenSearch URL, which is used instead of Elasticsearch if "global.opensearch.enabled" is true.
+*/}}
+
+{{- define "camundaPlatform.opensearchURL" -}}
+    {{- if .Values.global.opensearch.url -}}
+        {{- .Values.global.opensearch.url -}}
+    {{- else -}}
+        {{ .Values.global.opensearch.protocol }}://{{ .Values.global.opensearch.host }}:{{ .Values.global.opensearch.port }}
+    {{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Environment variables of the OpenSearch credentials, the password from the existing secret is set
+for every given variable name. The AWS region is set for the AWS SDK, which signs the requests if AWS is enabled.
+Usage: {{ include "camundaPlatform.opensearchEnv" (dict "passwordNames" (list "CAMUNDA_OPERATE_OPENSEARCH_PASSWORD") "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.opensearchEnv" -}}
+{{- $opensearch := .context.Values.global.opensearch -}}
+{{- $env := list -}}
+{{- if and $opensearch.auth.username $opensearch.auth.existingSecret -}}
+{{- range .passwordNames -}}
+{{- $env = append $env (dict "name" . "valueFrom" (dict "secretKeyRef" (dict "name" $opensearch.auth.existingSecret "key" $opensearch.auth.existingSecretKey))) -}}
+{{- end -}}
+{{- end -}}
+{{- if and $opensearch.aws.enabled $opensearch.aws.region -}}
+{{- $env = append $env (dict "name" "AWS_REGION" "value" $opensearch.aws.region) -}}
+{{- end -}}
+{{- if $env -}}
+{{ toYaml $env }}
+{{- end -}}
+{{- end -}}
+
+{{/*
+[camunda-platform] Index families of the retention policy by name, with their index prefix, the pattern of the indices to delete
+and the TTL in days. Zeebe uses the prefix of its exporter and all of its indices are dated. Operate and Tasklist use fixed
+prefixes and only their archived indices are deleted, which have a date suffix.
+*/}}
+
+{{- define "camundaPlatform.retentionIndexFamilies" -}}
+{{- $families := dict -}}
+{{- if and .Values.zeebe.enabled (or .Values.global.opensearch.enabled (not .Values.global.elasticsearch.disableExporter)) -}}
+{{- $prefix := ternary .Values.global.opensearch.prefix .Values.global.elasticsearch.prefix (eq (toString .Values.global.opensearch.enabled) "true") -}}
+{{- $_ := set $families "zeebe" (dict "prefix" $prefix "pattern" (printf "%s*" $prefix) "archivedOnly" false "ttl" (int .Values.retentionPolicy.zeebeIndexTTL)) -}}
+{{- end -}}
+{{- if .Values.operate.enabled -}}
+{{- $_ := set $families "operate" (dict "prefix" "operate-" "pattern" "operate-*_2*" "archivedOnly" true "ttl" (int .Values.retentionPolicy.operateIndexTTL)) -}}
+{{- end -}}
+{{- if .Values.tasklist.enabled -}}
+{{- $_ := set $families "tasklist" (dict "prefix" "tasklist-" "pattern" "tasklist-*_2*" "archivedOnly" true "ttl" (int .Values.retentionPolicy.tasklistIndexTTL)) -}}
+{{- end -}}
+{{ toYaml $families }}
+{{- end -}}
+
+{{/*
+[camunda-platform] Pod spec of a "helm test" hook, which runs the test runner with the checks of the component.
+Without the test runner, the hook only requests the "wget" address of the component.
+Usage: {{ include "camundaPlatform.testRunnerPodSpec" (dict "args" (list "-health=http://operate:80/ready") "wget" "operate:80" "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.testRunnerPodSpec" -}}
+{{- $global := .context.Values.global -}}
+containers:
+{{- if $global.testRunner.enabled }}
+  - name: test-runner
+    image: {{ include "camundaPlatform.imageByParams" (dict "base" $global "overlay" $global.testRunner) }}
+    imagePullPolicy: {{ $global.image.pullPolicy }}
+    args:
+      - {{ printf "-timeout=%s" $global.testRunner.timeout | quote }}
+      {{- range .args }}
+      - {{ . | quote }}
+      {{- end }}
+{{- if $global.image.pullSecrets }}
+imagePullSecrets:
+  {{- toYaml $global.image.pullSecrets | nindent 2 }}
+{{- end }}
+{{- else }}
+  - name: wget
+    image: busybox
+    command: ['wget']
+    args: [{{ .wget | squote }}]
+{{- end }}
+restartPolicy: Never
+{{- end -}}
+
+{{/*
+[camunda-platform] Topology spread constraints of a component pod spec. With the zone awareness of the component, a
+constraint is added which spreads the pods evenly across the zones, given by the node label "topologyKey".
+Usage: {{ include "camundaPlatform.topologySpreadConstraints" (dict "constraints" .Values.topologySpreadConstraints "zoneAwareness" .Values.zoneAwareness "matchLabels" (include "operate.matchLabels" .) "context" $) }}
+*/}}
+
+{{- define "camundaPlatform.topologySpreadConstraints" -}}
+{{- $constraints := .constraints | default list -}}
+{{- if .zoneAwareness.enabled -}}
+{{- $zoneConstraint := dict
+    "maxSkew" 1
+    "topologyKey" .zoneAwareness.topologyKey
+    "whenUnsatisfiable" .zoneAwareness.whenUnsatisfiable
+    "labelSelector" (dict "matchLabels" (fromYaml .matchLabels))
+-}}
+{{- $constraints = append $constraints $zoneConstraint -}}
+{{- end -}}
+topologySpreadConstraints:
+  {{- tpl (toYaml $constraints) .context | nindent 2 }}
+{{- end -}}
+
+{{/*
+[camunda-platform] HorizontalPodAutoscaler of a component deployment, configured by the autoscaling values of the component.
+Usage: {{ include "camundaPlatform.horizontalPodAutoscaler" (dict "name" (include "operate.fullname" .) "labels" (include "operate.labels" .) "autoscaling" .Values.autoscaling "component" "operate") }}
+*/}}
+
+{{- define "camundaPlatform.horizontalPodAutoscaler" -}}
+{{- if gt (int .autoscaling.minReplicas) (int .autoscaling.maxReplicas) -}}
+    {{- $errorMessage := printf "[%s][constraint] The autoscaling minReplicas (%v) can't be greater than the maxReplicas (%v)" .component .autoscaling.minReplicas .autoscaling.maxReplicas -}}
+    {{ printf "\n%s" $errorMessage | trimSuffix "\n" | fail }}
+{{- end -}}
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ .name }}
+  labels: {{- .labels | nindent 4 }}
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: {{ .name }}
+  minReplicas: {{ .autoscaling.minReplicas }}
+  maxReplicas: {{ .autoscaling.maxReplicas }}
+  {{- if or .autoscaling.targetCPUUtilizationPercentage .autoscaling.targetMemoryUtilizationPercentage .autoscaling.metrics }}
+  metrics:
+    {{- with .autoscaling.targetCPUUtilizationPercentage }}
+    - type: Resource
+      resource:
+        name: cpu
+        target:
+          type: Utilization
+          averageUtilization: {{ . }}
+    {{- end }}
+    {{- with .autoscaling.targetMemoryUtilizationPercentage }}
+    - type: Resource
+      resource:
+        name: memory
+        target:
+          type: Utilization
+          averageUtilization: {{ . }}
+    {{- end }}
+    {{- with .autoscaling.metrics }}
+    {{- toYaml . | nindent 4 }}
+    {{- end }}
+  {{- end }}
+  {{- with .autoscaling.behavior }}
+  behavior: {{- toYaml . | nindent 4 }}
+  {{- end }}
+{{- end -}}
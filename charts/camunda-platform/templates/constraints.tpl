@@ -0,0 +1,205 @@
+{{/*
+A template to handel constraints.
+*/}}
+
+{{/*
+Fail if OpenSearch is enabled together with Elasticsearch specifics. Helm can't disable the Elasticsearch dependency
+based on another value, the curator of the retention policy only supports Elasticsearch, the setup job of the
+"ilm" retention mode doesn't sign its requests for AWS, and Optimize only supports the host and port of OpenSearch.
+*/}}
+
+{{- if .Values.global.opensearch.enabled }}
+{{- $opensearchMessages := list }}
+{{- if .Values.elasticsearch.enabled }}
+{{- $opensearchMessages = append $opensearchMessages `
+[camunda][constraint] The Elasticsearch dependency can't be enabled together with "global.opensearch.enabled".
+Set "elasticsearch.enabled: false" to use OpenSearch.` }}
+{{- end }}
+{{- if and .Values.retentionPolicy.enabled (eq .Values.retentionPolicy.mode "curator") }}
+{{- $opensearchMessages = append $opensearchMessages `
+[camunda][constraint] The retention policy can't be enabled together with "global.opensearch.enabled",
+since its curator only supports Elasticsearch. Set "retentionPolicy.mode: ilm" to use OpenSearch.` }}
+{{- end }}
+{{- if and .Values.retentionPolicy.enabled (eq .Values.retentionPolicy.mode "ilm") .Values.global.opensearch.aws.enabled }}
+{{- $opensearchMessages = append $opensearchMessages `
+[camunda][constraint] The retention policy can't be enabled together with "global.opensearch.aws.enabled",
+since its setup job doesn't sign its requests. Create the ISM policies in the OpenSearch domain instead.` }}
+{{- end }}
+{{- if not (or .Values.global.opensearch.url .Values.global.opensearch.host) }}
+{{- $opensearchMessages = append $opensearchMessages `
+[camunda][constraint] The var "global.opensearch.host" or "global.opensearch.url" has to be set to use OpenSearch.` }}
+{{- else if and .Values.optimize.enabled (not .Values.global.opensearch.host) }}
+{{- $opensearchMessages = append $opensearchMessages `
+[camunda][constraint] The var "global.opensearch.host" has to be set when Optimize is enabled,
+since Optimize connects to OpenSearch by host and port and doesn't support "global.opensearch.url".` }}
+{{- end }}
+{{- if and .Values.global.opensearch.auth.username (not .Values.global.opensearch.auth.existingSecret) }}
+{{- $opensearchMessages = append $opensearchMessages `
+[camunda][constraint] The var "global.opensearch.auth.existingSecret" has to be set together with
+"global.opensearch.auth.username", it contains the password of the user.` }}
+{{- end }}
+{{- if $opensearchMessages }}
+{{- $opensearchMessage := printf "%s\nFor more details, please check Camunda Platform Helm chart documentation.\n" (join "\n" $opensearchMessages) -}}
+    {{ printf "\n%s" $opensearchMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- end }}
+
+{{/*
+Fail if the credentials or the CA certificate of Elasticsearch are incomplete.
+*/}}
+
+{{- $elasticsearchMessages := list }}
+{{- if and .Values.global.elasticsearch.auth.username (not .Values.global.elasticsearch.auth.existingSecret) }}
+{{- $elasticsearchMessages = append $elasticsearchMessages `
+[camunda][constraint] The var "global.elasticsearch.auth.existingSecret" has to be set together with
+"global.elasticsearch.auth.username", it contains the password of the user.` }}
+{{- end }}
+{{- if and .Values.global.elasticsearch.tls.existingSecret .Values.global.elasticsearch.tls.existingConfigMap }}
+{{- $elasticsearchMessages = append $elasticsearchMessages `
+[camunda][constraint] Only one of the vars "global.elasticsearch.tls.existingSecret" and
+"global.elasticsearch.tls.existingConfigMap" can be set, both contain the CA certificate of Elasticsearch.` }}
+{{- end }}
+{{- if $elasticsearchMessages }}
+{{- $elasticsearchMessage := printf "%s\nFor more details, please check Camunda Platform Helm chart documentation.\n" (join "\n" $elasticsearchMessages) -}}
+    {{ printf "\n%s" $elasticsearchMessage | trimSuffix "\n"| fail }}
+{{- end }}
+
+{{/*
+Fail if the mode of the retention policy is unknown.
+*/}}
+
+{{- if and .Values.retentionPolicy.enabled (not (has .Values.retentionPolicy.mode (list "curator" "ilm"))) }}
+{{- $retentionPolicyMessage := printf `
+[camunda][constraint] The var "retentionPolicy.mode" has to be "curator" or "ilm", but it's "%s".
+` (toString .Values.retentionPolicy.mode) -}}
+    {{ printf "\n%s" $retentionPolicyMessage | trimSuffix "\n"| fail }}
+{{- end }}
+
+{{/*
+Fail if the backup cronjob is enabled without the backup configuration of the components, or with a search engine its
+requests can't be signed for.
+*/}}
+
+{{- if .Values.backup.enabled }}
+{{- $backupMessages := list }}
+{{- if not (and .Values.zeebe.enabled .Values.zeebe.backup.store) }}
+{{- $backupMessages = append $backupMessages `
+[camunda][constraint] The var "zeebe.backup.store" has to be set together with "backup.enabled",
+the backup cronjob takes the backups of Zeebe in its backup store.` }}
+{{- end }}
+{{- if not .Values.global.backup.repositoryName }}
+{{- $backupMessages = append $backupMessages `
+[camunda][constraint] The var "global.backup.repositoryName" has to be set together with "backup.enabled",
+the backup cronjob and the web applications take their snapshots in this repository.` }}
+{{- end }}
+{{- if and .Values.global.opensearch.enabled .Values.global.opensearch.aws.enabled }}
+{{- $backupMessages = append $backupMessages `
+[camunda][constraint] The backup cronjob can't be enabled together with "global.opensearch.aws.enabled",
+since it doesn't sign its requests.` }}
+{{- end }}
+{{- if $backupMessages }}
+{{- $backupMessage := printf "%s\nFor more details, please check Camunda Platform Helm chart documentation.\n" (join "\n" $backupMessages) -}}
+    {{ printf "\n%s" $backupMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- end }}
+
+{{/*
+Fail if the OpenID Connect provider is incomplete. Identity only generates the client secrets of the Keycloak clients,
+so a generic provider and the client of Connectors need existing secrets, otherwise their secret references are empty.
+*/}}
+
+{{- if .Values.global.identity.auth.enabled }}
+{{- $auth := .Values.global.identity.auth }}
+{{- $authMessages := list }}
+{{- if not (has $auth.type (list "KEYCLOAK" "GENERIC")) }}
+{{- $authMessages = append $authMessages (printf `
+[camunda][constraint] The var "global.identity.auth.type" has to be "KEYCLOAK" or "GENERIC", but it's "%s".` (toString $auth.type)) }}
+{{- end }}
+{{- if eq $auth.type "GENERIC" }}
+{{- if and .Values.identity.enabled .Values.identity.keycloak.enabled }}
+{{- $authMessages = append $authMessages `
+[camunda][constraint] The Keycloak dependency can't be enabled together with "global.identity.auth.type: GENERIC".
+Set "identity.keycloak.enabled: false" to use a generic OpenID Connect provider.` }}
+{{- end }}
+{{- if not (and $auth.tokenUrl $auth.jwksUrl) }}
+{{- $authMessages = append $authMessages `
+[camunda][constraint] The vars "global.identity.auth.tokenUrl" and "global.identity.auth.jwksUrl" have to be set
+to use a generic OpenID Connect provider.` }}
+{{- end }}
+{{- end }}
+{{- $clients := list }}
+{{- range $component := list "operate" "tasklist" "optimize" }}
+{{- if (index $.Values $component).enabled }}
+{{- $clients = append $clients $component }}
+{{- end }}
+{{- end }}
+{{- if and $auth.zeebe.enabled .Values.connectors.enabled }}
+{{- $clients = append $clients "connectors" }}
+{{- end }}
+{{- range $component := $clients }}
+{{- $existingSecret := (index $auth $component).existingSecret }}
+{{- $generated := and (eq $auth.type "KEYCLOAK") (has $component (list "operate" "tasklist" "optimize")) }}
+{{- if not (or $generated (and $existingSecret (not (typeIs "string" $existingSecret)))) }}
+{{- $authMessages = append $authMessages (printf `
+[camunda][constraint] The var "global.identity.auth.%s.existingSecret" has to reference a secret object,
+it contains the client secret of %s. Identity only generates the client secrets of Operate, Tasklist and Optimize for Keycloak.` $component $component) }}
+{{- end }}
+{{- end }}
+{{- if $authMessages }}
+{{- $authMessage := printf "%s\nFor more details, please check Camunda Platform Helm chart documentation.\n" (join "\n" $authMessages) -}}
+    {{ printf "\n%s" $authMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- end }}
+
+{{/*
+Fail if an external database of Identity or Keycloak is incomplete. Helm can't disable the PostgreSQL dependency of
+Keycloak based on another value, so the external database of Keycloak requires disabling it explicitly.
+*/}}
+
+{{- if .Values.identity.enabled }}
+{{- $databaseMessages := list }}
+{{- if and .Values.identity.externalDatabase.enabled (not (and .Values.identity.externalDatabase.host .Values.identity.externalDatabase.existingSecret)) }}
+{{- $databaseMessages = append $databaseMessages `
+[camunda][constraint] The vars "identity.externalDatabase.host" and "identity.externalDatabase.existingSecret" have to be set
+together with "identity.externalDatabase.enabled", the secret contains the password of the database user.` }}
+{{- end }}
+{{- if and .Values.identity.keycloak.enabled .Values.identity.keycloak.externalDatabase.host .Values.identity.keycloak.postgresql.enabled }}
+{{- $databaseMessages = append $databaseMessages `
+[camunda][constraint] The PostgreSQL dependency of Keycloak can't be enabled together with "identity.keycloak.externalDatabase.host".
+Set "identity.keycloak.postgresql.enabled: false" to use the external database.` }}
+{{- end }}
+{{- if $databaseMessages }}
+{{- $databaseMessage := printf "%s\nFor more details, please check Camunda Platform Helm chart documentation.\n" (join "\n" $databaseMessages) -}}
+    {{ printf "\n%s" $databaseMessage | trimSuffix "\n"| fail }}
+{{- end }}
+{{- end }}
+
+{{/*
+Fail if Operate, Tasklist or Optimize is autoscaled while it imports the data. Every replica runs the importer (and the
+archiver of Operate and Tasklist), so the autoscaled deployment has to disable them and leave them to another instance.
+*/}}
+
+{{- $autoscalingMessages := list }}
+{{- range $component, $variables := dict
+    "operate" (list "CAMUNDA_OPERATE_IMPORTERENABLED" "CAMUNDA_OPERATE_ARCHIVERENABLED")
+    "tasklist" (list "CAMUNDA_TASKLIST_IMPORTERENABLED" "CAMUNDA_TASKLIST_ARCHIVERENABLED")
+    "optimize" (list "CAMUNDA_OPTIMIZE_ZEEBE_ENABLED") }}
+{{- $values := index $.Values $component }}
+{{- if and $values.enabled $values.autoscaling.enabled }}
+{{- $disabled := list }}
+{{- range $values.env }}
+{{- if and (has .name $variables) (eq (toString .value) "false") }}
+{{- $disabled = append $disabled .name }}
+{{- end }}
+{{- end }}
+{{- if lt (len (uniq $disabled)) (len $variables) }}
+{{- $autoscalingMessages = append $autoscalingMessages (printf `
+[camunda][constraint] The var "%s.autoscaling.enabled" requires "%s" set to "false" in "%s.env",
+since every replica would import the data. The data has to be imported by another instance, which isn't autoscaled.` $component (join `" and "` $variables) $component) }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $autoscalingMessages }}
+{{- $autoscalingMessage := printf "%s\nFor more details, please check Camunda Platform Helm chart documentation.\n" (join "\n" $autoscalingMessages) -}}
+    {{ printf "\n%s" $autoscalingMessage | trimSuffix "\n"| fail }}
+{{- end }}
@@ -0,0 +1,212 @@
+package test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+)
+
+// autoscaledComponent is a deployment, whose replicas can be managed by a HorizontalPodAutoscaler.
+type autoscaledComponent struct {
+	// name is the component in the constraint messages
+	name string
+	// values is the prefix of the autoscaling values of the component
+	values     string
+	template   string
+	deployment string
+	// fullname is the name of the deployment and its HorizontalPodAutoscaler
+	fullname string
+	enabled  map[string]string
+	// env are the string values of the environment, which Operate, Tasklist and Optimize require to be autoscaled
+	env map[string]string
+}
+
+var autoscaledComponents = []autoscaledComponent{
+	{
+		name:       "zeebe-gateway",
+		values:     "zeebe-gateway.autoscaling.",
+		template:   "charts/zeebe-gateway/templates/gateway-horizontalpodautoscaler.yaml",
+		deployment: "charts/zeebe-gateway/templates/gateway-deployment.yaml",
+		fullname:   "camunda-platform-test-zeebe-gateway",
+	},
+	{
+		name:       "identity",
+		values:     "identity.autoscaling.",
+		template:   "charts/identity/templates/horizontalpodautoscaler.yaml",
+		deployment: "charts/identity/templates/deployment.yaml",
+		fullname:   "camunda-platform-test-identity",
+	},
+	{
+		name:       "connectors",
+		values:     "connectors.autoscaling.",
+		template:   "templates/connectors/horizontalpodautoscaler.yaml",
+		deployment: "templates/connectors/deployment.yaml",
+		fullname:   "camunda-platform-test-connectors",
+		enabled:    map[string]string{"connectors.enabled": "true"},
+	},
+	{
+		name:       "operate",
+		values:     "operate.autoscaling.",
+		template:   "charts/operate/templates/horizontalpodautoscaler.yaml",
+		deployment: "charts/operate/templates/deployment.yaml",
+		fullname:   "camunda-platform-test-operate",
+		env: map[string]string{
+			"operate.env[0].name":  "CAMUNDA_OPERATE_IMPORTERENABLED",
+			"operate.env[0].value": "false",
+			"operate.env[1].name":  "CAMUNDA_OPERATE_ARCHIVERENABLED",
+			"operate.env[1].value": "false",
+		},
+	},
+	{
+		name:       "tasklist",
+		values:     "tasklist.autoscaling.",
+		template:   "charts/tasklist/templates/horizontalpodautoscaler.yaml",
+		deployment: "charts/tasklist/templates/deployment.yaml",
+		fullname:   "camunda-platform-test-tasklist",
+		env: map[string]string{
+			"tasklist.env[0].name":  "CAMUNDA_TASKLIST_IMPORTERENABLED",
+			"tasklist.env[0].value": "false",
+			"tasklist.env[1].name":  "CAMUNDA_TASKLIST_ARCHIVERENABLED",
+			"tasklist.env[1].value": "false",
+		},
+	},
+	{
+		name:       "optimize",
+		values:     "optimize.autoscaling.",
+		template:   "charts/optimize/templates/horizontalpodautoscaler.yaml",
+		deployment: "charts/optimize/templates/deployment.yaml",
+		fullname:   "camunda-platform-test-optimize",
+		env: map[string]string{
+			"optimize.env[0].name":  "CAMUNDA_OPTIMIZE_ZEEBE_ENABLED",
+			"optimize.env[0].value": "false",
+		},
+	},
+	{
+		name:       "web-modeler",
+		values:     "web-modeler.restapi.autoscaling.",
+		template:   "charts/web-modeler/templates/horizontalpodautoscaler-restapi.yaml",
+		deployment: "charts/web-modeler/templates/deployment-restapi.yaml",
+		fullname:   "camunda-platform-test-web-modeler-restapi",
+		enabled:    map[string]string{"web-modeler.enabled": "true"},
+	},
+	{
+		name:       "web-modeler",
+		values:     "web-modeler.webapp.autoscaling.",
+		template:   "charts/web-modeler/templates/horizontalpodautoscaler-webapp.yaml",
+		deployment: "charts/web-modeler/templates/deployment-webapp.yaml",
+		fullname:   "camunda-platform-test-web-modeler-webapp",
+		enabled:    map[string]string{"web-modeler.enabled": "true"},
+	},
+	{
+		name:       "web-modeler",
+		values:     "web-modeler.websockets.autoscaling.",
+		template:   "charts/web-modeler/templates/horizontalpodautoscaler-websockets.yaml",
+		deployment: "charts/web-modeler/templates/deployment-websockets.yaml",
+		fullname:   "camunda-platform-test-web-modeler-websockets",
+		enabled:    map[string]string{"web-modeler.enabled": "true"},
+	},
+}
+
+// render renders the templates with the values, which enable the component and set its autoscaling values.
+func (c autoscaledComponent) render(t *testing.T, autoscaling map[string]string, templates ...string) (string, error) {
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	values := map[string]string{}
+	for key, value := range c.enabled {
+		values[key] = value
+	}
+	for key, value := range autoscaling {
+		values[c.values+key] = value
+	}
+	options := &helm.Options{
+		SetValues:      values,
+		SetStrValues:   c.env,
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+	}
+	return helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", templates)
+}
+
+func TestAutoscalingShouldNotBeRenderedByDefault(t *testing.T) {
+	t.Parallel()
+
+	for _, component := range autoscaledComponents {
+		// when
+		_, err := component.render(t, nil, component.template)
+
+		// then
+		require.ErrorContains(t, err, "could not find template", component.fullname)
+	}
+}
+
+func TestAutoscalingShouldSetMetricsAndBehavior(t *testing.T) {
+	t.Parallel()
+
+	for _, component := range autoscaledComponents {
+		// when
+		output, err := component.render(t, map[string]string{
+			"enabled":                                       "true",
+			"minReplicas":                                   "2",
+			"maxReplicas":                                   "6",
+			"targetCPUUtilizationPercentage":                "",
+			"targetMemoryUtilizationPercentage":             "75",
+			"metrics[0].type":                               "Pods",
+			"metrics[0].pods.metric.name":                   "http_server_requests_per_second",
+			"metrics[0].pods.target.type":                   "AverageValue",
+			"metrics[0].pods.target.averageValue":           "100",
+			"behavior.scaleDown.stabilizationWindowSeconds": "300",
+		}, component.template)
+		require.NoError(t, err, component.fullname)
+		var autoscaler autoscalingv2.HorizontalPodAutoscaler
+		helm.UnmarshalK8SYaml(t, output, &autoscaler)
+
+		// then
+		require.Equal(t, "Deployment", autoscaler.Spec.ScaleTargetRef.Kind, component.fullname)
+		require.Equal(t, component.fullname, autoscaler.Spec.ScaleTargetRef.Name)
+		require.EqualValues(t, 2, *autoscaler.Spec.MinReplicas, component.fullname)
+		require.EqualValues(t, 6, autoscaler.Spec.MaxReplicas, component.fullname)
+		require.Len(t, autoscaler.Spec.Metrics, 2, component.fullname)
+		require.Equal(t, "memory", string(autoscaler.Spec.Metrics[0].Resource.Name), component.fullname)
+		require.EqualValues(t, 75, *autoscaler.Spec.Metrics[0].Resource.Target.AverageUtilization, component.fullname)
+		require.Equal(t, "http_server_requests_per_second", autoscaler.Spec.Metrics[1].Pods.Metric.Name, component.fullname)
+		require.Equal(t, "100", autoscaler.Spec.Metrics[1].Pods.Target.AverageValue.String(), component.fullname)
+		require.EqualValues(t, 300, *autoscaler.Spec.Behavior.ScaleDown.StabilizationWindowSeconds, component.fullname)
+	}
+}
+
+func TestAutoscalingShouldOmitDeploymentReplicas(t *testing.T) {
+	t.Parallel()
+
+	for _, component := range autoscaledComponents {
+		// when
+		output, err := component.render(t, map[string]string{"enabled": "true"}, component.deployment)
+		require.NoError(t, err, component.fullname)
+		var deployment appsv1.Deployment
+		helm.UnmarshalK8SYaml(t, output, &deployment)
+
+		// then
+		require.Nil(t, deployment.Spec.Replicas, component.fullname)
+	}
+}
+
+func TestAutoscalingShouldRejectMinReplicasAboveMaxReplicas(t *testing.T) {
+	t.Parallel()
+
+	for _, component := range autoscaledComponents {
+		// when
+		_, err := component.render(t, map[string]string{
+			"enabled":     "true",
+			"minReplicas": "4",
+			"maxReplicas": "3",
+		}, component.template)
+
+		// then
+		require.ErrorContains(t, err, "["+component.name+"][constraint] The autoscaling minReplicas (4) can't be greater than the maxReplicas (3)")
+	}
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"camunda-platform-helm/charts/camunda-platform/test/golden"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// backupValues enables the backup cronjob with the backup store of the brokers and an Optimize with backup API.
+func backupValues(store map[string]string) map[string]string {
+	values := map[string]string{
+		"backup.enabled":               "true",
+		"global.backup.repositoryName": "camunda-backups",
+		"optimize.image.tag":           "3.10.0",
+	}
+	for key, value := range store {
+		values[key] = value
+	}
+	return values
+}
+
+func TestGoldenBackupDefaults(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	templateNames := []string{"backup-configmap", "backup-cronjob"}
+
+	for _, name := range templateNames {
+		suite.Run(t, &golden.TemplateGoldenTest{
+			ChartPath:      chartPath,
+			Release:        "camunda-platform-test",
+			Namespace:      "camunda-platform-" + strings.ToLower(random.UniqueId()),
+			GoldenFileName: name,
+			Templates:      []string{"templates/" + name + ".yaml"},
+			SetValues: backupValues(map[string]string{
+				"zeebe.backup.store":         "S3",
+				"zeebe.backup.s3.bucketName": "camunda-backups",
+			}),
+		})
+	}
+}
+
+func TestBackupShouldNotBeConfiguredByDefault(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, nil)
+
+	// then
+	_, cronJob := release.podSpecs["camunda-platform-backup"]
+	require.False(t, cronJob)
+	for _, name := range []string{"camunda-platform-test-zeebe", "camunda-platform-test-operate", "camunda-platform-test-tasklist", "camunda-platform-test-optimize"} {
+		for variable := range containerEnv(release.podSpecs[name]) {
+			require.NotContains(t, variable, "BACKUP", name)
+		}
+	}
+}
+
+func TestBackupShouldConfigureEveryStoreType(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		store  string
+		values map[string]string
+	}{
+		{
+			store: "S3",
+			values: map[string]string{
+				"zeebe.backup.store":                   "S3",
+				"zeebe.backup.s3.bucketName":           "camunda-backups",
+				"zeebe.backup.s3.endpoint":             "http://minio:9000",
+				"zeebe.backup.s3.forcePathStyleAccess": "true",
+				"zeebe.backup.s3.existingSecret":       "backup-credentials",
+			},
+		},
+		{
+			store: "GCS",
+			values: map[string]string{
+				"zeebe.backup.store":              "GCS",
+				"zeebe.backup.gcs.bucketName":     "camunda-backups",
+				"zeebe.backup.gcs.existingSecret": "backup-credentials",
+				"zeebe.image.tag":                 "8.2.0",
+			},
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.store, func(t *testing.T) {
+			t.Parallel()
+
+			// when
+			release := renderRelease(t, backupValues(testCase.values))
+
+			// then
+			broker := containerEnv(release.podSpecs["camunda-platform-test-zeebe"])
+			require.Equal(t, testCase.store, broker["ZEEBE_BROKER_DATA_BACKUP_STORE"].Value)
+			require.Equal(t, "camunda-backups", broker["ZEEBE_BROKER_DATA_BACKUP_"+testCase.store+"_BUCKETNAME"].Value)
+			require.Equal(t, "camunda-backups", containerEnv(release.podSpecs["camunda-platform-test-operate"])["CAMUNDA_OPERATE_BACKUP_REPOSITORYNAME"].Value)
+			require.Equal(t, "camunda-backups", containerEnv(release.podSpecs["camunda-platform-test-tasklist"])["CAMUNDA_TASKLIST_BACKUP_REPOSITORYNAME"].Value)
+			require.Equal(t, "camunda-backups", containerEnv(release.podSpecs["camunda-platform-test-optimize"])["CAMUNDA_OPTIMIZE_BACKUP_REPOSITORY_NAME"].Value)
+
+			cronJob := containerEnv(release.podSpecs["camunda-platform-backup"])
+			require.Equal(t, "http://camunda-platform-test-zeebe-gateway:9600", cronJob["ZEEBE_GATEWAY_URL"].Value)
+			require.Equal(t, "http://camunda-platform-test-optimize:8092", cronJob["OPTIMIZE_URL"].Value)
+			require.Equal(t, "http://camunda-platform-test-operate:80", cronJob["OPERATE_URL"].Value)
+			require.Equal(t, "http://camunda-platform-test-tasklist:80", cronJob["TASKLIST_URL"].Value)
+			require.Equal(t, "camunda-backups", cronJob["BACKUP_REPOSITORY_NAME"].Value)
+			require.Equal(t, "http://elasticsearch-master:9200", cronJob["SEARCH_ENGINE_URL"].Value)
+		})
+	}
+}
+
+func TestBackupShouldOnlyBackUpEnabledWebApps(t *testing.T) {
+	t.Parallel()
+
+	// when
+	values := backupValues(map[string]string{
+		"zeebe.backup.store":         "S3",
+		"zeebe.backup.s3.bucketName": "camunda-backups",
+		"operate.contextPath":        "/operate",
+		"optimize.enabled":           "false",
+		"tasklist.enabled":           "false",
+	})
+	release := renderRelease(t, values)
+
+	// then
+	cronJob := containerEnv(release.podSpecs["camunda-platform-backup"])
+	require.Equal(t, "http://camunda-platform-test-operate:80/operate", cronJob["OPERATE_URL"].Value)
+	require.NotContains(t, cronJob, "OPTIMIZE_URL")
+	require.NotContains(t, cronJob, "TASKLIST_URL")
+}
+
+func TestBackupShouldOnlyBackUpOptimizeWithBackupApi(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		tag      string
+		expected bool
+	}{
+		{tag: "3.9.3", expected: false},
+		{tag: "3.10.0", expected: true},
+		{tag: "3.10.0-alpha2", expected: true},
+		{tag: "SNAPSHOT", expected: true},
+	} {
+		testCase := testCase
+		t.Run(testCase.tag, func(t *testing.T) {
+			t.Parallel()
+
+			// when
+			values := backupValues(map[string]string{
+				"zeebe.backup.store":         "S3",
+				"zeebe.backup.s3.bucketName": "camunda-backups",
+				"optimize.image.tag":         testCase.tag,
+			})
+			release := renderRelease(t, values)
+
+			// then
+			cronJob := containerEnv(release.podSpecs["camunda-platform-backup"])
+			if testCase.expected {
+				require.Equal(t, "http://camunda-platform-test-optimize:8092", cronJob["OPTIMIZE_URL"].Value)
+			} else {
+				require.NotContains(t, cronJob, "OPTIMIZE_URL")
+			}
+			require.Contains(t, cronJob, "OPERATE_URL")
+			require.Contains(t, cronJob, "TASKLIST_URL")
+		})
+	}
+}
+
+func TestBackupShouldFailWithoutStoreAndRepository(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		name     string
+		values   map[string]string
+		expected []string
+	}{
+		{
+			name:   "backup cronjob",
+			values: map[string]string{"backup.enabled": "true"},
+			expected: []string{
+				`[camunda][constraint] The var "zeebe.backup.store" has to be set together with "backup.enabled"`,
+				`[camunda][constraint] The var "global.backup.repositoryName" has to be set together with "backup.enabled"`,
+			},
+		},
+		{
+			name:     "unknown store",
+			values:   map[string]string{"zeebe.backup.store": "AZURE"},
+			expected: []string{`[zeebe][constraint] The var "zeebe.backup.store" has to be empty, "S3" or "GCS", but it's "AZURE".`},
+		},
+		{
+			name:     "store without bucket",
+			values:   map[string]string{"zeebe.backup.store": "GCS", "zeebe.image.tag": "8.2.0"},
+			expected: []string{`[zeebe][constraint] The var "zeebe.backup.gcs.bucketName" has to be set when "zeebe.backup.store" is "GCS".`},
+		},
+		{
+			name:     "GCS store before Zeebe 8.2",
+			values:   map[string]string{"zeebe.backup.store": "GCS", "zeebe.backup.gcs.bucketName": "camunda-backups"},
+			expected: []string{`[zeebe][constraint] The var "zeebe.backup.store" can only be "GCS" with Zeebe 8.2 or later, but the image tag is "8.1.7".`},
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// given
+			options := &helm.Options{
+				SetValues:      testCase.values,
+				KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-test"),
+			}
+
+			// when
+			_, err := helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", nil)
+
+			// then
+			for _, expected := range testCase.expected {
+				require.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package test
+
+import (
+	"camunda-platform-helm/charts/camunda-platform/test/golden"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestGoldenHorizontalPodAutoscalerDefaults(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	suite.Run(t, &golden.TemplateGoldenTest{
+		ChartPath:      chartPath,
+		Release:        "camunda-platform-test",
+		Namespace:      "camunda-platform-" + strings.ToLower(random.UniqueId()),
+		GoldenFileName: "connectors-horizontalpodautoscaler",
+		Templates:      []string{"templates/connectors/horizontalpodautoscaler.yaml"},
+		SetValues: map[string]string{
+			"connectors.enabled":             "true",
+			"connectors.autoscaling.enabled": "true",
+		},
+	})
+}
@@ -0,0 +1,281 @@
+package test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// securedElasticsearchValues configure an external Elasticsearch with basic authentication and a custom CA.
+var securedElasticsearchValues = map[string]string{
+	"elasticsearch.enabled":                       "false",
+	"retentionPolicy.enabled":                     "true",
+	"global.elasticsearch.protocol":               "https",
+	"global.elasticsearch.host":                   "elasticsearch.example.com",
+	"global.elasticsearch.port":                   "9243",
+	"global.elasticsearch.auth.username":          "camunda",
+	"global.elasticsearch.auth.existingSecret":    "elasticsearch-credentials",
+	"global.elasticsearch.auth.existingSecretKey": "elasticsearch-password",
+	"global.elasticsearch.tls.existingSecret":     "elasticsearch-ca",
+	"global.elasticsearch.tls.caKey":              "tls.crt",
+}
+
+// elasticsearchConsumer is a workload of the release, which accesses Elasticsearch.
+type elasticsearchConsumer struct {
+	name string
+	// passwordNames are the environment variables, which have to contain the password of Elasticsearch.
+	passwordNames []string
+	// truststore is true if the consumer trusts the CA with a Java truststore instead of the certificate path.
+	truststore bool
+}
+
+var elasticsearchConsumers = []elasticsearchConsumer{
+	{
+		name:          "camunda-platform-test-zeebe",
+		passwordNames: []string{"ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_AUTHENTICATION_PASSWORD"},
+		truststore:    true,
+	},
+	{
+		name:          "camunda-platform-test-operate",
+		passwordNames: []string{"CAMUNDA_OPERATE_ELASTICSEARCH_PASSWORD", "CAMUNDA_OPERATE_ZEEBEELASTICSEARCH_PASSWORD"},
+	},
+	{
+		name:          "camunda-platform-test-tasklist",
+		passwordNames: []string{"CAMUNDA_TASKLIST_ELASTICSEARCH_PASSWORD", "CAMUNDA_TASKLIST_ZEEBEELASTICSEARCH_PASSWORD"},
+	},
+	{
+		name:          "camunda-platform-test-optimize",
+		passwordNames: []string{"CAMUNDA_OPTIMIZE_ELASTICSEARCH_SECURITY_PASSWORD"},
+		truststore:    true,
+	},
+	{
+		name:          "camunda-platform-curator",
+		passwordNames: []string{"ELASTICSEARCH_PASSWORD"},
+	},
+}
+
+// renderedRelease contains the pod specs of the workloads and the config maps of a rendered release by their name.
+type renderedRelease struct {
+	podSpecs   map[string]corev1.PodSpec
+	configMaps map[string]corev1.ConfigMap
+}
+
+func renderRelease(t *testing.T, values map[string]string) renderedRelease {
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	options := &helm.Options{
+		SetValues:      values,
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+	}
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", nil)
+
+	release := renderedRelease{podSpecs: map[string]corev1.PodSpec{}, configMaps: map[string]corev1.ConfigMap{}}
+	for _, document := range strings.Split(output, "\n---\n") {
+		var object metav1.PartialObjectMetadata
+		helm.UnmarshalK8SYaml(t, document, &object)
+		switch object.Kind {
+		case "Deployment":
+			var deployment appsv1.Deployment
+			helm.UnmarshalK8SYaml(t, document, &deployment)
+			release.podSpecs[deployment.Name] = deployment.Spec.Template.Spec
+		case "StatefulSet":
+			var statefulSet appsv1.StatefulSet
+			helm.UnmarshalK8SYaml(t, document, &statefulSet)
+			release.podSpecs[statefulSet.Name] = statefulSet.Spec.Template.Spec
+		case "Job":
+			var job batchv1.Job
+			helm.UnmarshalK8SYaml(t, document, &job)
+			release.podSpecs[job.Name] = job.Spec.Template.Spec
+		case "CronJob":
+			var cronJob batchv1.CronJob
+			helm.UnmarshalK8SYaml(t, document, &cronJob)
+			release.podSpecs[cronJob.Name] = cronJob.Spec.JobTemplate.Spec.Template.Spec
+		case "ConfigMap":
+			var configMap corev1.ConfigMap
+			helm.UnmarshalK8SYaml(t, document, &configMap)
+			release.configMaps[configMap.Name] = configMap
+		}
+	}
+	return release
+}
+
+// configValue returns the value of the path in the YAML config file of the config map.
+func configValue(t *testing.T, configMap corev1.ConfigMap, file string, path ...string) interface{} {
+	var value interface{}
+	helm.UnmarshalK8SYaml(t, configMap.Data[file], &value)
+	for _, key := range path {
+		config, ok := value.(map[string]interface{})
+		require.True(t, ok, "%s of %s isn't a map", key, file)
+		value = config[key]
+	}
+	return value
+}
+
+func TestElasticsearchCredentialsAndCAShouldBeConsistentForEveryConsumer(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, securedElasticsearchValues)
+
+	// then
+	password := &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "elasticsearch-credentials"},
+		Key:                  "elasticsearch-password",
+	}}
+	caVolume := corev1.Volume{Name: "elasticsearch-ca", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+		SecretName: "elasticsearch-ca",
+		Items:      []corev1.KeyToPath{{Key: "tls.crt", Path: "ca.crt"}},
+	}}}
+	caMount := corev1.VolumeMount{Name: "elasticsearch-ca", MountPath: "/usr/local/share/elasticsearch-ca", ReadOnly: true}
+	truststoreVolume := corev1.Volume{Name: "elasticsearch-truststore", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	truststoreMount := corev1.VolumeMount{Name: "elasticsearch-truststore", MountPath: "/usr/local/share/elasticsearch-truststore"}
+
+	for _, consumer := range elasticsearchConsumers {
+		podSpec, ok := release.podSpecs[consumer.name]
+		require.True(t, ok, "%s isn't rendered", consumer.name)
+		container := podSpec.Containers[0]
+
+		for _, name := range consumer.passwordNames {
+			require.Contains(t, container.Env, corev1.EnvVar{Name: name, ValueFrom: password}, consumer.name)
+		}
+		require.Contains(t, podSpec.Volumes, caVolume, consumer.name)
+		require.Contains(t, container.VolumeMounts, caMount, consumer.name)
+
+		if !consumer.truststore {
+			require.Empty(t, podSpec.InitContainers, consumer.name)
+			require.NotContains(t, podSpec.Volumes, truststoreVolume, consumer.name)
+			continue
+		}
+		require.Len(t, podSpec.InitContainers, 1, consumer.name)
+		initContainer := podSpec.InitContainers[0]
+		require.Equal(t, "elasticsearch-truststore", initContainer.Name, consumer.name)
+		require.Equal(t, container.Image, initContainer.Image, "the init container uses the JVM of the %s image", consumer.name)
+		require.Equal(t, []corev1.VolumeMount{caMount, truststoreMount}, initContainer.VolumeMounts, consumer.name)
+		require.Contains(t, initContainer.Args[0], "-file /usr/local/share/elasticsearch-ca/ca.crt", consumer.name)
+		require.Contains(t, initContainer.Args[0], "-keystore /usr/local/share/elasticsearch-truststore/cacerts", consumer.name)
+		require.Contains(t, podSpec.Volumes, truststoreVolume, consumer.name)
+		require.Contains(t, container.VolumeMounts, truststoreMount, consumer.name)
+		var javaToolOptions string
+		for _, env := range container.Env {
+			if env.Name == "JAVA_TOOL_OPTIONS" {
+				javaToolOptions = env.Value
+			}
+		}
+		require.Contains(t, javaToolOptions, "-Djavax.net.ssl.trustStore=/usr/local/share/elasticsearch-truststore/cacerts", consumer.name)
+	}
+
+	require.Contains(t, release.podSpecs["camunda-platform-test-zeebe"].Containers[0].Env,
+		corev1.EnvVar{Name: "ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_AUTHENTICATION_USERNAME", Value: "camunda"})
+	optimizeEnv := release.podSpecs["camunda-platform-test-optimize"].Containers[0].Env
+	require.Contains(t, optimizeEnv, corev1.EnvVar{Name: "CAMUNDA_OPTIMIZE_ELASTICSEARCH_SECURITY_USERNAME", Value: "camunda"})
+	require.Contains(t, optimizeEnv, corev1.EnvVar{Name: "CAMUNDA_OPTIMIZE_ELASTICSEARCH_SECURITY_SSL_ENABLED", Value: "true"})
+
+	for _, app := range []string{"operate", "tasklist"} {
+		configMap := release.configMaps["camunda-platform-test-"+app]
+		for _, store := range []string{"elasticsearch", "zeebeElasticsearch"} {
+			require.Equal(t, "camunda", configValue(t, configMap, "application.yml", "camunda."+app, store, "username"), app)
+			require.Equal(t, "/usr/local/share/elasticsearch-ca/ca.crt",
+				configValue(t, configMap, "application.yml", "camunda."+app, store, "ssl", "certificatePath"), app)
+		}
+	}
+
+	curatorConfig := release.configMaps["camunda-platform-curator-config"]
+	require.Equal(t, []interface{}{"elasticsearch.example.com"}, configValue(t, curatorConfig, "config.yml", "client", "hosts"))
+	require.Equal(t, float64(9243), configValue(t, curatorConfig, "config.yml", "client", "port"))
+	require.Equal(t, true, configValue(t, curatorConfig, "config.yml", "client", "use_ssl"))
+	require.Equal(t, "camunda", configValue(t, curatorConfig, "config.yml", "client", "username"))
+	require.Equal(t, "${ELASTICSEARCH_PASSWORD}", configValue(t, curatorConfig, "config.yml", "client", "password"))
+	require.Equal(t, "/usr/local/share/elasticsearch-ca/ca.crt", configValue(t, curatorConfig, "config.yml", "client", "certificate"))
+}
+
+func TestElasticsearchCAShouldBeReadFromConfigMap(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"global.elasticsearch.tls.existingConfigMap": "elasticsearch-ca",
+	}
+
+	// when
+	release := renderRelease(t, values)
+
+	// then
+	caVolume := corev1.Volume{Name: "elasticsearch-ca", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "elasticsearch-ca"},
+		Items:                []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+	}}}
+	for _, consumer := range elasticsearchConsumers[:4] {
+		require.Contains(t, release.podSpecs[consumer.name].Volumes, caVolume, consumer.name)
+		for _, env := range release.podSpecs[consumer.name].Containers[0].Env {
+			require.NotContains(t, env.Name, "PASSWORD", consumer.name)
+		}
+	}
+}
+
+func TestElasticsearchShouldNotUseCredentialsAndCAByDefault(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, map[string]string{"retentionPolicy.enabled": "true"})
+
+	// then
+	for _, consumer := range elasticsearchConsumers {
+		podSpec, ok := release.podSpecs[consumer.name]
+		require.True(t, ok, "%s isn't rendered", consumer.name)
+		require.Empty(t, podSpec.InitContainers, consumer.name)
+		for _, volume := range podSpec.Volumes {
+			require.NotContains(t, volume.Name, "elasticsearch", consumer.name)
+		}
+		for _, env := range podSpec.Containers[0].Env {
+			require.NotContains(t, env.Name, "PASSWORD", consumer.name)
+			require.NotContains(t, env.Value, "javax.net.ssl", consumer.name)
+		}
+	}
+}
+
+func TestElasticsearchShouldNotCheckIndexTemplatesInTestHooksIfSecured(t *testing.T) {
+	t.Parallel()
+
+	// when
+	hooks := renderTestHooks(t, securedElasticsearchValues)
+
+	// then
+	for component, pod := range hooks {
+		for _, arg := range testRunnerArgs(t, pod) {
+			require.NotContains(t, arg, "-elasticsearch=", component)
+			require.NotContains(t, arg, "-index-template=", component)
+		}
+	}
+}
+
+func TestElasticsearchConstraints(t *testing.T) {
+	t.Parallel()
+
+	// given
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"global.elasticsearch.auth.username":         "camunda",
+			"global.elasticsearch.tls.existingSecret":    "elasticsearch-ca",
+			"global.elasticsearch.tls.existingConfigMap": "elasticsearch-ca",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+	}
+
+	// when
+	_, err = helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", nil)
+
+	// then
+	require.ErrorContains(t, err, `[camunda][constraint] The var "global.elasticsearch.auth.existingSecret" has to be set together with`)
+	require.ErrorContains(t, err, `[camunda][constraint] Only one of the vars "global.elasticsearch.tls.existingSecret" and`)
+}
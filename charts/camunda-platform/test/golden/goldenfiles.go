@@ -35,6 +35,7 @@ type TemplateGoldenTest struct {
 	Templates      []string
 	IgnoredLines   []string
 	SetValues      map[string]string
+	SetStrValues   map[string]string
 	ExtraHelmArgs  []string
 }
 
@@ -42,6 +43,7 @@ func (s *TemplateGoldenTest) TestContainerGoldenTestDefaults() {
 	options := &helm.Options{
 		KubectlOptions: k8s.NewKubectlOptions("", "", s.Namespace),
 		SetValues:      s.SetValues,
+		SetStrValues:   s.SetStrValues,
 	}
 	output := helm.RenderTemplate(s.T(), options, s.ChartPath, s.Release, s.Templates, s.ExtraHelmArgs...)
 
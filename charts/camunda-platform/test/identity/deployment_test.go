@@ -857,6 +857,68 @@ func (s *deploymentTemplateTest) TestContainerShouldAddContextPath() {
 	)
 }
 
+func (s *deploymentTemplateTest) TestContainerWithExternalDatabase() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"identity.externalDatabase.enabled":                   "true",
+			"identity.externalDatabase.host":                      "postgres.example.com",
+			"identity.externalDatabase.port":                      "6432",
+			"identity.externalDatabase.database":                  "identity-db",
+			"identity.externalDatabase.user":                      "identity-user",
+			"identity.externalDatabase.existingSecret":            "identity-database",
+			"identity.externalDatabase.existingSecretPasswordKey": "db-password",
+			"identity.externalDatabase.sslMode":                   "verify-full",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	env := deployment.Spec.Template.Spec.Containers[0].Env
+	s.Require().Contains(env,
+		corev1.EnvVar{
+			Name:  "SPRING_DATASOURCE_URL",
+			Value: "jdbc:postgresql://postgres.example.com:6432/identity-db?sslmode=verify-full",
+		})
+	s.Require().Contains(env,
+		corev1.EnvVar{
+			Name:  "SPRING_DATASOURCE_USERNAME",
+			Value: "identity-user",
+		})
+	s.Require().Contains(env,
+		corev1.EnvVar{
+			Name: "SPRING_DATASOURCE_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "identity-database"},
+					Key:                  "db-password",
+				},
+			},
+		})
+}
+
+func (s *deploymentTemplateTest) TestContainerShouldNotSetDatabaseByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	for _, envvar := range deployment.Spec.Template.Spec.Containers[0].Env {
+		s.Require().NotContains(envvar.Name, "SPRING_DATASOURCE")
+	}
+}
+
 // readinessProbe is enabled by default so it's tested by golden files.
 
 func (s *deploymentTemplateTest) TestContainerStartupProbe() {
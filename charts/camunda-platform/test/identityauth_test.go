@@ -0,0 +1,190 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// genericAuthValues configure an external OpenID Connect provider with the clients of all components.
+func genericAuthValues() map[string]string {
+	return map[string]string{
+		"global.identity.auth.type":                           "GENERIC",
+		"global.identity.auth.publicIssuerUrl":                "https://login.example.com/",
+		"global.identity.auth.tokenUrl":                       "https://login.example.com/oauth/token",
+		"global.identity.auth.jwksUrl":                        "https://login.example.com/.well-known/jwks.json",
+		"global.identity.auth.operate.existingSecret.name":    "oidc-clients",
+		"global.identity.auth.operate.clientId":               "operate-client",
+		"global.identity.auth.operate.audience":               "operate-audience",
+		"global.identity.auth.tasklist.existingSecret.name":   "oidc-clients",
+		"global.identity.auth.optimize.existingSecret.name":   "oidc-clients",
+		"global.identity.auth.optimize.existingSecretKey":     "optimize-client-secret",
+		"global.identity.auth.webModeler.clientId":            "modeler-client",
+		"global.identity.auth.webModeler.audience":            "modeler-audience",
+		"global.identity.auth.connectors.existingSecret.name": "oidc-clients",
+		"global.identity.auth.zeebe.enabled":                  "true",
+		"global.identity.keycloak.url.protocol":               "https",
+		"global.identity.keycloak.url.host":                   "keycloak.example.com",
+		"global.identity.keycloak.url.port":                   "443",
+		"identity.keycloak.enabled":                           "false",
+		"web-modeler.enabled":                                 "true",
+		"connectors.enabled":                                  "true",
+	}
+}
+
+// containerEnv returns the environment variables of the first container by their name.
+func containerEnv(podSpec corev1.PodSpec) map[string]corev1.EnvVar {
+	env := map[string]corev1.EnvVar{}
+	for _, variable := range podSpec.Containers[0].Env {
+		env[variable.Name] = variable
+	}
+	return env
+}
+
+// secretEnv returns an environment variable, which references the key of the secret.
+func secretEnv(name string, secret string, key string) corev1.EnvVar {
+	return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret}, Key: key},
+	}}
+}
+
+func TestIdentityAuthShouldUseKeycloakByDefault(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, map[string]string{"web-modeler.enabled": "true", "connectors.enabled": "true"})
+
+	// then
+	realm := "http://camunda-platform-tes:80/auth/realms/camunda-platform"
+	operate := containerEnv(release.podSpecs["camunda-platform-test-operate"])
+	require.NotContains(t, operate, "CAMUNDA_IDENTITY_TYPE")
+	require.Equal(t, realm, operate["SPRING_SECURITY_OAUTH2_RESOURCESERVER_JWT_ISSUERURI"].Value)
+	require.Equal(t, realm+"/protocol/openid-connect/certs", operate["SPRING_SECURITY_OAUTH2_RESOURCESERVER_JWT_JWKSETURI"].Value)
+	require.Equal(t, "operate", operate["CAMUNDA_OPERATE_IDENTITY_CLIENT_ID"].Value)
+	require.Equal(t, "operate-api", operate["CAMUNDA_OPERATE_IDENTITY_AUDIENCE"].Value)
+	require.Equal(t, secretEnv("CAMUNDA_OPERATE_IDENTITY_CLIENT_SECRET", "camunda-platform-test-operate-identity-secret", "operate-secret"),
+		operate["CAMUNDA_OPERATE_IDENTITY_CLIENT_SECRET"])
+	require.NotContains(t, operate, "ZEEBE_CLIENT_ID")
+
+	tasklist := containerEnv(release.podSpecs["camunda-platform-test-tasklist"])
+	require.Equal(t, "tasklist", tasklist["CAMUNDA_TASKLIST_IDENTITY_CLIENT_ID"].Value)
+	require.Equal(t, "tasklist-api", tasklist["CAMUNDA_TASKLIST_IDENTITY_AUDIENCE"].Value)
+
+	optimize := containerEnv(release.podSpecs["camunda-platform-test-optimize"])
+	require.Equal(t, "optimize", optimize["CAMUNDA_OPTIMIZE_IDENTITY_CLIENTID"].Value)
+	require.Equal(t, realm+"/protocol/openid-connect/certs", optimize["SPRING_SECURITY_OAUTH2_RESOURCESERVER_JWT_JWK_SET_URI"].Value)
+
+	webapp := containerEnv(release.podSpecs["camunda-platform-test-web-modeler-webapp"])
+	require.Equal(t, "web-modeler", webapp["OAUTH2_CLIENT_ID"].Value)
+	require.NotContains(t, webapp, "OAUTH2_TYPE")
+	require.NotContains(t, webapp, "OAUTH2_JWKS_URL")
+	require.Equal(t, "camunda-platform", webapp["KEYCLOAK_REALM"].Value)
+	require.Equal(t, realm+"/protocol/openid-connect/certs", webapp["KEYCLOAK_JWKS_URL"].Value)
+	restapi := containerEnv(release.podSpecs["camunda-platform-test-web-modeler-restapi"])
+	require.NotContains(t, restapi, "RESTAPI_OAUTH2_TOKEN_AUDIENCE")
+	require.NotContains(t, restapi, "RESTAPI_OAUTH2_JWKS_URL")
+	require.NotContains(t, restapi, "CAMUNDA_IDENTITY_TYPE")
+
+	require.NotContains(t, containerEnv(release.podSpecs["camunda-platform-test-connectors"]), "ZEEBE_CLIENT_ID")
+	require.NotContains(t, containerEnv(release.podSpecs["camunda-platform-test-zeebe-gateway"]), "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_MODE")
+}
+
+func TestIdentityAuthShouldConfigureGenericProvider(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, genericAuthValues())
+
+	// then
+	operate := containerEnv(release.podSpecs["camunda-platform-test-operate"])
+	require.Equal(t, "GENERIC", operate["CAMUNDA_IDENTITY_TYPE"].Value)
+	require.Equal(t, "https://login.example.com/", operate["SPRING_SECURITY_OAUTH2_RESOURCESERVER_JWT_ISSUERURI"].Value)
+	require.Equal(t, "https://login.example.com/.well-known/jwks.json", operate["SPRING_SECURITY_OAUTH2_RESOURCESERVER_JWT_JWKSETURI"].Value)
+	require.Equal(t, "https://login.example.com/", operate["CAMUNDA_OPERATE_IDENTITY_ISSUER_BACKEND_URL"].Value)
+	require.Equal(t, "operate-client", operate["CAMUNDA_OPERATE_IDENTITY_CLIENT_ID"].Value)
+	require.Equal(t, "operate-audience", operate["CAMUNDA_OPERATE_IDENTITY_AUDIENCE"].Value)
+	require.Equal(t, secretEnv("CAMUNDA_OPERATE_IDENTITY_CLIENT_SECRET", "oidc-clients", "operate-secret"), operate["CAMUNDA_OPERATE_IDENTITY_CLIENT_SECRET"])
+	require.Equal(t, "operate-client", operate["ZEEBE_CLIENT_ID"].Value)
+	require.Equal(t, secretEnv("ZEEBE_CLIENT_SECRET", "oidc-clients", "operate-secret"), operate["ZEEBE_CLIENT_SECRET"])
+	require.Equal(t, "https://login.example.com/oauth/token", operate["ZEEBE_AUTHORIZATION_SERVER_URL"].Value)
+	require.Equal(t, "zeebe-api", operate["ZEEBE_TOKEN_AUDIENCE"].Value)
+
+	tasklist := containerEnv(release.podSpecs["camunda-platform-test-tasklist"])
+	require.Equal(t, secretEnv("CAMUNDA_TASKLIST_IDENTITY_CLIENT_SECRET", "oidc-clients", "tasklist-secret"), tasklist["CAMUNDA_TASKLIST_IDENTITY_CLIENT_SECRET"])
+	require.Equal(t, "tasklist", tasklist["ZEEBE_CLIENT_ID"].Value)
+
+	optimize := containerEnv(release.podSpecs["camunda-platform-test-optimize"])
+	require.Equal(t, secretEnv("CAMUNDA_OPTIMIZE_IDENTITY_CLIENTSECRET", "oidc-clients", "optimize-client-secret"), optimize["CAMUNDA_OPTIMIZE_IDENTITY_CLIENTSECRET"])
+	require.Equal(t, "https://login.example.com/.well-known/jwks.json", optimize["SPRING_SECURITY_OAUTH2_RESOURCESERVER_JWT_JWK_SET_URI"].Value)
+
+	webapp := containerEnv(release.podSpecs["camunda-platform-test-web-modeler-webapp"])
+	require.Equal(t, "modeler-client", webapp["OAUTH2_CLIENT_ID"].Value)
+	require.Equal(t, "GENERIC", webapp["OAUTH2_TYPE"].Value)
+	require.Equal(t, "https://login.example.com/.well-known/jwks.json", webapp["OAUTH2_JWKS_URL"].Value)
+	require.NotContains(t, webapp, "KEYCLOAK_REALM")
+	restapi := containerEnv(release.podSpecs["camunda-platform-test-web-modeler-restapi"])
+	require.Equal(t, "https://login.example.com/", restapi["RESTAPI_OAUTH2_TOKEN_ISSUER_BACKEND_URL"].Value)
+	require.Equal(t, "modeler-audience", restapi["RESTAPI_OAUTH2_TOKEN_AUDIENCE"].Value)
+	require.Equal(t, "https://login.example.com/.well-known/jwks.json", restapi["RESTAPI_OAUTH2_JWKS_URL"].Value)
+	require.Equal(t, "GENERIC", restapi["CAMUNDA_IDENTITY_TYPE"].Value)
+
+	connectors := containerEnv(release.podSpecs["camunda-platform-test-connectors"])
+	require.Equal(t, "connectors", connectors["ZEEBE_CLIENT_ID"].Value)
+	require.Equal(t, secretEnv("ZEEBE_CLIENT_SECRET", "oidc-clients", "connectors-secret"), connectors["ZEEBE_CLIENT_SECRET"])
+
+	gateway := containerEnv(release.podSpecs["camunda-platform-test-zeebe-gateway"])
+	require.Equal(t, "identity", gateway["ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_MODE"].Value)
+	require.Equal(t, "https://login.example.com/", gateway["ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_ISSUERBACKENDURL"].Value)
+	require.Equal(t, "zeebe-api", gateway["ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_AUDIENCE"].Value)
+	require.Equal(t, "GENERIC", gateway["ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_TYPE"].Value)
+}
+
+func TestIdentityAuthShouldCheckDiscoveryOfGenericProvider(t *testing.T) {
+	t.Parallel()
+
+	// when
+	hooks := renderTestHooks(t, genericAuthValues())
+
+	// then
+	for _, component := range []string{"operate", "tasklist", "optimize", "identity"} {
+		require.Contains(t, testRunnerArgs(t, hooks[component]), "-oidc-discovery=https://login.example.com/.well-known/openid-configuration", component)
+	}
+	require.NotContains(t, testRunnerArgs(t, hooks["identity"]), "-keycloak-realm=https://login.example.com/")
+	for _, arg := range testRunnerArgs(t, hooks["zeebe-broker"]) {
+		require.NotContains(t, arg, "-zeebe-gateway", "the gateway rejects requests without token")
+	}
+}
+
+func TestIdentityAuthShouldFailOnIncompleteGenericProvider(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"global.identity.auth.type":                         "GENERIC",
+			"global.identity.auth.operate.existingSecret":       "plain-secret",
+			"global.identity.auth.tasklist.existingSecret.name": "oidc-clients",
+			"global.identity.auth.optimize.existingSecret.name": "oidc-clients",
+			"global.identity.auth.zeebe.enabled":                "true",
+			"connectors.enabled":                                "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-test"),
+	}
+
+	// when
+	_, err = helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", nil)
+
+	// then
+	require.ErrorContains(t, err, `[camunda][constraint] The Keycloak dependency can't be enabled together with "global.identity.auth.type: GENERIC".`)
+	require.ErrorContains(t, err, `[camunda][constraint] The vars "global.identity.auth.tokenUrl" and "global.identity.auth.jwksUrl" have to be set`)
+	require.ErrorContains(t, err, `[camunda][constraint] The var "global.identity.auth.operate.existingSecret" has to reference a secret object`)
+	require.NotContains(t, err.Error(), `"global.identity.auth.tasklist.existingSecret"`)
+	require.ErrorContains(t, err, `[camunda][constraint] The var "global.identity.auth.connectors.existingSecret" has to reference a secret object`)
+}
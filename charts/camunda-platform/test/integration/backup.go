@@ -0,0 +1,201 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+const (
+	// kBackupStandInImage is a MinIO, which the suite deploys into the test namespace as S3-compatible backup store.
+	kBackupStandInImage       = "minio/minio:RELEASE.2023-01-31T02-24-19Z"
+	kBackupStandInClientImage = "minio/mc:RELEASE.2023-01-28T20-29-34Z"
+	kBackupStandInPort        = 9000
+	kBackupBucket             = "camunda-backups"
+	kBackupSecret             = "backup-store-credentials"
+)
+
+// zeebeBackupStatus is the response of the backup API of the Zeebe Gateway for a single backup.
+type zeebeBackupStatus struct {
+	BackupId      int64  `json:"backupId"`
+	State         string `json:"state"`
+	FailureReason string `json:"failureReason"`
+}
+
+// parseZeebeBackupStatus returns the state of the backup, it fails if the backup failed.
+func parseZeebeBackupStatus(body []byte) (string, error) {
+	var status zeebeBackupStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return "", err
+	}
+	switch status.State {
+	case "COMPLETED", "IN_PROGRESS":
+		return status.State, nil
+	default:
+		return "", fmt.Errorf("the backup %d has the state %s: %s", status.BackupId, status.State, status.FailureReason)
+	}
+}
+
+// backupStandInValues configure the brokers to store their backups in the MinIO of deployBackupStandIn.
+func (s *integrationSuite) backupStandInValues() map[string]string {
+	return map[string]string{
+		"zeebe.backup.store":                   "S3",
+		"zeebe.backup.s3.bucketName":           kBackupBucket,
+		"zeebe.backup.s3.region":               "us-east-1",
+		"zeebe.backup.s3.endpoint":             fmt.Sprintf("http://%s:%d", s.serviceName("backup-store"), kBackupStandInPort),
+		"zeebe.backup.s3.forcePathStyleAccess": "true",
+		"zeebe.backup.s3.existingSecret":       kBackupSecret,
+	}
+}
+
+// deployBackupStandIn deploys a MinIO with the bucket of the backups and the secret of its credentials, which the
+// brokers use as S3-compatible backup store.
+func (s *integrationSuite) deployBackupStandIn() {
+	name := s.serviceName("backup-store")
+	manifest := fmt.Sprintf(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: %[2]s
+stringData:
+  access-key: camunda
+  secret-key: camunda-backups
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+spec:
+  selector:
+    matchLabels:
+      app: %[1]s
+  template:
+    metadata:
+      labels:
+        app: %[1]s
+    spec:
+      containers:
+        - name: minio
+          image: %[3]s
+          args: ["server", "/data"]
+          env:
+            - name: MINIO_ROOT_USER
+              valueFrom:
+                secretKeyRef:
+                  name: %[2]s
+                  key: access-key
+            - name: MINIO_ROOT_PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: %[2]s
+                  key: secret-key
+          ports:
+            - containerPort: %[5]d
+          readinessProbe:
+            httpGet:
+              path: /minio/health/ready
+              port: %[5]d
+          volumeMounts:
+            - name: data
+              mountPath: /data
+      volumes:
+        - name: data
+          emptyDir: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+    - name: s3
+      port: %[5]d
+      targetPort: %[5]d
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: %[1]s-bucket
+spec:
+  backoffLimit: 10
+  template:
+    spec:
+      restartPolicy: OnFailure
+      containers:
+        - name: mc
+          image: %[4]s
+          command: ["sh", "-c", "mc alias set store http://%[1]s:%[5]d $ACCESS_KEY $SECRET_KEY && mc mb --ignore-existing store/%[6]s"]
+          env:
+            - name: ACCESS_KEY
+              valueFrom:
+                secretKeyRef:
+                  name: %[2]s
+                  key: access-key
+            - name: SECRET_KEY
+              valueFrom:
+                secretKeyRef:
+                  name: %[2]s
+                  key: secret-key
+`, name, kBackupSecret, kBackupStandInImage, kBackupStandInClientImage, kBackupStandInPort, kBackupBucket)
+	k8s.KubectlApplyFromString(s.T(), s.kubeOptions, manifest)
+	s.waitUntilPodAvailable("app=" + name)
+	k8s.WaitUntilJobSucceed(s.T(), s.kubeOptions, name+"-bucket", 30, 10*time.Second)
+}
+
+// assertZeebeBackup takes a backup through the backup API of the Zeebe Gateway and waits until all partitions are
+// backed up to the store.
+func (s *integrationSuite) assertZeebeBackup() {
+	url := "http://" + s.portForward(s.zeebeGatewayServiceName(), 9600) + "/actuator/backups"
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	backupId := time.Now().Unix()
+
+	body, err := json.Marshal(map[string]int64{"backupId": backupId})
+	s.Require().NoError(err)
+	response, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	response.Body.Close()
+	s.Require().Equal(http.StatusAccepted, response.StatusCode, "taking the backup %d", backupId)
+
+	message := retry.DoWithRetry(s.T(), "Wait for the Zeebe backup", 30, 10*time.Second, func() (string, error) {
+		response, err := httpClient.Get(fmt.Sprintf("%s/%d", url, backupId))
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		content, err := io.ReadAll(response.Body)
+		if err != nil {
+			return "", err
+		}
+		state, err := parseZeebeBackupStatus(content)
+		if err != nil {
+			return "", retry.FatalError{Underlying: err}
+		}
+		if state != "COMPLETED" {
+			return "", fmt.Errorf("the backup %d is %s", backupId, state)
+		}
+		return fmt.Sprintf("The Zeebe backup %d is completed.", backupId), nil
+	})
+	s.T().Logf(message)
+}
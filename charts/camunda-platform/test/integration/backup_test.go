@@ -0,0 +1,48 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseZeebeBackupStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		body  string
+		state string
+		err   string
+	}{
+		{body: `{"backupId": 1, "state": "COMPLETED", "details": []}`, state: "COMPLETED"},
+		{body: `{"backupId": 1, "state": "IN_PROGRESS"}`, state: "IN_PROGRESS"},
+		{body: `{"backupId": 1, "state": "FAILED", "failureReason": "Access Denied"}`, err: "the backup 1 has the state FAILED: Access Denied"},
+		{body: `{"backupId": 1, "state": "DOES_NOT_EXIST"}`, err: "the backup 1 has the state DOES_NOT_EXIST"},
+		{body: `not found`, err: "invalid character"},
+	} {
+		// when
+		state, err := parseZeebeBackupStatus([]byte(testCase.body))
+
+		// then
+		if testCase.err != "" {
+			require.ErrorContains(t, err, testCase.err, testCase.body)
+			continue
+		}
+		require.NoError(t, err, testCase.body)
+		require.Equal(t, testCase.state, state)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	kZeebeDataPath = "/usr/local/zeebe/data"
+	// kZeebeDataMarker is written to the data volume of a broker before it's evicted, to check that it rejoins with
+	// the same data.
+	kZeebeDataMarker = kZeebeDataPath + "/.camunda-platform-it-marker"
+)
+
+func (s *integrationSuite) zeebePodName(nodeId int32) string {
+	return fmt.Sprintf("%s-zeebe-%d", s.release, nodeId)
+}
+
+// partitionLeaders returns the leader of every partition which has one in the topology.
+func partitionLeaders(topology *pb.TopologyResponse) map[int32]int32 {
+	leaders := map[int32]int32{}
+	for _, broker := range topology.Brokers {
+		for _, partition := range broker.Partitions {
+			if partition.Role == pb.Partition_LEADER {
+				leaders[partition.PartitionId] = broker.NodeId
+			}
+		}
+	}
+	return leaders
+}
+
+// mostLeadingBroker returns the broker which leads most partitions, the one with the lowest node id on a tie.
+// Losing it moves the most leaders.
+func mostLeadingBroker(leaders map[int32]int32) int32 {
+	counts := map[int32]int{}
+	for _, nodeId := range leaders {
+		counts[nodeId]++
+	}
+	nodeIds := make([]int32, 0, len(counts))
+	for nodeId := range counts {
+		nodeIds = append(nodeIds, nodeId)
+	}
+	sort.Slice(nodeIds, func(i, j int) bool {
+		if counts[nodeIds[i]] != counts[nodeIds[j]] {
+			return counts[nodeIds[i]] > counts[nodeIds[j]]
+		}
+		return nodeIds[i] < nodeIds[j]
+	})
+	return nodeIds[0]
+}
+
+// leadershipMoved reports whether every partition the broker led before has another leader now.
+func leadershipMoved(before map[int32]int32, after map[int32]int32, nodeId int32) bool {
+	for partitionId, leader := range before {
+		if leader != nodeId {
+			continue
+		}
+		if newLeader, ok := after[partitionId]; !ok || newLeader == nodeId {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *integrationSuite) sendTopology(client zbc.Client) (*pb.TopologyResponse, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	return client.NewTopologyCommand().Send(ctx)
+}
+
+// evictPod evicts the pod through the Eviction API, which respects the PodDisruptionBudgets of the namespace.
+func (s *integrationSuite) evictPod(name string) error {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	if err != nil {
+		return err
+	}
+	eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace}}
+	return clientset.PolicyV1().Evictions(s.namespace).Evict(context.Background(), eviction)
+}
+
+// assertEvictionBlocked checks that the PodDisruptionBudget of the brokers rejects evicting the pod.
+func (s *integrationSuite) assertEvictionBlocked(name string) {
+	err := s.evictPod(name)
+	s.Require().Error(err, "expected the PodDisruptionBudget to block evicting %s", name)
+	s.Require().True(apierrors.IsTooManyRequests(err), "expected the eviction of %s to be rejected by the PodDisruptionBudget, but got: %v", name, err)
+	s.T().Logf("Eviction of %s was blocked: %v", name, err)
+}
+
+// writeDataMarker writes a marker with the value to the data volume of the broker.
+func (s *integrationSuite) writeDataMarker(podName string, value string) {
+	_, err := k8s.RunKubectlAndGetOutputE(s.T(), s.kubeOptions, "exec", podName, "-c", "zeebe", "--",
+		"sh", "-c", fmt.Sprintf("echo %s > %s", value, kZeebeDataMarker))
+	s.Require().NoError(err, "cannot write data marker to %s", podName)
+}
+
+func (s *integrationSuite) assertDataMarker(podName string, value string) {
+	output, err := k8s.RunKubectlAndGetOutputE(s.T(), s.kubeOptions, "exec", podName, "-c", "zeebe", "--", "cat", kZeebeDataMarker)
+	s.Require().NoError(err, "cannot read data marker of %s, the broker didn't rejoin with its data", podName)
+	s.Require().Equal(value, strings.TrimSpace(output), "the data volume of %s has another marker", podName)
+}
+
+// dataClaimUID returns the UID of the persistent volume claim the broker pod stores its data on.
+func (s *integrationSuite) dataClaimUID(podName string) types.UID {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err)
+	claim, err := clientset.CoreV1().PersistentVolumeClaims(s.namespace).Get(context.Background(), "data-"+podName, metav1.GetOptions{})
+	s.Require().NoError(err, "cannot get data volume claim of %s", podName)
+	return claim.UID
+}
+
+// awaitLeadershipMoved waits until other brokers took over all partitions the broker led.
+func (s *integrationSuite) awaitLeadershipMoved(client zbc.Client, before map[int32]int32, nodeId int32) {
+	message := retry.DoWithRetry(s.T(), "Wait for the partition leadership to move", 60, 2*time.Second, func() (string, error) {
+		topology, err := s.sendTopology(client)
+		if err != nil {
+			return "", err
+		}
+		after := partitionLeaders(topology)
+		if !leadershipMoved(before, after, nodeId) {
+			return "", fmt.Errorf("partitions of broker %d have no new leader yet: %v", nodeId, after)
+		}
+		return fmt.Sprintf("Partition leaders moved from %v to %v.", before, after), nil
+	})
+	s.T().Logf(message)
+}
+
+// awaitPodReplaced waits until the pod was recreated and is ready again.
+func (s *integrationSuite) awaitPodReplaced(name string, oldUID types.UID) {
+	message := retry.DoWithRetry(s.T(), "Wait for "+name+" to be recreated", 60, 10*time.Second, func() (string, error) {
+		pod, err := k8s.GetPodE(s.T(), s.kubeOptions, name)
+		if err != nil {
+			return "", err
+		}
+		if pod.UID == oldUID {
+			return "", fmt.Errorf("pod %s wasn't recreated yet", name)
+		}
+		if !k8s.IsPodAvailable(pod) {
+			return "", fmt.Errorf("pod %s isn't ready yet", name)
+		}
+		return fmt.Sprintf("Pod %s was recreated and is ready.", name), nil
+	})
+	s.T().Logf(message)
+}
+
+// assertBrokerFailureResilience evicts the broker leading most partitions while a workload runs. The
+// PodDisruptionBudget has to block evicting a second broker, the other brokers have to take over its partitions,
+// and the broker has to rejoin with its data. The workload has to finish without losing instances.
+func (s *integrationSuite) assertBrokerFailureResilience() {
+	client, closeFn, err := s.createPortForwardedClient(s.zeebeGatewayServiceName())
+	s.Require().NoError(err, "failed to create Zeebe client")
+	defer closeFn()
+
+	topology, err := s.sendTopology(client)
+	s.Require().NoError(err, "failed to obtain gateway topology")
+	leaders := partitionLeaders(topology)
+	s.Require().NotEmpty(leaders, "no partition has a leader")
+	victim := mostLeadingBroker(leaders)
+	victimPod := s.zeebePodName(victim)
+	otherPod := s.zeebePodName((victim + 1) % topology.ClusterSize)
+
+	pod := k8s.GetPod(s.T(), s.kubeOptions, victimPod)
+	claimUID := s.dataClaimUID(victimPod)
+	s.writeDataMarker(victimPod, string(pod.UID))
+
+	running := s.startWorkload(defaultWorkloadModels)
+
+	s.T().Logf("Evicting broker %d (%s), leader of partitions %v", victim, victimPod, leaders)
+	s.Require().NoError(s.evictPod(victimPod), "failed to evict %s", victimPod)
+	s.assertEvictionBlocked(otherPod)
+	s.awaitLeadershipMoved(client, leaders, victim)
+
+	s.awaitPodReplaced(victimPod, pod.UID)
+	s.Require().Equal(claimUID, s.dataClaimUID(victimPod), "%s was recreated with another data volume claim", victimPod)
+	s.assertDataMarker(victimPod, string(pod.UID))
+	s.assertGatewayTopology(nil, client)
+
+	expectations := s.awaitWorkload(running)
+	s.assertWorkloadInOperate(expectations)
+}
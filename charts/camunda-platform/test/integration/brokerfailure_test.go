@@ -0,0 +1,73 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionLeadersShouldReturnLeaderPerPartition(t *testing.T) {
+	t.Parallel()
+
+	// given
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+
+	// when
+	leaders := partitionLeaders(topology)
+
+	// then
+	require.Equal(t, map[int32]int32{1: 0, 2: 1, 3: 2}, leaders)
+}
+
+func TestMostLeadingBrokerShouldPreferBrokerWithMostPartitions(t *testing.T) {
+	t.Parallel()
+
+	// given
+	leaders := map[int32]int32{1: 2, 2: 1, 3: 2}
+
+	// when
+	nodeId := mostLeadingBroker(leaders)
+
+	// then
+	require.EqualValues(t, 2, nodeId)
+}
+
+func TestMostLeadingBrokerShouldPreferLowestNodeIdOnTie(t *testing.T) {
+	t.Parallel()
+
+	// given
+	leaders := map[int32]int32{1: 2, 2: 1, 3: 0}
+
+	// when
+	nodeId := mostLeadingBroker(leaders)
+
+	// then
+	require.EqualValues(t, 0, nodeId)
+}
+
+func TestLeadershipMovedShouldRequireNewLeaderForAllPartitionsOfBroker(t *testing.T) {
+	t.Parallel()
+
+	// given
+	before := map[int32]int32{1: 0, 2: 1, 3: 0}
+
+	// then
+	require.True(t, leadershipMoved(before, map[int32]int32{1: 1, 2: 1, 3: 2}, 0))
+	require.False(t, leadershipMoved(before, map[int32]int32{1: 1, 2: 1, 3: 0}, 0), "partition 3 is still led by broker 0")
+	require.False(t, leadershipMoved(before, map[int32]int32{1: 1, 2: 1}, 0), "partition 3 has no leader")
+	require.True(t, leadershipMoved(before, map[int32]int32{1: 2, 3: 1}, 0), "partition 2 wasn't led by broker 0")
+}
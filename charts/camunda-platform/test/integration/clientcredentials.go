@@ -0,0 +1,177 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+// componentApi describes how a component is called with an access token of its own Identity client.
+type componentApi struct {
+	service       string
+	clientId      string
+	secretSuffix  string
+	secretKey     string
+	audience      string
+	containerPort int
+	method        string
+	path          string
+	body          string
+}
+
+var componentApis = []componentApi{
+	{
+		service: "operate", clientId: "operate", secretSuffix: "-operate-identity-secret", secretKey: "operate-secret",
+		audience: "operate-api", containerPort: 8080,
+		method: http.MethodPost, path: "/v1/process-definitions/search", body: "{}",
+	},
+	{
+		service: "tasklist", clientId: "tasklist", secretSuffix: "-tasklist-identity-secret", secretKey: "tasklist-secret",
+		audience: "tasklist-api", containerPort: 8080,
+		method: http.MethodPost, path: "/graphql", body: `{"query": "{tasks(query: {}) {id}}"}`,
+	},
+	{
+		service: "optimize", clientId: "optimize", secretSuffix: "-optimize-identity-secret", secretKey: "optimize-secret",
+		audience: "optimize-api", containerPort: 8090,
+		method: http.MethodGet, path: "/api/public/dashboard?collectionId=it-test-collection",
+	},
+}
+
+// requestClientToken requests an access token for the client of the component and checks its claims.
+func (s *integrationSuite) requestClientToken(issuerUrl string, api componentApi, clientSecret string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := oidc.ClientCredentials(ctx, &http.Client{Transport: s.httpTransport()}, oidc.TokenURL(issuerUrl), api.clientId, clientSecret)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := oidc.ParseClaims(token)
+	if err != nil {
+		return "", err
+	}
+	if !claims.Audience.Contains(api.audience) {
+		return "", fmt.Errorf("expected token of client '%s' to have the audience '%s', but got %v", api.clientId, api.audience, claims.Audience)
+	}
+	if claims.AuthorizedParty != api.clientId {
+		return "", fmt.Errorf("expected token to be issued for client '%s', but got '%s'", api.clientId, claims.AuthorizedParty)
+	}
+	return token, nil
+}
+
+// callApiWithToken calls the API of the component with the bearer token and returns the response status code.
+func (s *integrationSuite) callApiWithToken(endpoint string, api componentApi, token string) (int, error) {
+	var body io.Reader
+	if api.body != "" {
+		body = strings.NewReader(api.body)
+	}
+	request, err := http.NewRequest(api.method, s.webScheme()+"://"+endpoint+api.path, body)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: s.httpTransport()}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	return response.StatusCode, nil
+}
+
+func isAuthenticationRejected(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
+// verifyClientCredentialsAccess checks for every component that its client gets a token with the expected audience,
+// that the token is accepted by the component and not by the next one, and that a wrong secret is rejected.
+// The endpoints are the host and port of the components by service name.
+func (s *integrationSuite) verifyClientCredentialsAccess(issuerUrl string, apis []componentApi, secrets map[string]string, endpoints map[string]string) error {
+	tokens := map[string]string{}
+	for _, api := range apis {
+		token, err := s.requestClientToken(issuerUrl, api, secrets[api.clientId])
+		if err != nil {
+			return err
+		}
+		tokens[api.clientId] = token
+
+		statusCode, err := s.callApiWithToken(endpoints[api.service], api, token)
+		if err != nil {
+			return err
+		}
+		if statusCode != http.StatusOK {
+			return fmt.Errorf("expected %s to accept the token of client '%s', but got status code %d", api.service, api.clientId, statusCode)
+		}
+
+		_, err = s.requestClientToken(issuerUrl, api, secrets[api.clientId]+"-wrong")
+		var tokenError *oidc.TokenError
+		if !errors.As(err, &tokenError) || tokenError.StatusCode != http.StatusUnauthorized {
+			return fmt.Errorf("expected the token request of client '%s' with a wrong secret to be rejected with 401, but got: %v", api.clientId, err)
+		}
+	}
+
+	for i, api := range apis {
+		other := apis[(i+1)%len(apis)]
+		statusCode, err := s.callApiWithToken(endpoints[api.service], api, tokens[other.clientId])
+		if err != nil {
+			return err
+		}
+		if !isAuthenticationRejected(statusCode) {
+			return fmt.Errorf("expected %s to reject the token of client '%s', but got status code %d", api.service, other.clientId, statusCode)
+		}
+	}
+	return nil
+}
+
+func (s *integrationSuite) assertClientCredentialsApiAccess() {
+	// the issuer in the tokens has to match the publicIssuerUrl, so Keycloak is called through its pinned port-forward
+	// or the ingress
+	issuerUrl := s.webScheme() + "://" + s.webEndpoint("keycloak", 8080) + kRealmPath
+
+	secrets := map[string]string{}
+	endpoints := map[string]string{}
+	for _, api := range componentApis {
+		secrets[api.clientId] = s.getSecret(api.secretSuffix, api.secretKey)
+		endpoints[api.service] = s.webEndpoint(api.service, api.containerPort)
+	}
+
+	message := retry.DoWithRetry(s.T(),
+		"Try to call the component APIs with client credentials tokens",
+		10,
+		10*time.Second,
+		func() (string, error) {
+			if err := s.verifyClientCredentialsAccess(issuerUrl, componentApis, secrets, endpoints); err != nil {
+				return "", err
+			}
+			return "Component APIs accepted only their own client tokens", nil
+		})
+	s.T().Logf(message)
+}
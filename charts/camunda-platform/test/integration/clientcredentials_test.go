@@ -0,0 +1,135 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/fake"
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type clientCredentialsTest struct {
+	suite.Suite
+	keycloak  *fake.Keycloak
+	apps      []*fake.App
+	secrets   map[string]string
+	endpoints map[string]string
+}
+
+func TestClientCredentials(t *testing.T) {
+	t.Parallel()
+
+	suite.Run(t, &clientCredentialsTest{})
+}
+
+func (s *clientCredentialsTest) SetupTest() {
+	s.keycloak = fake.NewKeycloak(fake.LoginPageLegacy)
+	s.apps = []*fake.App{fake.NewOperate(s.keycloak), fake.NewTasklist(s.keycloak), fake.NewOptimize(s.keycloak)}
+	s.secrets = map[string]string{}
+	s.endpoints = map[string]string{}
+	for _, api := range componentApis {
+		s.secrets[api.clientId] = api.clientId + "-it-secret"
+		s.keycloak.Clients[api.clientId] = fake.Client{Secret: s.secrets[api.clientId], Audience: api.audience}
+	}
+	for _, app := range s.apps {
+		s.endpoints[app.Name] = app.Endpoint()
+	}
+}
+
+func (s *clientCredentialsTest) TearDownTest() {
+	for _, app := range s.apps {
+		app.Close()
+	}
+	s.keycloak.Close()
+}
+
+func (s *clientCredentialsTest) integrationSuite() *integrationSuite {
+	it := &integrationSuite{}
+	it.SetT(s.T())
+	return it
+}
+
+func (s *clientCredentialsTest) TestRequestClientTokenShouldHaveAudience() {
+	// given
+	it := s.integrationSuite()
+
+	for _, api := range componentApis {
+		// when
+		token, err := it.requestClientToken(s.keycloak.RealmURL(), api, s.secrets[api.clientId])
+
+		// then
+		s.Require().NoError(err)
+		claims, err := oidc.ParseClaims(token)
+		s.Require().NoError(err)
+		s.Require().Equal(s.keycloak.RealmURL(), claims.Issuer)
+		s.Require().Equal(oidc.Audience{api.audience}, claims.Audience)
+	}
+}
+
+func (s *clientCredentialsTest) TestRequestClientTokenWithWrongSecretShouldBeRejected() {
+	// given
+	it := s.integrationSuite()
+
+	// when
+	_, err := it.requestClientToken(s.keycloak.RealmURL(), componentApis[0], "wrong-secret")
+
+	// then
+	var tokenError *oidc.TokenError
+	s.Require().True(errors.As(err, &tokenError), "expected a token error but got: %v", err)
+	s.Require().Equal(http.StatusUnauthorized, tokenError.StatusCode)
+}
+
+func (s *clientCredentialsTest) TestRequestClientTokenWithUnexpectedAudienceShouldFail() {
+	// given
+	it := s.integrationSuite()
+	s.keycloak.Clients["operate"] = fake.Client{Secret: s.secrets["operate"], Audience: "tasklist-api"}
+
+	// when
+	_, err := it.requestClientToken(s.keycloak.RealmURL(), componentApis[0], s.secrets["operate"])
+
+	// then
+	s.Require().ErrorContains(err, "to have the audience 'operate-api'")
+}
+
+func (s *clientCredentialsTest) TestCallApiWithTokenOfOtherComponentShouldBeRejected() {
+	// given
+	it := s.integrationSuite()
+	operate, tasklist := componentApis[0], componentApis[1]
+	token, err := it.requestClientToken(s.keycloak.RealmURL(), tasklist, s.secrets[tasklist.clientId])
+	s.Require().NoError(err)
+
+	// when
+	statusCode, err := it.callApiWithToken(s.endpoints[operate.service], operate, token)
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusUnauthorized, statusCode)
+}
+
+func (s *clientCredentialsTest) TestVerifyClientCredentialsAccess() {
+	// given
+	it := s.integrationSuite()
+
+	// when
+	err := it.verifyClientCredentialsAccess(s.keycloak.RealmURL(), componentApis, s.secrets, s.endpoints)
+
+	// then
+	s.Require().NoError(err)
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	kClusterLockPrefix       = "camunda-platform-it-lock-"
+	kClusterLockDuration     = 10 * time.Minute
+	kClusterLockPollInterval = 2 * time.Second
+	kClusterLockTimeout      = 15 * time.Minute
+)
+
+// clusterLock is a lock over all tests running against a cluster, also from other processes and machines. It's a
+// Lease which exists as long as the lock is held, a Lease of a crashed test expires after its duration.
+type clusterLock struct {
+	client       kubernetes.Interface
+	namespace    string
+	name         string
+	holder       string
+	duration     time.Duration
+	pollInterval time.Duration
+}
+
+func newClusterLock(client kubernetes.Interface, namespace string, name string, holder string) *clusterLock {
+	return &clusterLock{
+		client:       client,
+		namespace:    namespace,
+		name:         kClusterLockPrefix + strings.ToLower(name),
+		holder:       holder,
+		duration:     kClusterLockDuration,
+		pollInterval: kClusterLockPollInterval,
+	}
+}
+
+// Acquire blocks until the lock is held or the context is done.
+func (l *clusterLock) Acquire(ctx context.Context) error {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	for {
+		durationSeconds := int32(l.duration.Seconds())
+		now := metav1.NewMicroTime(time.Now())
+		lease := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: l.name, Namespace: l.namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.holder,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+			},
+		}
+		_, err := leases.Create(ctx, lease, metav1.CreateOptions{})
+		if err == nil {
+			return nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("cannot create lease %s: %w", l.name, err)
+		}
+
+		existing, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+		if err == nil && isLeaseExpired(existing) {
+			// the holder didn't release the lock in time, probably it crashed; the precondition makes sure
+			// only one of the waiting tests deletes it
+			resourceVersion := existing.ResourceVersion
+			_ = leases.Delete(ctx, l.name, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{ResourceVersion: &resourceVersion},
+			})
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			holder := "unknown"
+			if existing != nil && existing.Spec.HolderIdentity != nil {
+				holder = *existing.Spec.HolderIdentity
+			}
+			return fmt.Errorf("cannot acquire lock %s held by %s: %w", l.name, holder, ctx.Err())
+		case <-time.After(l.pollInterval):
+		}
+	}
+}
+
+// Release releases the lock, if it is still held by this holder.
+func (l *clusterLock) Release(ctx context.Context) error {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	existing, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Spec.HolderIdentity == nil || *existing.Spec.HolderIdentity != l.holder {
+		return nil
+	}
+	resourceVersion := existing.ResourceVersion
+	return leases.Delete(ctx, l.name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{ResourceVersion: &resourceVersion},
+	})
+}
+
+func isLeaseExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.AcquireTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expiry := lease.Spec.AcquireTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return time.Now().After(expiry)
+}
+
+// clusterKubeOptions are the options for cluster-scoped objects, without the namespace of the test.
+func (s *integrationSuite) clusterKubeOptions() *k8s.KubectlOptions {
+	return k8s.NewKubectlOptions(s.kubeOptions.ContextName, s.kubeOptions.ConfigPath, "")
+}
+
+// withClusterLock runs fn while holding the named lock over all tests running against the cluster.
+func (s *integrationSuite) withClusterLock(name string, fn func()) {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err, "cannot create Kubernetes client for the cluster lock")
+
+	lock := newClusterLock(clientset, getEnv("CAMUNDA_DISTRO_TEST_LOCK_NAMESPACE", "default"), name, s.namespace)
+	ctx, cancelFn := context.WithTimeout(context.Background(), kClusterLockTimeout)
+	defer cancelFn()
+	s.Require().NoError(lock.Acquire(ctx))
+	defer func() {
+		if err := lock.Release(context.Background()); err != nil {
+			s.T().Logf("Failed to release cluster lock %s: %v", name, err)
+		}
+	}()
+	fn()
+}
+
+// ensureClusterObject applies a cluster-scoped object like a CRD or an IngressClass if it doesn't exist yet. All tests
+// share these objects, so they are created under a cluster lock and are never deleted by a test.
+func (s *integrationSuite) ensureClusterObject(kind string, name string, manifestPath string) {
+	s.withClusterLock(kind+"-"+name, func() {
+		options := s.clusterKubeOptions()
+		if _, err := k8s.RunKubectlAndGetOutputE(s.T(), options, "get", kind, name); err == nil {
+			s.T().Logf("Cluster-scoped %s %s already exists, reusing it", kind, name)
+			return
+		}
+		k8s.KubectlApply(s.T(), options, manifestPath)
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestClusterLock(client *fake.Clientset, holder string) *clusterLock {
+	lock := newClusterLock(client, "default", "IngressClass-nginx", holder)
+	lock.pollInterval = 10 * time.Millisecond
+	return lock
+}
+
+func TestClusterLockShouldBeExclusive(t *testing.T) {
+	t.Parallel()
+
+	// given
+	client := fake.NewSimpleClientset()
+	first := newTestClusterLock(client, "test-a")
+	second := newTestClusterLock(client, "test-b")
+	require.NoError(t, first.Acquire(context.Background()))
+
+	// when
+	ctx, cancelFn := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFn()
+	err := second.Acquire(ctx)
+
+	// then
+	require.ErrorContains(t, err, "held by test-a")
+}
+
+func TestClusterLockShouldBeAcquiredAfterRelease(t *testing.T) {
+	t.Parallel()
+
+	// given
+	client := fake.NewSimpleClientset()
+	first := newTestClusterLock(client, "test-a")
+	second := newTestClusterLock(client, "test-b")
+	require.NoError(t, first.Acquire(context.Background()))
+
+	// when
+	require.NoError(t, second.Release(context.Background()), "releasing a lock held by another test is a no-op")
+	require.NoError(t, first.Release(context.Background()))
+	err := second.Acquire(context.Background())
+
+	// then
+	require.NoError(t, err)
+	lease, err := client.CoordinationV1().Leases("default").Get(context.Background(), second.name, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "test-b", *lease.Spec.HolderIdentity)
+	require.Equal(t, "camunda-platform-it-lock-ingressclass-nginx", lease.Name)
+}
+
+func TestClusterLockShouldTakeOverExpiredLease(t *testing.T) {
+	t.Parallel()
+
+	// given
+	holder := "crashed-test"
+	durationSeconds := int32(60)
+	acquireTime := metav1.NewMicroTime(time.Now().Add(-time.Hour))
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "camunda-platform-it-lock-ingressclass-nginx", Namespace: "default"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &durationSeconds,
+			AcquireTime:          &acquireTime,
+		},
+	})
+	lock := newTestClusterLock(client, "test-a")
+
+	// when
+	ctx, cancelFn := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFn()
+	err := lock.Acquire(ctx)
+
+	// then
+	require.NoError(t, err)
+}
@@ -23,7 +23,6 @@ import (
 	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
 	"github.com/cenkalti/backoff"
 	"github.com/gruntwork-io/terratest/modules/k8s"
-	"google.golang.org/grpc"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -64,59 +63,22 @@ func (s *integrationSuite) waitUntilServiceAvailable(serviceName string) {
 	s.waitUntil("Service", serviceName, serviceIsAvailable, "Available")
 }
 
-func (s *integrationSuite) waitUntilPortForwarded(tunnel *k8s.Tunnel, serviceName string) {
-	s.T().Logf("Waiting to port forward for endpoint %s", tunnel.Endpoint())
-	portForwardedIsReady := func() bool {
-		if err := tunnel.ForwardPortE(s.T()); err != nil {
-			return false
-		}
-		return true
-	}
-	s.waitUntil("PortForward", serviceName, portForwardedIsReady, "Ready")
-}
-
 func (s *integrationSuite) createPortForwardedClient(serviceName string) (zbc.Client, func(), error) {
-	s.waitUntilServiceAvailable(serviceName)
-
-	// port forward the gateway service to avoid having to set up a public endpoint that the test can access externally
-	localGatewayPort := k8s.GetAvailablePort(s.T())
-	tunnel := k8s.NewTunnel(s.kubeOptions, k8s.ResourceTypeService, serviceName, localGatewayPort, 26500)
-
+	// port forward the gateway service to avoid having to set up a public endpoint that the test can access externally,
 	// the gateway is not ready/receiving traffic until at least one leader is present
-	s.waitUntilPortForwarded(tunnel, serviceName)
-
-	endpoint := fmt.Sprintf("localhost:%d", localGatewayPort)
-	client, err := zbc.NewClient(&zbc.ClientConfig{
-		GatewayAddress:         endpoint,
-		DialOpts:               []grpc.DialOption{},
-		UsePlaintextConnection: true,
-	})
+	// the client connects with the security settings the gateway is installed with
+	endpoint := s.portForward(serviceName, kZeebeGatewayPort)
+	config, err := s.zeebeClientConfig(endpoint, s.zeebeGatewaySecurity())
 	if err != nil {
-		return nil, tunnel.Close, err
+		return nil, func() {}, err
+	}
+	client, err := zbc.NewClient(config)
+	if err != nil {
+		return nil, func() {}, err
 	}
 
-	return client, func() { client.Close(); tunnel.Close() }, nil
-}
-
-func (s *integrationSuite) createPortForwardedHttpClientWithPort(serviceName string, port int) (string, func()) {
-	return s.createPortForwardedHttpClientWithPortAndContainerPort(serviceName, port, 8080)
-}
-
-func (s *integrationSuite) createPortForwardedHttpClientWithPortAndContainerPort(serviceName string, port int, containerPort int) (string, func()) {
-	s.waitUntilServiceAvailable(serviceName)
-
-	// remote port needs to be container port - not service port!
-	tunnel := k8s.NewTunnel(s.kubeOptions, k8s.ResourceTypeService, serviceName, port, containerPort)
-
-	// the gateway is not ready/receiving traffic until at least one leader is present
-	s.waitUntilPortForwarded(tunnel, serviceName)
-
-	endpoint := fmt.Sprintf("localhost:%d", port)
-	return endpoint, tunnel.Close
-}
-
-func (s *integrationSuite) createPortForwardedHttpClient(serviceName string) (string, func()) {
-	return s.createPortForwardedHttpClientWithPort(serviceName, k8s.GetAvailablePort(s.T()))
+	// the tunnel is shared and closed with the test
+	return client, func() { client.Close() }, nil
 }
 
 func (s *integrationSuite) createHttpClientWithJar() (http.Client, *cookiejar.Jar, error) {
@@ -126,8 +88,9 @@ func (s *integrationSuite) createHttpClientWithJar() (http.Client, *cookiejar.Ja
 		return http.Client{}, nil, err
 	}
 	httpClient := http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
+		Jar:       jar,
+		Timeout:   30 * time.Second,
+		Transport: s.httpTransport(),
 	}
 	return httpClient, jar, nil
 }
@@ -0,0 +1,259 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+)
+
+const IdentityJWTCookie = "IDENTITY_JWT"
+
+// App is a stand-in for a Camunda Platform web application which logs in users through Keycloak.
+type App struct {
+	Server *httptest.Server
+	Name   string
+	// Audience is the audience an access token of a client needs to call the API of the application,
+	// empty if the application doesn't accept client tokens.
+	Audience string
+
+	keycloak     *Keycloak
+	loginPath    string
+	callbackPath string
+	cookieName   string
+
+	mu       sync.Mutex
+	sessions map[string]string
+}
+
+func newApp(keycloak *Keycloak, name string, loginPath string, callbackPath string, cookieName string) *App {
+	app := &App{
+		Name:         name,
+		keycloak:     keycloak,
+		loginPath:    loginPath,
+		callbackPath: callbackPath,
+		cookieName:   cookieName,
+		sessions:     map[string]string{},
+	}
+	app.Server = httptest.NewUnstartedServer(http.NotFoundHandler())
+	return app
+}
+
+func (a *App) start(mux *http.ServeMux) *App {
+	mux.HandleFunc(a.callbackPath, a.handleCallback)
+	a.Server.Config.Handler = mux
+	a.Server.Start()
+	return a
+}
+
+// NewIdentity starts an Identity stand-in, which stores the JWT of the logged-in user in the IDENTITY_JWT cookie.
+func NewIdentity(keycloak *Keycloak) *App {
+	app := newApp(keycloak, "identity", "/auth/login", "/auth/login-callback", IdentityJWTCookie)
+	mux := http.NewServeMux()
+	mux.HandleFunc(app.loginPath, app.handleLogin)
+	mux.HandleFunc("/", app.authenticated(app.handleIndex))
+	mux.HandleFunc("/api/clients", app.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{
+			{"id": "operate", "name": "Operate", "clientId": "operate", "type": "CONFIDENTIAL"},
+			{"id": "tasklist", "name": "Tasklist", "clientId": "tasklist", "type": "CONFIDENTIAL"},
+			{"id": "optimize", "name": "Optimize", "clientId": "optimize", "type": "CONFIDENTIAL"},
+		})
+	}))
+	return app.start(mux)
+}
+
+// NewOperate starts an Operate stand-in which answers the process definition search with canned data.
+func NewOperate(keycloak *Keycloak) *App {
+	app := newApp(keycloak, "operate", "/", "/identity-callback", "OPERATE-SESSION")
+	app.Audience = "operate-api"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", app.handleLogin)
+	mux.HandleFunc("/v1/process-definitions/search", app.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"items": []map[string]interface{}{
+				{"key": 2251799813685249, "name": nil, "version": 1, "bpmnProcessId": "it-test-process"},
+			},
+			"sortValues": []interface{}{2251799813685249},
+			"total":      1,
+		})
+	}))
+	return app.start(mux)
+}
+
+// NewTasklist starts a Tasklist stand-in which answers GraphQL task queries and mutations with canned data.
+func NewTasklist(keycloak *Keycloak) *App {
+	app := newApp(keycloak, "tasklist", "/", "/identity-callback", "TASKLIST-SESSION")
+	app.Audience = "tasklist-api"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", app.handleLogin)
+	mux.HandleFunc("/graphql", app.authenticated(app.handleGraphql))
+	return app.start(mux)
+}
+
+// NewOptimize starts an Optimize stand-in which answers the public dashboard API with an empty list.
+func NewOptimize(keycloak *Keycloak) *App {
+	app := newApp(keycloak, "optimize", "/", "/api/authentication/callback", "X-Optimize-Authorization")
+	app.Audience = "optimize-api"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", app.handleLogin)
+	mux.HandleFunc("/api/public/dashboard", app.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("collectionId") == "" {
+			http.Error(w, "collectionId is required", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, []interface{}{})
+	}))
+	return app.start(mux)
+}
+
+// URL is the base URL of the server, it uses "localhost" like the port-forwards of the integration tests.
+func (a *App) URL() string {
+	return localhostURL(a.Server)
+}
+
+// Endpoint is the host and port of the server, as returned by the port-forward helpers.
+func (a *App) Endpoint() string {
+	return strings.TrimPrefix(a.URL(), "http://")
+}
+
+func (a *App) Close() {
+	a.Server.Close()
+}
+
+func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if a.session(r) != "" {
+		a.handleIndex(w, r)
+		return
+	}
+	http.Redirect(w, r, a.keycloak.AuthURL(a.Name, a.URL()+a.callbackPath, randomId()), http.StatusFound)
+}
+
+func (a *App) handleCallback(w http.ResponseWriter, r *http.Request) {
+	token, ok := a.keycloak.ExchangeCode(r.URL.Query().Get("code"), a.Name)
+	if !ok {
+		http.Error(w, "invalid authorization code", http.StatusUnauthorized)
+		return
+	}
+
+	sessionId := token
+	if a.cookieName != IdentityJWTCookie {
+		sessionId = randomId()
+	}
+	a.mu.Lock()
+	a.sessions[sessionId] = token
+	a.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: a.cookieName, Value: sessionId, Path: "/", HttpOnly: true})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (a *App) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	_, _ = io.WriteString(w, "<!doctype html><html><head><title>"+a.Name+"</title></head><body></body></html>")
+}
+
+// session returns the token of the request, taken either from the session cookie or from the bearer header.
+// A bearer token is accepted if it belongs to a session or if it is a client token for the audience of the application.
+func (a *App) session(r *http.Request) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cookie, err := r.Cookie(a.cookieName); err == nil {
+		if token, exists := a.sessions[cookie.Value]; exists {
+			return token
+		}
+	}
+	for _, header := range []string{"Authorization", "Authentication"} {
+		token := strings.TrimPrefix(r.Header.Get(header), "Bearer ")
+		if token == "" {
+			continue
+		}
+		if _, exists := a.sessions[token]; exists || a.acceptsClientToken(token) {
+			return token
+		}
+	}
+	return ""
+}
+
+func (a *App) acceptsClientToken(token string) bool {
+	if a.Audience == "" {
+		return false
+	}
+	claims, err := oidc.ParseClaims(token)
+	if err != nil {
+		return false
+	}
+	return claims.Issuer == a.keycloak.RealmURL() &&
+		claims.Audience.Contains(a.Audience) &&
+		claims.ExpiresAt > time.Now().Unix()
+}
+
+func (a *App) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.session(r) == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (a *App) handleGraphql(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task := map[string]interface{}{
+		"id":                "2251799813685256",
+		"name":              "It Test",
+		"taskState":         "CREATED",
+		"assignee":          nil,
+		"processInstanceId": "2251799813685251",
+	}
+	switch {
+	case strings.Contains(request.Query, "claimTask"):
+		task["assignee"] = DefaultUsername
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"claimTask": task}})
+	case strings.Contains(request.Query, "completeTask"):
+		task["assignee"] = DefaultUsername
+		task["taskState"] = "COMPLETED"
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"completeTask": task}})
+	case strings.Contains(request.Query, "tasks"):
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"tasks": []interface{}{task}}})
+	default:
+		writeJSON(w, map[string]interface{}{"errors": []map[string]string{{"message": "unsupported query"}}})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,377 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginPageFormat defines how the Keycloak login page is rendered.
+type LoginPageFormat int
+
+const (
+	// LoginPageLegacy is the login page of Keycloak v16 served under the "/auth" context path.
+	LoginPageLegacy LoginPageFormat = iota
+	// LoginPageV19 is the login page of Keycloak v19 served under the "/" context path.
+	LoginPageV19
+	// LoginPageCustomTheme is a login page of a custom theme, with reordered attributes spread over several lines
+	// and without the Keycloak form id, served under the "/" context path.
+	LoginPageCustomTheme
+)
+
+const (
+	Realm           = "camunda-platform"
+	DefaultUsername = "demo"
+	DefaultPassword = "demo"
+)
+
+var loginPageTemplates = map[LoginPageFormat]*template.Template{
+	LoginPageLegacy: template.Must(template.New("legacy").Parse(`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" class="login-pf">
+<head>
+    <meta charset="utf-8">
+    <title>Sign in to Camunda Platform</title>
+</head>
+<body class="">
+  <div class="login-pf-page">
+    <div id="kc-content">
+      <div id="kc-content-wrapper">
+        {{- if .Error }}
+        <div class="alert alert-error"><span class="kc-feedback-text">{{ .Error }}</span></div>
+        {{- end }}
+        <form id="kc-form-login" onsubmit="login.disabled = true; return true;" action="{{ .Action }}" method="post">
+            <input tabindex="1" id="username" class="pf-c-form-control" name="username" value="" type="text" autofocus autocomplete="off" />
+            <input tabindex="2" id="password" class="pf-c-form-control" name="password" type="password" autocomplete="off" />
+            <input tabindex="4" class="pf-c-button pf-m-primary pf-m-block btn-lg" name="login" id="kc-login" type="submit" value="Sign In"/>
+        </form>
+      </div>
+    </div>
+  </div>
+</body>
+</html>
+`)),
+	LoginPageV19: template.Must(template.New("v19").Parse(`<!DOCTYPE html>
+<html class="login-pf">
+<head>
+    <meta charset="utf-8">
+    <meta name="robots" content="noindex, nofollow">
+    <title>Sign in to Camunda Platform</title>
+    <script src="{{ .ResourcesPath }}/js/menu-button-links.js" type="module"></script>
+</head>
+<body class="">
+<div class="login-pf-page">
+    <div class="card-pf">
+        {{- if .Error }}
+        <div class="alert-error pf-c-alert pf-m-inline pf-m-danger">
+            <span class="pf-c-alert__title kc-feedback-text">{{ .Error }}</span>
+        </div>
+        {{- end }}
+        <div id="kc-form">
+          <div id="kc-form-wrapper">
+            <form id="kc-form-login" onsubmit="login.disabled = true; return true;" action="{{ .Action }}" method="post">
+                <div class="form-group">
+                    <label for="username" class="pf-c-form__label pf-c-form__label-text">Username or email</label>
+                    <input tabindex="1" id="username" class="pf-c-form-control" name="username" value="" type="text" autofocus autocomplete="off" aria-invalid="" />
+                </div>
+                <div class="form-group">
+                    <label for="password" class="pf-c-form__label pf-c-form__label-text">Password</label>
+                    <input tabindex="2" id="password" class="pf-c-form-control" name="password" type="password" autocomplete="off" aria-invalid="" />
+                </div>
+                <div id="kc-form-buttons" class="form-group">
+                    <input type="hidden" id="id-hidden-input" name="credentialId"/>
+                    <input tabindex="4" class="pf-c-button pf-m-primary pf-m-block btn-lg" name="login" id="kc-login" type="submit" value="Sign In"/>
+                </div>
+            </form>
+          </div>
+        </div>
+    </div>
+</div>
+</body>
+</html>
+`)),
+	LoginPageCustomTheme: template.Must(template.New("custom").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Camunda</title></head>
+<body>
+<form method="get" action="/search" class="search"><input type="text" name="q"></form>
+{{- if .Error }}
+<p role="alert"><span class="kc-feedback-text">{{ .Error }}</span></p>
+{{- end }}
+<form
+    method="post"
+    class="login-form"
+    action="{{ .Action }}"
+    novalidate="novalidate">
+  <input name="username" type="text">
+  <input name="password" type="password">
+  <input name="rememberMe" type="checkbox">
+  <button type="submit">Log in</button>
+</form>
+</body>
+</html>
+`)),
+}
+
+// Client is a confidential client which can request tokens with the client credentials grant.
+type Client struct {
+	Secret   string
+	Audience string
+}
+
+type loginSession struct {
+	redirectUri string
+	state       string
+	clientId    string
+}
+
+// Keycloak is a stand-in for the Keycloak browser login flow of the Camunda Platform realm.
+type Keycloak struct {
+	Server      *httptest.Server
+	Format      LoginPageFormat
+	ContextPath string
+	Username    string
+	Password    string
+	// Clients are the confidential clients of the realm by client id.
+	Clients map[string]Client
+
+	mu       sync.Mutex
+	sessions map[string]loginSession
+	codes    map[string]string
+}
+
+// NewKeycloak starts a Keycloak stand-in which serves the login page in the given format.
+func NewKeycloak(format LoginPageFormat) *Keycloak {
+	keycloak := &Keycloak{
+		Format:   format,
+		Username: DefaultUsername,
+		Password: DefaultPassword,
+		Clients:  map[string]Client{},
+		sessions: map[string]loginSession{},
+		codes:    map[string]string{},
+	}
+	if format == LoginPageLegacy {
+		keycloak.ContextPath = "/auth"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(keycloak.realmPath()+"/protocol/openid-connect/auth", keycloak.handleAuth)
+	mux.HandleFunc(keycloak.realmPath()+"/login-actions/authenticate", keycloak.handleAuthenticate)
+	mux.HandleFunc(keycloak.realmPath()+"/protocol/openid-connect/token", keycloak.handleToken)
+	keycloak.Server = httptest.NewServer(mux)
+	return keycloak
+}
+
+// URL is the base URL of the server, it uses "localhost" like the port-forwards of the integration tests.
+func (k *Keycloak) URL() string {
+	return localhostURL(k.Server)
+}
+
+// RealmURL is the issuer URL of the Camunda Platform realm.
+func (k *Keycloak) RealmURL() string {
+	return k.URL() + k.realmPath()
+}
+
+func (k *Keycloak) Close() {
+	k.Server.Close()
+}
+
+func (k *Keycloak) realmPath() string {
+	return k.ContextPath + "/realms/" + Realm
+}
+
+// AuthURL returns the URL an application redirects the browser to in order to log in.
+func (k *Keycloak) AuthURL(clientId string, redirectUri string, state string) string {
+	query := url.Values{
+		"client_id":     {clientId},
+		"redirect_uri":  {redirectUri},
+		"response_type": {"code"},
+		"scope":         {"openid"},
+		"state":         {state},
+	}
+	return k.RealmURL() + "/protocol/openid-connect/auth?" + query.Encode()
+}
+
+// ExchangeCode returns a token for an authorization code issued by a successful login, each code is valid once.
+func (k *Keycloak) ExchangeCode(code string, audience string) (string, bool) {
+	k.mu.Lock()
+	username, exists := k.codes[code]
+	delete(k.codes, code)
+	k.mu.Unlock()
+
+	if !exists {
+		return "", false
+	}
+	return k.IssueToken(username, audience, audience), true
+}
+
+// IssueToken creates an unsigned JWT with the claims the Camunda Platform components look at.
+func (k *Keycloak) IssueToken(subject string, clientId string, audience string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "none", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":                k.RealmURL(),
+		"sub":                subject,
+		"aud":                audience,
+		"azp":                clientId,
+		"preferred_username": subject,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+	})
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims) + "."
+}
+
+func (k *Keycloak) handleAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("redirect_uri") == "" || query.Get("client_id") == "" {
+		http.Error(w, "Invalid parameter: redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	sessionCode := randomId()
+	k.mu.Lock()
+	k.sessions[sessionCode] = loginSession{
+		redirectUri: query.Get("redirect_uri"),
+		state:       query.Get("state"),
+		clientId:    query.Get("client_id"),
+	}
+	k.mu.Unlock()
+
+	k.renderLoginPage(w, sessionCode, query.Get("client_id"), "")
+}
+
+func (k *Keycloak) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessionCode := r.URL.Query().Get("session_code")
+	k.mu.Lock()
+	session, exists := k.sessions[sessionCode]
+	k.mu.Unlock()
+	if !exists {
+		http.Error(w, "Your login attempt timed out. Login will start from the beginning.", http.StatusBadRequest)
+		return
+	}
+
+	if r.PostForm.Get("username") != k.Username || r.PostForm.Get("password") != k.Password {
+		// like Keycloak, a rejected login renders the login page again with an error message and status 200
+		k.renderLoginPage(w, sessionCode, session.clientId, "Invalid username or password.")
+		return
+	}
+
+	code := randomId()
+	k.mu.Lock()
+	delete(k.sessions, sessionCode)
+	k.codes[code] = r.PostForm.Get("username")
+	k.mu.Unlock()
+
+	redirect, err := url.Parse(session.redirectUri)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	query.Set("state", session.state)
+	redirect.RawQuery = query.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (k *Keycloak) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type "+grantType)
+		return
+	}
+
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client, exists := k.Clients[clientId]
+	if !exists || client.Secret != clientSecret {
+		// Keycloak answers an unknown client and a wrong secret the same way
+		writeTokenError(w, http.StatusUnauthorized, "unauthorized_client", "Invalid client secret")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": k.IssueToken("service-account-"+clientId, clientId, client.Audience),
+		"expires_in":   300,
+		"token_type":   "Bearer",
+		"scope":        "email profile",
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func (k *Keycloak) renderLoginPage(w http.ResponseWriter, sessionCode string, clientId string, loginError string) {
+	// keep the parameter order of Keycloak, url.Values would sort them
+	query := fmt.Sprintf("session_code=%s&execution=%s&client_id=%s&tab_id=%s",
+		sessionCode, randomId(), url.QueryEscape(clientId), randomId()[:11])
+	data := map[string]interface{}{
+		"Action":        template.URL(k.RealmURL() + "/login-actions/authenticate?" + query),
+		"ResourcesPath": k.ContextPath + "/resources/login/keycloak",
+		"Error":         loginError,
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	if err := loginPageTemplates[k.Format].Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func localhostURL(server *httptest.Server) string {
+	return strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+func randomId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("cannot generate random id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,517 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	kConnectivityPortForward = "port-forward"
+	kConnectivityIngress     = "ingress"
+	// kIngressStandInClass is the IngressClass of the stand-in controller, which the suite deploys into the test
+	// namespace if no ingress class of an existing controller is configured.
+	kIngressStandInClass    = "camunda-platform-it"
+	kIngressStandInPort     = 8080
+	kIngressStandInTLSPort  = 8443
+	kIngressStandInImage    = "nginxinc/nginx-unprivileged:1.23-alpine"
+	kNginxRewriteAnnotation = "nginx.ingress.kubernetes.io/rewrite-target"
+)
+
+// ingressRoute is a path of an ingress rule and the service it routes to.
+type ingressRoute struct {
+	host    string
+	path    string
+	service string
+	port    int32
+}
+
+func (r ingressRoute) String() string {
+	return fmt.Sprintf("%s%s -> %s:%d", r.host, r.path, r.service, r.port)
+}
+
+// usesIngress returns true if the web applications are reached through the ingress instead of port-forwards.
+func (s *integrationSuite) usesIngress() bool {
+	return s.options.connectivity == kConnectivityIngress
+}
+
+// usesIngressStandIn returns true if the suite deploys its own controller, because no ingress class is configured.
+func (s *integrationSuite) usesIngressStandIn() bool {
+	return s.options.ingressClass == ""
+}
+
+func (s *integrationSuite) ingressClass() string {
+	if s.usesIngressStandIn() {
+		return kIngressStandInClass
+	}
+	return s.options.ingressClass
+}
+
+// ingressHost returns the host of the combined ingress, it's unique per release since the tests share the controller.
+func (s *integrationSuite) ingressHost() string {
+	return s.release + "." + s.options.ingressDomain
+}
+
+// identityIngressHost returns the host of the separate Identity ingress, Identity can't use a context path over HTTP.
+func (s *integrationSuite) identityIngressHost() string {
+	return "identity." + s.ingressHost()
+}
+
+// webModelerIngressHosts returns the hosts of the Web Modeler webapp and websockets, which have their own ingress.
+func (s *integrationSuite) webModelerIngressHosts() (string, string) {
+	return "modeler." + s.ingressHost(), "websockets." + s.ingressHost()
+}
+
+// ingressHosts returns all hosts of the release, the server certificate is issued for them.
+func (s *integrationSuite) ingressHosts() []string {
+	modelerHost, websocketsHost := s.webModelerIngressHosts()
+	return []string{s.ingressHost(), s.identityIngressHost(), modelerHost, websocketsHost}
+}
+
+// ingressHostPort returns the host with the port the ingress is reached at, the stand-in is reached through a tunnel.
+func (s *integrationSuite) ingressHostPort(host string) string {
+	if s.usesIngressStandIn() {
+		return fmt.Sprintf("%s:%d", host, s.allocatePublicPorts()["ingress"])
+	}
+	return host
+}
+
+// ingressValues returns the values which expose the web applications through the ingress, the public URLs of the
+// release point to the ingress paths. With TLS all ingresses use the secret of the test CA.
+func (s *integrationSuite) ingressValues() map[string]string {
+	scheme := s.webScheme() + "://"
+	baseUrl := scheme + s.ingressHostPort(s.ingressHost())
+	modelerHost, websocketsHost := s.webModelerIngressHosts()
+	values := map[string]string{
+		"global.ingress.enabled":                      "true",
+		"global.ingress.className":                    s.ingressClass(),
+		"global.ingress.host":                         s.ingressHost(),
+		"operate.contextPath":                         "/operate",
+		"tasklist.contextPath":                        "/tasklist",
+		"optimize.contextPath":                        "/optimize",
+		"identity.ingress.enabled":                    "true",
+		"identity.ingress.className":                  s.ingressClass(),
+		"identity.ingress.host":                       s.identityIngressHost(),
+		"web-modeler.ingress.enabled":                 "true",
+		"web-modeler.ingress.className":               s.ingressClass(),
+		"web-modeler.ingress.webapp.host":             modelerHost,
+		"web-modeler.ingress.websockets.host":         websocketsHost,
+		"global.identity.auth.publicIssuerUrl":        baseUrl + kRealmPath,
+		"global.identity.auth.operate.redirectUrl":    baseUrl + "/operate",
+		"global.identity.auth.tasklist.redirectUrl":   baseUrl + "/tasklist",
+		"global.identity.auth.optimize.redirectUrl":   baseUrl + "/optimize",
+		"global.identity.auth.webModeler.redirectUrl": scheme + s.ingressHostPort(modelerHost),
+		"identity.fullURL":                            scheme + s.ingressHostPort(s.identityIngressHost()),
+	}
+	if s.usesTLS() {
+		for key, value := range s.tlsValues() {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// expectedIngressRoutes returns the path layout the ingress values above have to render.
+func (s *integrationSuite) expectedIngressRoutes() []ingressRoute {
+	routes := []ingressRoute{
+		{host: s.ingressHost(), path: "/auth", service: s.resolveKeycloakServiceName(), port: 80},
+		{host: s.ingressHost(), path: "/operate", service: s.serviceName("operate"), port: 80},
+		{host: s.ingressHost(), path: "/tasklist", service: s.serviceName("tasklist"), port: 80},
+		{host: s.ingressHost(), path: "/optimize", service: s.serviceName("optimize"), port: 80},
+		{host: s.identityIngressHost(), path: "/", service: s.serviceName("identity"), port: 80},
+	}
+	if s.hasWebModeler() {
+		modelerHost, websocketsHost := s.webModelerIngressHosts()
+		routes = append(routes,
+			ingressRoute{host: modelerHost, path: "/", service: s.serviceName("web-modeler-webapp"), port: 80},
+			ingressRoute{host: websocketsHost, path: "/", service: s.serviceName("web-modeler-websockets"), port: 80})
+	}
+	return routes
+}
+
+// hasWebModeler returns true if the release contains Web Modeler, which is only enabled by some tests.
+func (s *integrationSuite) hasWebModeler() bool {
+	if s.kubeOptions == nil {
+		return false
+	}
+	_, err := k8s.GetServiceE(s.T(), s.kubeOptions, s.serviceName("web-modeler-webapp"))
+	return err == nil
+}
+
+// webEndpoint returns the endpoint of the web API of a component as host, port and context path, either through a
+// tunnel to the container port or through the ingress.
+func (s *integrationSuite) webEndpoint(component string, containerPort int) string {
+	if s.usesIngress() {
+		return s.ingressEndpoint(component)
+	}
+	if component == "keycloak" {
+		return s.portForwardKeycloak()
+	}
+	return s.portForward(s.serviceName(component), containerPort)
+}
+
+// ingressEndpoint returns the ingress endpoint of a component, Keycloak serves the /auth path itself.
+func (s *integrationSuite) ingressEndpoint(component string) string {
+	s.connectIngress()
+	switch component {
+	case "keycloak":
+		return s.ingressHostPort(s.ingressHost())
+	case "identity":
+		return s.ingressHostPort(s.identityIngressHost())
+	case "web-modeler":
+		modelerHost, _ := s.webModelerIngressHosts()
+		return s.ingressHostPort(modelerHost)
+	default:
+		return s.ingressHostPort(s.ingressHost()) + "/" + component
+	}
+}
+
+// connectIngress checks the rendered ingress layout and resolves the address of the controller, once per test.
+func (s *integrationSuite) connectIngress() {
+	if s.ingressAddress != "" {
+		return
+	}
+
+	ingresses := s.getReleaseIngresses()
+	s.Require().NoError(verifyIngressLayout(ingresses, s.ingressClass(), s.tlsSecretName(), s.expectedIngressRoutes()),
+		"the rendered ingress doesn't match the expected path layout")
+
+	if s.usesIngressStandIn() {
+		s.deployIngressStandIn(ingresses)
+		port := kIngressStandInPort
+		if s.usesTLS() {
+			port = kIngressStandInTLSPort
+		}
+		s.ingressAddress = s.portForward(s.serviceName("ingress"), port)
+	} else {
+		s.ingressAddress = s.findIngressControllerAddress()
+	}
+	s.T().Logf("Ingress %s is reached through %s", s.ingressHost(), s.ingressAddress)
+}
+
+func (s *integrationSuite) getReleaseIngresses() []netv1.Ingress {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err)
+
+	ingresses, err := clientset.NetworkingV1().Ingresses(s.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/instance=" + s.release,
+	})
+	s.Require().NoError(err)
+	return ingresses.Items
+}
+
+// verifyIngressLayout checks that the ingresses route exactly the expected paths with the given class. Rewrites are
+// rejected, since the applications serve their context paths themselves. With a TLS secret every host has to be
+// served with that secret.
+func verifyIngressLayout(ingresses []netv1.Ingress, className string, tlsSecretName string, expected []ingressRoute) error {
+	var errs []string
+	actual := map[string]ingressRoute{}
+	tlsSecrets := map[string]string{}
+	for _, ingress := range ingresses {
+		for host, secretName := range ingressTLSHosts(ingress) {
+			tlsSecrets[host] = secretName
+		}
+		if ingress.Spec.IngressClassName == nil || *ingress.Spec.IngressClassName != className {
+			errs = append(errs, fmt.Sprintf("ingress %s doesn't use the ingress class %s", ingress.Name, className))
+		}
+		if target, ok := ingress.Annotations[kNginxRewriteAnnotation]; ok {
+			errs = append(errs, fmt.Sprintf("ingress %s rewrites the context paths to '%s'", ingress.Name, target))
+		}
+		for _, route := range ingressRoutes(ingress) {
+			actual[route.host+route.path] = route
+		}
+	}
+
+	for _, route := range expected {
+		found, ok := actual[route.host+route.path]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("missing route %s", route))
+			continue
+		}
+		if found != route {
+			errs = append(errs, fmt.Sprintf("expected route %s, but got %s", route, found))
+		}
+		if tlsSecretName != "" && tlsSecrets[route.host] != tlsSecretName {
+			errs = append(errs, fmt.Sprintf("expected host %s to be served with TLS secret '%s', but got '%s'",
+				route.host, tlsSecretName, tlsSecrets[route.host]))
+		}
+		delete(actual, route.host+route.path)
+	}
+	for _, route := range actual {
+		errs = append(errs, fmt.Sprintf("unexpected route %s", route))
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ingressRoutes returns the prefix paths of an ingress, other path types are not used by the chart.
+func ingressRoutes(ingress netv1.Ingress) []ingressRoute {
+	var routes []ingressRoute
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.PathType == nil || *path.PathType != netv1.PathTypePrefix || path.Backend.Service == nil {
+				continue
+			}
+			routes = append(routes, ingressRoute{
+				host:    rule.Host,
+				path:    path.Path,
+				service: path.Backend.Service.Name,
+				port:    path.Backend.Service.Port.Number,
+			})
+		}
+	}
+	return routes
+}
+
+// ingressTLSHosts returns the TLS secret by host of an ingress.
+func ingressTLSHosts(ingress netv1.Ingress) map[string]string {
+	hosts := map[string]string{}
+	for _, tls := range ingress.Spec.TLS {
+		for _, host := range tls.Hosts {
+			hosts[host] = tls.SecretName
+		}
+	}
+	return hosts
+}
+
+// deployIngressStandIn deploys an nginx which routes the ingress rules like a controller would. The IngressClass is
+// shared by all tests, the nginx runs in the test namespace.
+func (s *integrationSuite) deployIngressStandIn(ingresses []netv1.Ingress) {
+	s.ensureClusterObject("ingressclass", kIngressStandInClass, "it-ingress-class.yaml")
+
+	name := s.serviceName("ingress")
+	var volumes, volumeMounts strings.Builder
+	for _, secretName := range standInTLSSecrets(ingresses) {
+		fmt.Fprintf(&volumeMounts, "\n            - name: %[1]s\n              mountPath: /etc/nginx/tls/%[1]s", secretName)
+		fmt.Fprintf(&volumes, "\n        - name: %[1]s\n          secret:\n            secretName: %[1]s", secretName)
+	}
+	manifest := fmt.Sprintf(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+data:
+  default.conf: |
+%[2]s
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+spec:
+  selector:
+    matchLabels:
+      app: %[1]s
+  template:
+    metadata:
+      labels:
+        app: %[1]s
+    spec:
+      containers:
+        - name: nginx
+          image: %[3]s
+          ports:
+            - containerPort: %[4]d
+            - containerPort: %[5]d
+          readinessProbe:
+            tcpSocket:
+              port: %[4]d
+          volumeMounts:
+            - name: config
+              mountPath: /etc/nginx/conf.d%[6]s
+      volumes:
+        - name: config
+          configMap:
+            name: %[1]s%[7]s
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+    - name: http
+      port: %[4]d
+      targetPort: %[4]d
+    - name: https
+      port: %[5]d
+      targetPort: %[5]d
+`, name, indent(standInNginxConfig(ingresses, s.namespace), "    "), kIngressStandInImage, kIngressStandInPort,
+		kIngressStandInTLSPort, volumeMounts.String(), volumes.String())
+	k8s.KubectlApplyFromString(s.T(), s.kubeOptions, manifest)
+	s.waitUntilPodAvailable("app=" + name)
+}
+
+// standInTLSSecrets returns the TLS secrets of the ingresses, sorted and without duplicates.
+func standInTLSSecrets(ingresses []netv1.Ingress) []string {
+	var secretNames []string
+	seen := map[string]bool{}
+	for _, ingress := range ingresses {
+		for _, secretName := range ingressTLSHosts(ingress) {
+			if secretName != "" && !seen[secretName] {
+				seen[secretName] = true
+				secretNames = append(secretNames, secretName)
+			}
+		}
+	}
+	sort.Strings(secretNames)
+	return secretNames
+}
+
+// standInNginxConfig translates the ingress rules into nginx servers. Prefix paths match the path itself and its
+// sub paths, the nginx rewrite annotation rewrites every matching request to the target like ingress-nginx does.
+// Hosts with a TLS secret are served over HTTPS on their own port.
+func standInNginxConfig(ingresses []netv1.Ingress, namespace string) string {
+	servers := map[string][]string{}
+	tlsSecrets := map[string]string{}
+	var hosts []string
+	for _, ingress := range ingresses {
+		for host, secretName := range ingressTLSHosts(ingress) {
+			if secretName != "" {
+				tlsSecrets[host] = secretName
+			}
+		}
+		rewrite := ""
+		if target, ok := ingress.Annotations[kNginxRewriteAnnotation]; ok {
+			rewrite = fmt.Sprintf("    rewrite ^ %s break;\n", target)
+		}
+		for _, route := range ingressRoutes(ingress) {
+			host := route.host
+			if host == "" {
+				host = "_"
+			}
+			if _, ok := servers[host]; !ok {
+				hosts = append(hosts, host)
+			}
+			proxy := fmt.Sprintf("%s    proxy_pass http://%s.%s.svc.cluster.local:%d;\n", rewrite, route.service, namespace, route.port)
+			if route.path == "/" {
+				servers[host] = append(servers[host], fmt.Sprintf("  location / {\n%s  }\n", proxy))
+				continue
+			}
+			path := strings.TrimSuffix(route.path, "/")
+			servers[host] = append(servers[host],
+				fmt.Sprintf("  location = %s {\n%s  }\n", path, proxy),
+				fmt.Sprintf("  location %s/ {\n%s  }\n", path, proxy))
+		}
+	}
+
+	var config strings.Builder
+	config.WriteString("proxy_set_header Host $http_host;\n")
+	config.WriteString("proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n")
+	config.WriteString("proxy_set_header X-Forwarded-Host $http_host;\n")
+	config.WriteString("proxy_set_header X-Forwarded-Proto $scheme;\n")
+	// the Keycloak responses carry large cookies
+	config.WriteString("proxy_buffer_size 64k;\n")
+	config.WriteString("proxy_buffers 8 64k;\n")
+	for _, host := range hosts {
+		if secretName, ok := tlsSecrets[host]; ok {
+			fmt.Fprintf(&config, "server {\n  listen %d ssl;\n  server_name %s;\n", kIngressStandInTLSPort, host)
+			fmt.Fprintf(&config, "  ssl_certificate /etc/nginx/tls/%[1]s/tls.crt;\n  ssl_certificate_key /etc/nginx/tls/%[1]s/tls.key;\n", secretName)
+		} else {
+			fmt.Fprintf(&config, "server {\n  listen %d;\n  server_name %s;\n", kIngressStandInPort, host)
+		}
+		for _, location := range servers[host] {
+			config.WriteString(location)
+		}
+		config.WriteString("}\n")
+	}
+	return config.String()
+}
+
+func indent(text string, prefix string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
+
+// findIngressControllerAddress returns the load balancer address of the existing controller, which is found by the
+// label selector configured with CAMUNDA_DISTRO_TEST_INGRESS_CONTROLLER_SELECTOR. With TLS it's the HTTPS port.
+func (s *integrationSuite) findIngressControllerAddress() string {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err)
+
+	services, err := clientset.CoreV1().Services("").List(context.Background(), metav1.ListOptions{
+		LabelSelector: s.options.ingressControllerSelector,
+	})
+	s.Require().NoError(err)
+	port := "80"
+	if s.usesTLS() {
+		port = "443"
+	}
+	for _, service := range services.Items {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				return net.JoinHostPort(ingress.IP, port)
+			}
+			if ingress.Hostname != "" {
+				return net.JoinHostPort(ingress.Hostname, port)
+			}
+		}
+	}
+	s.T().Fatalf("No load balancer address found for the ingress controller with selector '%s'", s.options.ingressControllerSelector)
+	return ""
+}
+
+// httpTransport returns the transport of the HTTP clients. Through the ingress every request to a host of the
+// release is sent to the controller, the host header is kept so the ingress rules match. The controller address is
+// known once an endpoint was resolved with webEndpoint. With TLS only the test CA is trusted.
+func (s *integrationSuite) httpTransport() http.RoundTripper {
+	if !s.usesIngress() {
+		return http.DefaultTransport
+	}
+	var rootCAs *x509.CertPool
+	if s.usesTLS() {
+		rootCAs = s.testCA().CertPool()
+	}
+	return ingressTransport(s.ingressHost(), rootCAs, func() string {
+		return s.ingressAddress
+	})
+}
+
+// ingressTransport dials the controller address for the host and its sub domains, other hosts are dialed directly.
+// The root CAs verify the server certificates, nil uses the system roots.
+func ingressTransport(host string, rootCAs *x509.CertPool, controllerAddress func() string) http.RoundTripper {
+	dialer := &net.Dialer{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		addressHost, _, err := net.SplitHostPort(address)
+		if err == nil && (addressHost == host || strings.HasSuffix(addressHost, "."+host)) {
+			address = controllerAddress()
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	return transport
+}
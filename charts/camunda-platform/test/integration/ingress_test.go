@@ -0,0 +1,201 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestIngress(name string, host string, paths map[string]string) netv1.Ingress {
+	className := kIngressStandInClass
+	pathType := netv1.PathTypePrefix
+	rule := netv1.IngressRule{Host: host, IngressRuleValue: netv1.IngressRuleValue{HTTP: &netv1.HTTPIngressRuleValue{}}}
+	for path, service := range paths {
+		rule.HTTP.Paths = append(rule.HTTP.Paths, netv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathType,
+			Backend: netv1.IngressBackend{Service: &netv1.IngressServiceBackend{
+				Name: service,
+				Port: netv1.ServiceBackendPort{Number: 80},
+			}},
+		})
+	}
+	return netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{"ingress.kubernetes.io/rewrite-target": "/"},
+		},
+		Spec: netv1.IngressSpec{IngressClassName: &className, Rules: []netv1.IngressRule{rule}},
+	}
+}
+
+func newIngressTestSuite() *integrationSuite {
+	return &integrationSuite{
+		release:        "camunda-platform-it-abc123",
+		keycloakLegacy: true,
+		options:        integrationSuiteOptions{connectivity: kConnectivityIngress, ingressDomain: "camunda-platform.test"},
+		publicPorts:    map[string]int{"ingress": 28443},
+	}
+}
+
+func renderedTestIngresses() []netv1.Ingress {
+	return []netv1.Ingress{
+		newTestIngress("camunda-platform-it-abc123", "camunda-platform-it-abc123.camunda-platform.test", map[string]string{
+			"/auth":     "camunda-platform-it",
+			"/operate":  "camunda-platform-it-abc123-operate",
+			"/tasklist": "camunda-platform-it-abc123-tasklist",
+			"/optimize": "camunda-platform-it-abc123-optimize",
+		}),
+		newTestIngress("camunda-platform-it-abc123-identity", "identity.camunda-platform-it-abc123.camunda-platform.test", map[string]string{
+			"/": "camunda-platform-it-abc123-identity",
+		}),
+	}
+}
+
+func TestVerifyIngressLayoutShouldAcceptExpectedRoutes(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := newIngressTestSuite()
+
+	// when
+	err := verifyIngressLayout(renderedTestIngresses(), kIngressStandInClass, "", s.expectedIngressRoutes())
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyIngressLayoutShouldReportMissingAndUnexpectedRoutes(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := newIngressTestSuite()
+	ingresses := renderedTestIngresses()
+	ingresses[0].Spec.Rules[0].HTTP.Paths = ingresses[0].Spec.Rules[0].HTTP.Paths[:0]
+	ingresses[0].Annotations[kNginxRewriteAnnotation] = "/"
+
+	// when
+	err := verifyIngressLayout(ingresses, "nginx", "", s.expectedIngressRoutes())
+
+	// then
+	require.ErrorContains(t, err, "missing route camunda-platform-it-abc123.camunda-platform.test/operate -> camunda-platform-it-abc123-operate:80")
+	require.ErrorContains(t, err, "ingress camunda-platform-it-abc123 doesn't use the ingress class nginx")
+	require.ErrorContains(t, err, "ingress camunda-platform-it-abc123 rewrites the context paths to '/'")
+}
+
+func TestVerifyIngressLayoutShouldRequireTLSSecretForEveryHost(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := newIngressTestSuite()
+	ingresses := renderedTestIngresses()
+	ingresses[0].Spec.TLS = []netv1.IngressTLS{{
+		Hosts:      []string{"camunda-platform-it-abc123.camunda-platform.test"},
+		SecretName: "camunda-platform-it-abc123-tls",
+	}}
+
+	// when
+	err := verifyIngressLayout(ingresses, kIngressStandInClass, "camunda-platform-it-abc123-tls", s.expectedIngressRoutes())
+
+	// then
+	require.EqualError(t, err, "expected host identity.camunda-platform-it-abc123.camunda-platform.test to be served with TLS secret 'camunda-platform-it-abc123-tls', but got ''")
+}
+
+func TestIngressValuesShouldPointPublicUrlsToIngressPaths(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := newIngressTestSuite()
+
+	// when
+	values := s.publicUrlValues()
+
+	// then
+	require.Equal(t, "http://camunda-platform-it-abc123.camunda-platform.test:28443/auth/realms/camunda-platform", values["global.identity.auth.publicIssuerUrl"])
+	require.Equal(t, "http://camunda-platform-it-abc123.camunda-platform.test:28443/operate", values["global.identity.auth.operate.redirectUrl"])
+	require.Equal(t, "http://identity.camunda-platform-it-abc123.camunda-platform.test:28443", values["identity.fullURL"])
+	require.Equal(t, "identity.camunda-platform-it-abc123.camunda-platform.test", values["identity.ingress.host"])
+	require.Equal(t, kIngressStandInClass, values["global.ingress.className"])
+}
+
+func TestStandInNginxConfigShouldRouteIngressPaths(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ingresses := renderedTestIngresses()
+	ingresses[1].Annotations[kNginxRewriteAnnotation] = "/"
+
+	// when
+	config := standInNginxConfig(ingresses, "it-namespace")
+
+	// then
+	require.Contains(t, config, "server_name camunda-platform-it-abc123.camunda-platform.test;")
+	require.Contains(t, config, "  location = /operate {\n    proxy_pass http://camunda-platform-it-abc123-operate.it-namespace.svc.cluster.local:80;\n  }\n")
+	require.Contains(t, config, "  location /operate/ {\n")
+	require.Contains(t, config, "  location / {\n    rewrite ^ / break;\n    proxy_pass http://camunda-platform-it-abc123-identity.it-namespace.svc.cluster.local:80;\n  }\n")
+	require.Contains(t, config, "proxy_set_header Host $http_host;")
+}
+
+func TestStandInNginxConfigShouldServeTLSHostsWithSecret(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ingresses := renderedTestIngresses()
+	ingresses[1].Spec.TLS = []netv1.IngressTLS{{
+		Hosts:      []string{"identity.camunda-platform-it-abc123.camunda-platform.test"},
+		SecretName: "camunda-platform-it-abc123-tls",
+	}}
+
+	// when
+	config := standInNginxConfig(ingresses, "it-namespace")
+
+	// then
+	require.Contains(t, config, "server {\n  listen 8443 ssl;\n  server_name identity.camunda-platform-it-abc123.camunda-platform.test;\n"+
+		"  ssl_certificate /etc/nginx/tls/camunda-platform-it-abc123-tls/tls.crt;\n"+
+		"  ssl_certificate_key /etc/nginx/tls/camunda-platform-it-abc123-tls/tls.key;\n")
+	require.Contains(t, config, "server {\n  listen 8080;\n  server_name camunda-platform-it-abc123.camunda-platform.test;\n")
+	require.Equal(t, []string{"camunda-platform-it-abc123-tls"}, standInTLSSecrets(ingresses))
+}
+
+func TestIngressTransportShouldSendRequestsOfReleaseHostsToController(t *testing.T) {
+	t.Parallel()
+
+	// given
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+r.URL.Path)
+	}))
+	defer controller.Close()
+	client := http.Client{Transport: ingressTransport("release.camunda-platform.test", nil, func() string {
+		return strings.TrimPrefix(controller.URL, "http://")
+	})}
+
+	// when
+	response, err := client.Get("http://identity.release.camunda-platform.test:28443/auth/login")
+
+	// then
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "identity.release.camunda-platform.test:28443/auth/login", string(body))
+}
@@ -19,7 +19,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -30,8 +32,13 @@ import (
 
 	"context"
 
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+	"camunda-platform-helm/charts/camunda-platform/test/integration/portforward"
+	"camunda-platform-helm/charts/camunda-platform/test/integration/testca"
+
 	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
 	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,17 +46,54 @@ import (
 
 type integrationSuiteOptions struct {
 	deleteNamespace bool
+	// workloadInstances is the number of instances started per workload model.
+	workloadInstances int
+	// workloadRate is the number of instances started per second by the workload.
+	workloadRate float64
+	// loginUsername and loginPassword overwrite the credentials used to log in through Keycloak.
+	loginUsername string
+	loginPassword string
+	// keycloakPort is the local port of the Keycloak tunnel, the public issuer URL of the release points to it.
+	keycloakPort int
+	// connectivity is either "port-forward" or "ingress", through the ingress the web applications are reached with
+	// their ingress paths. Without ingressClass the suite deploys a stand-in controller, otherwise the controller of
+	// the class is found by the ingressControllerSelector. The hosts of the releases are sub domains of ingressDomain.
+	connectivity              string
+	ingressClass              string
+	ingressDomain             string
+	ingressControllerSelector string
+	// tls serves all ingresses with certificates of a generated test CA, only through the ingress.
+	tls bool
+	// zeebeTLS serves the Zeebe gateway with a certificate of the test CA, zeebeAuth requires access tokens for the
+	// zeebeAudience, the suite requests them with the Identity client zeebeClientId.
+	zeebeTLS      bool
+	zeebeAuth     bool
+	zeebeClientId string
+	zeebeAudience string
+	// scalingFailureThreshold is the ratio of requests to a component which may fail while it's scaled.
+	scalingFailureThreshold float64
 }
 
 type integrationSuite struct {
 	suite.Suite
-	chartPath         string
+	chartPath string
+	// releasePrefix is the prefix of the release name, each test installs its own release.
+	releasePrefix     string
 	release           string
 	namespace         string
 	namespaceMetadata metav1.ObjectMeta
 	kubeOptions       *k8s.KubectlOptions
 	options           integrationSuiteOptions
 	keycloakLegacy    bool
+	credentials       oidc.Credentials
+	// tunnels are the port-forwards of the current test, publicPorts the local ports the public URLs of the
+	// release are installed with.
+	tunnels     *portforward.Manager
+	publicPorts map[string]int
+	// ingressAddress is the address the ingress controller is reached at, if the test connects through the ingress.
+	ingressAddress string
+	// ca is the test CA which issued the certificates of the release, if it's served with TLS.
+	ca *testca.CA
 }
 
 func (s *integrationSuite) getSecret(secretSuffix string, secretKey string) string {
@@ -58,6 +102,19 @@ func (s *integrationSuite) getSecret(secretSuffix string, secretKey string) stri
 	return secret
 }
 
+// upgradeSecretValues returns the generated secrets of the release, an upgrade has to set them explicitly,
+// otherwise they are generated again and don't match the persisted state anymore.
+func (s *integrationSuite) upgradeSecretValues() map[string]string {
+	return map[string]string{
+		"global.identity.auth.tasklist.existingSecret": s.getSecret("-tasklist-identity-secret", "tasklist-secret"),
+		"global.identity.auth.optimize.existingSecret": s.getSecret("-optimize-identity-secret", "optimize-secret"),
+		"global.identity.auth.operate.existingSecret":  s.getSecret("-operate-identity-secret", "operate-secret"),
+		"identity.keycloak.auth.adminPassword":         s.getSecret("-keycloak", "admin-password"),
+		"identity.keycloak.auth.managementPassword":    s.getSecret("-keycloak", "management-password"),
+		"identity.keycloak.postgresql.auth.password":   s.getSecret("-postgresql", "postgres-password"),
+	}
+}
+
 func (s *integrationSuite) assertProcessDefinitionFromOperate() {
 	message := retry.DoWithRetry(s.T(),
 		"Try to query and assert process definition from operate",
@@ -77,7 +134,7 @@ func (s *integrationSuite) assertProcessDefinitionFromOperate() {
 			}
 
 			total := objectMap["total"].(float64)
-			s.Require().GreaterOrEqual(float64(1), total)
+			s.Require().GreaterOrEqual(total, float64(1))
 
 			s.Require().Contains(jsonString, "it-test-process")
 
@@ -88,27 +145,20 @@ func (s *integrationSuite) assertProcessDefinitionFromOperate() {
 
 func (s *integrationSuite) assertTasksFromTasklist() {
 	message := retry.DoWithRetry(s.T(),
-		"Try to query and assert process definition from operate",
+		"Try to query and assert user tasks from tasklist",
 		10,
 		10*time.Second,
 		func() (string, error) {
-			responseBuf, err := s.queryTasksFromTasklist()
+			tasks, err := s.queryTasksFromTasklist()
 			if err != nil {
 				return "", err
 			}
 
-			var objectMap map[string]interface{}
-			err = json.Unmarshal(responseBuf.Bytes(), &objectMap)
-			if err != nil {
-				return "", err
+			s.Require().GreaterOrEqual(len(tasks), 1)
+			for _, task := range tasks {
+				s.Require().Equal("It Test", task.Name)
 			}
 
-			data := objectMap["data"].(map[string]interface{})
-			tasks := data["tasks"].([]interface{})
-			s.Require().GreaterOrEqual(1, len(tasks))
-			task := tasks[0].(map[string]interface{})
-			s.Require().Equal("It Test", task["name"])
-
 			return "User Task 'It Test' successful queried from tasklist!", nil
 		})
 	s.T().Logf(message)
@@ -131,24 +181,23 @@ func (s *integrationSuite) tryToLoginToOptimize() {
 }
 
 func (s *integrationSuite) loginToOptimize() error {
-	_, _, closeFn, err := s.doLogin("optimize", 8083, 8090)
-	defer closeFn()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, _, err := s.doLogin("optimize", 8090)
+	return err
 }
 
-func (s *integrationSuite) queryTasksFromTasklist() (*bytes.Buffer, error) {
-	endpoint, httpClient, closeFn, err := s.doLogin("tasklist", 8082, 8080)
-	defer closeFn()
+func (s *integrationSuite) queryTasksFromTasklist() ([]tasklistTask, error) {
+	endpoint, httpClient, err := s.doLogin("tasklist", 8080)
 	if err != nil {
 		return nil, err
 	}
 
 	// curl -i -H "Content-Type: application/json" -XPOST "http://localhost:8080/graphql" --cookie "ope-session"  -d '{"query": "{tasks(query:{}){name}}"}'
-	return s.queryApi(httpClient, "http://"+endpoint+"/graphql", bytes.NewBufferString(`{"query": "{tasks(query:{}){name}}"}`))
+	var response tasklistTasksResponse
+	err = s.queryTasklistGraphql(httpClient, endpoint, graphqlRequest{Query: "{tasks(query:{}){name}}"}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data.Tasks, graphqlErrorsToError(response.Errors)
 }
 
 func (s *integrationSuite) deployProcess(err error, client zbc.Client) *pb.DeployProcessResponse {
@@ -160,8 +209,9 @@ func (s *integrationSuite) deployProcess(err error, client zbc.Client) *pb.Deplo
 	return deployProcessResponse
 }
 
-func (s *integrationSuite) createProcessInstance() {
-	serviceName := fmt.Sprintf("%s-zeebe-gateway", s.release)
+// createProcessInstance deploys the "it-test-process" and starts one instance of it, the key of the instance is returned.
+func (s *integrationSuite) createProcessInstance() int64 {
+	serviceName := s.zeebeGatewayServiceName()
 	client, closeFn, err := s.createPortForwardedClient(serviceName)
 	s.Require().NoError(err, "failed to create Zeebe client")
 	defer closeFn()
@@ -171,11 +221,17 @@ func (s *integrationSuite) createProcessInstance() {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancelFn()
 
+	var processInstanceKey int64
 	message := retry.DoWithRetry(s.T(), "Try to create Process instance", 10, 1*time.Second, func() (string, error) {
-		_, err = client.NewCreateInstanceCommand().ProcessDefinitionKey(deployProcessResponse.Processes[0].ProcessDefinitionKey).Send(ctx)
-		return "Process instance created.", err
+		response, err := client.NewCreateInstanceCommand().ProcessDefinitionKey(deployProcessResponse.Processes[0].ProcessDefinitionKey).Send(ctx)
+		if err != nil {
+			return "", err
+		}
+		processInstanceKey = response.ProcessInstanceKey
+		return fmt.Sprintf("Process instance %d created.", processInstanceKey), nil
 	})
 	s.T().Logf(message)
+	return processInstanceKey
 }
 
 func (s *integrationSuite) tryTologinToIdentity() {
@@ -196,23 +252,15 @@ func (s *integrationSuite) tryTologinToIdentity() {
 func (s *integrationSuite) assertLoginToIdentity() error {
 	// in order to login to identity we need to port-forward to identity AND keycloak
 	// identity needs to redirect (forward) requests to keycloak to enable the login
-
-	// create keycloak port-forward
-	keycloakServiceName := s.resolveKeycloakServiceName()
-	_, closeKeycloakPortForward := s.createPortForwardedHttpClientWithPort(keycloakServiceName, 18080)
-	defer closeKeycloakPortForward()
-
-	// create identity port-forward
-	identityServiceName := fmt.Sprintf("%s-identity", s.release)
-	identityEndpoint, closeIdentityPortForward := s.createPortForwardedHttpClientWithPort(identityServiceName, 8080)
-	defer closeIdentityPortForward()
+	s.webEndpoint("keycloak", 8080)
+	identityEndpoint := s.webEndpoint("identity", 8080)
 
 	httpClient, jar, err := s.createHttpClientWithJar()
 	if err != nil {
 		return err
 	}
 
-	err = s.doSessionBasedLogin("http://"+identityEndpoint+"/auth/login", httpClient)
+	err = s.doSessionBasedLogin(s.webScheme()+"://"+identityEndpoint+"/auth/login", httpClient)
 	if err != nil {
 		return err
 	}
@@ -221,13 +269,12 @@ func (s *integrationSuite) assertLoginToIdentity() error {
 }
 
 func (s *integrationSuite) queryProcessDefinitionsFromOperate() (*bytes.Buffer, error) {
-	endpoint, httpClient, closeFn, err := s.doLogin("operate", 8081, 8080)
-	defer closeFn()
+	endpoint, httpClient, err := s.doLogin("operate", 8080)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.queryApi(httpClient, "http://"+endpoint+"/v1/process-definitions/search", bytes.NewBufferString("{}"))
+	return s.queryApi(httpClient, s.webScheme()+"://"+endpoint+"/v1/process-definitions/search", bytes.NewBufferString("{}"))
 }
 
 func (s *integrationSuite) queryApi(httpClient http.Client, url string, jsonData *bytes.Buffer) (*bytes.Buffer, error) {
@@ -293,15 +340,25 @@ func (s *integrationSuite) awaitElasticPods() {
 	s.waitUntilPodAvailable("release=" + s.release)
 }
 
+// assertGatewayTopology checks the topology of the gateway against the one the release was installed with.
+// Partition leaders are elected after the brokers are ready, so the check is retried until the topology settled.
 func (s *integrationSuite) assertGatewayTopology(err error, client zbc.Client) {
-	ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancelFn()
-	topology, err := client.NewTopologyCommand().Send(ctx)
-
-	s.Require().NoError(err, "failed to obtain gateway topology")
-	s.Require().EqualValues(3, topology.ClusterSize)
-	s.Require().EqualValues(3, topology.PartitionsCount)
-	s.Require().EqualValues(3, topology.ReplicationFactor)
+	expected, err := s.expectedTopology()
+	s.Require().NoError(err, "failed to read expected topology of the release")
+
+	message := retry.DoWithRetry(s.T(), "Try to assert gateway topology", 12, 5*time.Second, func() (string, error) {
+		ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancelFn()
+		topology, err := client.NewTopologyCommand().Send(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to obtain gateway topology: %w", err)
+		}
+		if err := verifyTopology(topology, expected); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Gateway topology matches %s.", expected), nil
+	})
+	s.T().Logf(message)
 }
 
 func (s *integrationSuite) resolveKeycloakServiceName() string {
@@ -316,6 +373,14 @@ func (s *integrationSuite) resolveKeycloakServiceName() string {
 }
 
 func (s *integrationSuite) updateIdentityChartWithKeycloakV19() {
+	// the chart is changed on disk, so the test uses its own copy to not affect the tests running at the same time
+	chartCopy, err := files.CopyFolderToTemp(s.chartPath, "camunda-platform-it-", func(path string) bool {
+		return filepath.Base(path) != "test"
+	})
+	s.Require().NoError(err, "cannot copy chart")
+	s.T().Cleanup(func() { _ = os.RemoveAll(filepath.Dir(chartCopy)) })
+	s.chartPath = chartCopy
+
 	chartPath := s.chartPath + "/charts/identity"
 	chartFilePath := chartPath + "/Chart.yaml"
 
@@ -24,24 +24,27 @@ import (
 	"github.com/gruntwork-io/terratest/modules/helm"
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/stretchr/testify/require"
-	"github.com/stretchr/testify/suite"
 )
 
 func TestIntegration(t *testing.T) {
 	chartPath, err := filepath.Abs("../../")
 	require.NoError(t, err)
 
-	suite.Run(t, &integrationSuite{
-		chartPath:      chartPath,
-		release:        "camunda-platform-it",
-		options:        getIntegrationSuiteOptions(),
-		keycloakLegacy: true,
+	options := getIntegrationSuiteOptions()
+	runParallel(t, parallelLimit(t), func() *integrationSuite {
+		return &integrationSuite{
+			chartPath:      chartPath,
+			releasePrefix:  "camunda-platform-it",
+			options:        options,
+			keycloakLegacy: true,
+		}
 	})
 }
 
 func (s *integrationSuite) SetupTest() {
-	nsMetadata := createNamespaceObjectMeta()
+	nsMetadata := createNamespaceObjectMeta(s.T().Name())
 	s.namespace = nsMetadata.Name
+	s.release = testReleaseName(s.releasePrefix, s.namespace)
 	s.kubeOptions = k8s.NewKubectlOptions("", "", s.namespace)
 
 	if _, err := k8s.GetNamespaceE(s.T(), s.kubeOptions, s.namespace); err != nil {
@@ -52,6 +55,7 @@ func (s *integrationSuite) SetupTest() {
 }
 
 func (s *integrationSuite) TearDownTest() {
+	s.closePortForwards()
 	if s.T().Failed() {
 		s.T().Logf("Test failed on namespace: %s!", s.namespace)
 	}
@@ -65,6 +69,7 @@ func (s *integrationSuite) TestServicesEnd2End() {
 	// given
 	options := &helm.Options{
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 	}
 
 	// when
@@ -74,21 +79,217 @@ func (s *integrationSuite) TestServicesEnd2End() {
 		s.T().Logf("Helm chart was already installed, rerun assertions.")
 	}
 
+	// then
+	s.awaitAllPodsForThisRelease()
+	processInstanceKey := s.createProcessInstance()
+	expectations := s.runWorkload(defaultWorkloadModels)
+
+	s.awaitElasticPods()
+	s.tryTologinToIdentity()
+	s.assertProcessDefinitionFromOperate()
+	s.assertTasksFromTasklist()
+	s.completeUserTaskFromTasklist(processInstanceKey, itTestTaskVariables)
+	s.assertProcessInstanceCompletedInOperate(processInstanceKey, itTestTaskVariables)
+	s.assertWorkloadInOperate(expectations)
+	s.tryToLoginToOptimize()
+	s.assertClientCredentialsApiAccess()
+	s.assertMetricsEndpoints()
+}
+
+func (s *integrationSuite) TestServicesEnd2EndWithIngress() {
+	s.options.connectivity = kConnectivityIngress
+
+	// given
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
+	}
+
+	// when
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+
+	// then
+	s.awaitAllPodsForThisRelease()
+	processInstanceKey := s.createProcessInstance()
+
+	s.awaitElasticPods()
+	s.tryTologinToIdentity()
+	s.assertProcessDefinitionFromOperate()
+	s.assertTasksFromTasklist()
+	s.completeUserTaskFromTasklist(processInstanceKey, itTestTaskVariables)
+	s.assertProcessInstanceCompletedInOperate(processInstanceKey, itTestTaskVariables)
+	s.tryToLoginToOptimize()
+	s.assertClientCredentialsApiAccess()
+}
+
+func (s *integrationSuite) TestServicesEnd2EndWithTLS() {
+	s.options.connectivity = kConnectivityIngress
+	s.options.tls = true
+
+	// given
+	options := &helm.Options{
+		ValuesFiles:    []string{"it-tls-values.yaml"},
+		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
+	}
+
+	// This is needed to access WebModeler Docker image. It will be removed once WebModeler is public.
+	k8s.RunKubectl(s.T(), s.kubeOptions, "create", "secret", "generic", "registry-camunda-cloud", "--from-file=.dockerconfigjson="+getEnv("DOCKER_CONFIG_FILE", ""), "--type=kubernetes.io/dockerconfigjson")
+
+	// when
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+
 	// then
 	s.awaitAllPodsForThisRelease()
 	s.createProcessInstance()
 
 	s.awaitElasticPods()
+	s.assertTLSCertificateChains()
+	s.assertKeycloakRedirectsUseHttps()
+	s.assertWebModelerHttpsOnly()
 	s.tryTologinToIdentity()
 	s.assertProcessDefinitionFromOperate()
 	s.assertTasksFromTasklist()
 	s.tryToLoginToOptimize()
+	s.assertClientCredentialsApiAccess()
+}
+
+func (s *integrationSuite) TestZeebeGatewayWithTLS() {
+	s.options.zeebeTLS = true
+
+	// given
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      mergeValues(s.publicUrlValues(), s.zeebeGatewayValues()),
+	}
+
+	// when
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+
+	// then
+	s.awaitAllPodsForThisRelease()
+	s.createProcessInstance()
+}
+
+func (s *integrationSuite) TestZeebeGatewayWithOAuth() {
+	s.options.zeebeTLS = true
+	s.options.zeebeAuth = true
+
+	// given
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      mergeValues(s.publicUrlValues(), s.zeebeGatewayValues()),
+	}
+
+	// when
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+
+	// then
+	s.awaitAllPodsForThisRelease()
+	s.assertUnauthenticatedZeebeClientRejected()
+	s.createProcessInstance()
+}
+
+func (s *integrationSuite) TestZeebeBrokerFailure() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues: mergeValues(s.publicUrlValues(), map[string]string{
+			"zeebe.podDisruptionBudget.enabled": "true",
+		}),
+	}
+
+	// when
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+
+	// then
+	s.awaitAllPodsForThisRelease()
+	s.createProcessInstance()
+
+	s.awaitElasticPods()
+	s.assertBrokerFailureResilience()
+}
+
+func (s *integrationSuite) TestZeebeBackupWithS3StandIn() {
+	// given
+	s.deployBackupStandIn()
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      mergeValues(s.publicUrlValues(), s.backupStandInValues()),
+	}
+
+	// when
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+
+	// then
+	s.awaitAllPodsForThisRelease()
+	s.createProcessInstance()
+	s.assertZeebeBackup()
+}
+
+func (s *integrationSuite) TestScaleZeebeGateway() {
+	s.runScalingScenario(s.zeebeGatewayComponent(), 2, 3)
+}
+
+func (s *integrationSuite) TestScaleConnectors() {
+	s.runScalingScenario(s.connectorsComponent(), 1, 2)
+}
+
+func (s *integrationSuite) TestScaleElasticsearch() {
+	s.runScalingScenario(s.elasticsearchComponent(), 2, 3)
+}
+
+func (s *integrationSuite) runScalingScenario(component scalableComponent, from int, to int) {
+	// given
+	installValues := s.publicUrlValues()
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      installValues,
+	}
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+	s.awaitAllPodsForThisRelease()
+	s.awaitElasticPods()
+	s.createProcessInstance()
+
+	// when / then
+	s.assertScalingDuringWorkload(component, installValues, from, to)
+}
+
+func (s *integrationSuite) TestZeebeClusterSizeChangeShouldFailWithUpgrade() {
+	// given
+	installValues := s.publicUrlValues()
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      installValues,
+	}
+	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
+		helm.Install(s.T(), options, s.chartPath, s.release)
+	}
+	s.awaitAllPodsForThisRelease()
+
+	// when / then
+	s.assertZeebeClusterSizeChangeRejected(installValues)
 }
 
 func (s *integrationSuite) TestServicesEnd2EndShouldFailWithUpgrade() {
 	// given
 	options := &helm.Options{
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 	}
 	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
 		helm.Install(s.T(), options, s.chartPath, s.release)
@@ -105,39 +306,32 @@ func (s *integrationSuite) TestServicesEnd2EndWithUpgrade() {
 	// given
 	options := &helm.Options{
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 	}
 	if _, err := k8s.GetPodE(s.T(), s.kubeOptions, s.release+"-zeebe-0"); err != nil {
 		helm.Install(s.T(), options, s.chartPath, s.release)
 	}
-	tasklistSecret := s.getSecret("-tasklist-identity-secret", "tasklist-secret")
-	operateSecret := s.getSecret("-operate-identity-secret", "operate-secret")
-	optimizeSecret := s.getSecret("-optimize-identity-secret", "optimize-secret")
-	keycloakAdminPassword := s.getSecret("-keycloak", "admin-password")
-	keycloakManagementPassword := s.getSecret("-keycloak", "management-password")
-	postgresqlPassword := s.getSecret("-postgresql", "postgres-password")
 
 	// when
 	upgradeOptions := &helm.Options{
 		KubectlOptions: s.kubeOptions,
-		SetStrValues: map[string]string{
-			"global.identity.auth.tasklist.existingSecret": tasklistSecret,
-			"global.identity.auth.optimize.existingSecret": optimizeSecret,
-			"global.identity.auth.operate.existingSecret":  operateSecret,
-			"identity.keycloak.auth.adminPassword":         keycloakAdminPassword,
-			"identity.keycloak.auth.managementPassword":    keycloakManagementPassword,
-			"identity.keycloak.postgresql.auth.password":   postgresqlPassword,
-		},
+		SetValues:      s.publicUrlValues(),
+		SetStrValues:   s.upgradeSecretValues(),
 	}
 	helm.Upgrade(s.T(), upgradeOptions, s.chartPath, s.release)
 
 	// then
 	s.awaitAllPodsForThisRelease()
-	s.createProcessInstance()
+	processInstanceKey := s.createProcessInstance()
+	expectations := s.runWorkload(defaultWorkloadModels)
 
 	s.awaitElasticPods()
 	s.tryTologinToIdentity()
 	s.assertProcessDefinitionFromOperate()
 	s.assertTasksFromTasklist()
+	s.completeUserTaskFromTasklist(processInstanceKey, itTestTaskVariables)
+	s.assertProcessInstanceCompletedInOperate(processInstanceKey, itTestTaskVariables)
+	s.assertWorkloadInOperate(expectations)
 	s.tryToLoginToOptimize()
 }
 
@@ -146,6 +340,7 @@ func (s *integrationSuite) TestServicesEnd2EndWithConfig() {
 	options := &helm.Options{
 		ValuesFiles:    []string{"it-custom-values.yaml"},
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 	}
 
 	// This is needed to access WebModeler Docker image. It will be removed once WebModeler is public.
@@ -174,6 +369,7 @@ func (s *integrationSuite) TestServicesEnd2EndWithKeycloakV19() {
 	options := &helm.Options{
 		ValuesFiles:    []string{"it-keycloak-v19-values.yaml"},
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 	}
 
 	// This is needed to access WebModeler Docker image. It will be removed once WebModeler is public.
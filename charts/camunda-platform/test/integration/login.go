@@ -15,102 +15,127 @@
 package integration
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"regexp"
-	"strings"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const kKeycloakDefaultPort = 18080
+const kIdentityJWTCookie = "IDENTITY_JWT"
 
-func (s *integrationSuite) doSessionBasedLogin(loginUrl string, httpClient http.Client) error {
-	sessionUrl, err := s.resolveSessionLoginUrl(loginUrl, httpClient)
+// loginCredentials returns the credentials used to log in through the Keycloak login page, they are resolved once.
+func (s *integrationSuite) loginCredentials() oidc.Credentials {
+	if s.credentials == (oidc.Credentials{}) {
+		s.credentials = s.resolveLoginCredentials()
+	}
+	return s.credentials
+}
+
+// resolveLoginCredentials takes the credentials from the test configuration, otherwise from the first user configured
+// in the Identity deployment (either as plain value or as secret reference), otherwise the chart defaults are used.
+func (s *integrationSuite) resolveLoginCredentials() oidc.Credentials {
+	if s.options.loginUsername != "" && s.options.loginPassword != "" {
+		return oidc.Credentials{Username: s.options.loginUsername, Password: s.options.loginPassword}
+	}
+
+	defaultCredentials := oidc.Credentials{Username: "demo", Password: "demo"}
+	if s.kubeOptions == nil {
+		return defaultCredentials
+	}
+
+	deployment, err := s.getIdentityDeployment()
 	if err != nil {
-		return err
+		s.T().Logf("Cannot get Identity deployment to resolve login credentials, using defaults: %v", err)
+		return defaultCredentials
 	}
-	s.T().Logf("Send log in request to %s", sessionUrl)
 
-	// log in as demo:demo
-	values := url.Values{
-		"username": {"demo"},
-		"password": {"demo"},
+	credentials := defaultCredentials
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			switch env.Name {
+			case "KEYCLOAK_USERS_0_USERNAME":
+				credentials.Username = s.resolveEnvValue(env, credentials.Username)
+			case "KEYCLOAK_USERS_0_PASSWORD":
+				credentials.Password = s.resolveEnvValue(env, credentials.Password)
+			}
+		}
+	}
+	return credentials
+}
+
+func (s *integrationSuite) getIdentityDeployment() (*appsv1.Deployment, error) {
+	if s.kubeOptions == nil {
+		return nil, errors.New("no cluster configured")
 	}
-	loginResponse, err := httpClient.PostForm(sessionUrl, values)
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if loginResponse.StatusCode != 200 {
-		return errors.New(fmt.Sprintf("On log in expected an 200 status code, but got %d", loginResponse.StatusCode))
+	identityDeploymentName := fmt.Sprintf("%s-identity", s.release)
+	return clientset.AppsV1().Deployments(s.namespace).Get(context.Background(), identityDeploymentName, metav1.GetOptions{})
+}
+
+func (s *integrationSuite) resolveEnvValue(env corev1.EnvVar, defaultValue string) string {
+	if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+		secret, err := k8s.GetSecretE(s.T(), s.kubeOptions, env.ValueFrom.SecretKeyRef.Name)
+		if err != nil {
+			s.T().Logf("Cannot read secret %s for %s, using default: %v", env.ValueFrom.SecretKeyRef.Name, env.Name, err)
+			return defaultValue
+		}
+		return string(secret.Data[env.ValueFrom.SecretKeyRef.Key])
 	}
-	s.T().Logf("Log in at '%s' sucessful!", loginUrl)
-	return nil
+	if env.Value != "" {
+		return env.Value
+	}
+	return defaultValue
 }
 
-func (s *integrationSuite) resolveSessionLoginUrl(loginUrl string, httpClient http.Client) (string, error) {
-	// Send request to /auth/login, and follow redirect to keycloak to retrieve the login page.
+func (s *integrationSuite) newBrowserFlow(httpClient http.Client) *oidc.BrowserFlow {
+	flow := oidc.NewBrowserFlow(httpClient, s.loginCredentials())
+	flow.Logf = s.T().Logf
+	return flow
+}
+
+func (s *integrationSuite) doSessionBasedLogin(loginUrl string, httpClient http.Client) error {
+	// Send request to the login URL, and follow the redirects to Keycloak to retrieve the login page.
 	// We need to read the returned login page to get the correct URL with session code, only with this session code
 	// we are able to log in correctly to keycloak / identity. Additionally, this kind of mimics the user interaction.
-
-	request, err := http.NewRequest("GET", loginUrl, nil)
-	if err != nil {
-		return "", err
-	}
-	response, err := httpClient.Do(request)
+	session, err := s.newBrowserFlow(httpClient).Login(loginUrl)
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	// The returned login page (from keycloak) is no valid html code, which means we can't use an HTML parser,
-	// but we can extract the url via regex.
-	//
-	// Example form with corresponding URL we are looking for:
-	//
-	// <form id="kc-form-login" onsubmit="login.disabled = true; return true;"
-	//		action="http://localhost:18080/auth/realms/camunda-platform/login-actions/authenticate?session_code=B0BxW2ST2DH0NYE1J-THQncuCVc2yPck5JFmgEnLWbM&amp;execution=be1c2750-2b28-4044-8cf3-22b1331efeae&amp;client_id=camunda-identity&amp;tab_id=tp2zBJnsh6o"
-	//		method="post">
-	//
-	//
-	defer response.Body.Close()
-	body := response.Body
-	b, err := io.ReadAll(body)
-
-	regexCompiled := regexp.MustCompile("(action=\")(.*)(\"[\\s\\w]+=\")")
-	match := regexCompiled.FindStringSubmatch(string(b))
-	if len(match) < 3 {
-		return "", errors.New(fmt.Sprintf("Expected to extract session url from response %s", string(b)))
-	}
-	sessionUrl := match[2]
-
-	// the url is encoded in the html document, which means we need to replace some characters
-	return strings.Replace(sessionUrl, "&amp;", "&", -1), nil
+	s.T().Logf("Log in at '%s' sucessful! (Keycloak realm '%s' with context path '%s')", loginUrl, session.Realm, session.ContextPath)
+	return nil
 }
 
-func (s *integrationSuite) extractJWTTokenFromCookieJar(jar *cookiejar.Jar) (string, error) {
-	cookies := jar.Cookies(&url.URL{Scheme: "http", Host: "localhost"})
-	identityJWT := "IDENTITY_JWT"
-	for _, cookie := range cookies {
-		if cookie.Name == identityJWT {
-			return cookie.Value, nil
-		}
+func (s *integrationSuite) extractJWTTokenFromCookieJar(jar *cookiejar.Jar, identityEndpoint string) (string, error) {
+	identityUrl, err := url.Parse(s.webScheme() + "://" + identityEndpoint + "/")
+	if err != nil {
+		return "", err
 	}
-	return "", errors.New("no JWT token found in cookie jar")
+	return oidc.CookieValue(jar, identityUrl, kIdentityJWTCookie)
 }
 
 func (s *integrationSuite) doJWTBasedLogin(err error, jar *cookiejar.Jar, identityEndpoint string, httpClient http.Client) error {
 	// The previous log in request caused to store a token in our cookie jar.
 	// In order to verify whether this token is valid and works with identity we have to extract the token and set
 	// the cookie value (JWT token) as authentication header.
-	jwtToken, err := s.extractJWTTokenFromCookieJar(jar)
+	jwtToken, err := s.extractJWTTokenFromCookieJar(jar, identityEndpoint)
 	if err != nil {
 		return err
 	}
 	s.T().Logf("Extracted following JWT token from cookie jar '%s'.", jwtToken)
 
-	verificationUrl := "http://" + identityEndpoint + "/api/clients"
+	verificationUrl := s.webScheme() + "://" + identityEndpoint + "/api/clients"
 	getRequest, err := http.NewRequest("GET", verificationUrl, nil)
 	if err != nil {
 		return err
@@ -129,27 +154,20 @@ func (s *integrationSuite) doJWTBasedLogin(err error, jar *cookiejar.Jar, identi
 	return nil
 }
 
-func (s *integrationSuite) doLogin(service string, localPort int, containerPort int) (string, http.Client, func(), error) {
+func (s *integrationSuite) doLogin(service string, containerPort int) (string, http.Client, error) {
 	// In order to login to the service we need to port-forward to Keycloak.
 	// The service will redirect (forward) requests to Keycloak to enable the login
+	s.webEndpoint("keycloak", 8080)
+	endpoint := s.webEndpoint(service, containerPort)
 
-	// create keycloak port-forward
-	keycloakServiceName := s.resolveKeycloakServiceName()
-	_, closeKeycloakPortForward := s.createPortForwardedHttpClientWithPort(keycloakServiceName, kKeycloakDefaultPort)
-
-	// create service port-forward
-	serviceName := fmt.Sprintf("%s-%s", s.release, service)
-	endpoint, closeFn := s.createPortForwardedHttpClientWithPortAndContainerPort(serviceName, localPort, containerPort)
-
-	coupledCloseFn := func() { closeFn(); closeKeycloakPortForward() }
 	httpClient, _, err := s.createHttpClientWithJar()
 	if err != nil {
-		return "", http.Client{}, coupledCloseFn, err
+		return "", http.Client{}, err
 	}
 
-	err = s.doSessionBasedLogin("http://"+endpoint+"/", httpClient)
+	err = s.doSessionBasedLogin(s.webScheme()+"://"+endpoint+"/", httpClient)
 	if err != nil {
-		return "", http.Client{}, coupledCloseFn, err
+		return "", http.Client{}, err
 	}
-	return endpoint, httpClient, coupledCloseFn, nil
+	return endpoint, httpClient, nil
 }
@@ -0,0 +1,246 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/fake"
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type loginTest struct {
+	suite.Suite
+	format   fake.LoginPageFormat
+	keycloak *fake.Keycloak
+	identity *fake.App
+	operate  *fake.App
+	tasklist *fake.App
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []fake.LoginPageFormat{fake.LoginPageLegacy, fake.LoginPageV19, fake.LoginPageCustomTheme} {
+		suite.Run(t, &loginTest{format: format})
+	}
+}
+
+func (s *loginTest) SetupTest() {
+	s.keycloak = fake.NewKeycloak(s.format)
+	s.identity = fake.NewIdentity(s.keycloak)
+	s.operate = fake.NewOperate(s.keycloak)
+	s.tasklist = fake.NewTasklist(s.keycloak)
+}
+
+func (s *loginTest) TearDownTest() {
+	s.tasklist.Close()
+	s.operate.Close()
+	s.identity.Close()
+	s.keycloak.Close()
+}
+
+// integrationSuite returns an integration suite bound to the current test, without any cluster access.
+func (s *loginTest) integrationSuite() *integrationSuite {
+	it := &integrationSuite{}
+	it.SetT(s.T())
+	return it
+}
+
+func (s *loginTest) TestResolveSessionLoginUrl() {
+	// given
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	form, _, err := it.newBrowserFlow(httpClient).ResolveLoginForm(s.identity.URL() + "/auth/login")
+
+	// then
+	s.Require().NoError(err)
+	sessionUrl := form.Action
+	s.Require().True(strings.HasPrefix(sessionUrl, s.keycloak.RealmURL()+"/login-actions/authenticate?session_code="), sessionUrl)
+	s.Require().NotContains(sessionUrl, "&amp;")
+	s.Require().Contains(sessionUrl, "&client_id=identity")
+}
+
+func (s *loginTest) TestSessionBasedLoginToIdentity() {
+	// given
+	it := s.integrationSuite()
+	httpClient, jar, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	err = it.doSessionBasedLogin(s.identity.URL()+"/auth/login", httpClient)
+
+	// then
+	s.Require().NoError(err)
+	token, err := it.extractJWTTokenFromCookieJar(jar, strings.TrimPrefix(s.identity.URL(), "http://"))
+	s.Require().NoError(err)
+	s.Require().Len(strings.Split(token, "."), 3)
+}
+
+func (s *loginTest) TestJWTBasedLoginToIdentity() {
+	// given
+	it := s.integrationSuite()
+	httpClient, jar, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+	s.Require().NoError(it.doSessionBasedLogin(s.identity.URL()+"/auth/login", httpClient))
+
+	// when
+	err = it.doJWTBasedLogin(nil, jar, s.identity.Endpoint(), httpClient)
+
+	// then
+	s.Require().NoError(err)
+}
+
+func (s *loginTest) TestJWTBasedLoginWithoutSessionShouldFail() {
+	// given
+	it := s.integrationSuite()
+	httpClient, jar, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	err = it.doJWTBasedLogin(nil, jar, s.identity.Endpoint(), httpClient)
+
+	// then
+	s.requireLoginErrorAtStep(err, oidc.StepCookie)
+}
+
+func (s *loginTest) TestLoginShouldDiscoverKeycloakContextPath() {
+	// given
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	session, err := it.newBrowserFlow(httpClient).Login(s.identity.URL() + "/auth/login")
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Equal(s.keycloak.ContextPath, session.ContextPath)
+	s.Require().Equal(fake.Realm, session.Realm)
+	s.Require().Equal(s.identity.URL()+"/", session.FinalURL)
+}
+
+func (s *loginTest) TestLoginWithConfiguredCredentials() {
+	// given
+	s.keycloak.Username = "it-user"
+	s.keycloak.Password = "it-password"
+	it := s.integrationSuite()
+	it.options.loginUsername = "it-user"
+	it.options.loginPassword = "it-password"
+	httpClient, jar, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	err = it.doSessionBasedLogin(s.identity.URL()+"/auth/login", httpClient)
+
+	// then
+	s.Require().NoError(err)
+	_, err = it.extractJWTTokenFromCookieJar(jar, strings.TrimPrefix(s.identity.URL(), "http://"))
+	s.Require().NoError(err)
+}
+
+func (s *loginTest) TestLoginWithRejectedCredentialsShouldFailAtCredentialsStep() {
+	// given
+	s.keycloak.Password = "not-demo"
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	err = it.doSessionBasedLogin(s.identity.URL()+"/auth/login", httpClient)
+
+	// then
+	s.requireLoginErrorAtStep(err, oidc.StepCredentials)
+	s.Require().Contains(err.Error(), "Invalid username or password.")
+}
+
+func (s *loginTest) TestLoginWithUnreachablePageShouldFailAtRedirectStep() {
+	// given
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+
+	// when
+	err = it.doSessionBasedLogin(s.identity.URL()+"/api/clients", httpClient)
+
+	// then
+	s.requireLoginErrorAtStep(err, oidc.StepRedirect)
+}
+
+func (s *loginTest) TestLoginWithoutLoginFormShouldFailAtFormDiscoveryStep() {
+	// given
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+	s.Require().NoError(it.doSessionBasedLogin(s.operate.URL()+"/", httpClient))
+
+	// when
+	// the session is already established, so Operate doesn't redirect to the login page anymore
+	_, _, err = it.newBrowserFlow(httpClient).ResolveLoginForm(s.operate.URL() + "/")
+
+	// then
+	s.requireLoginErrorAtStep(err, oidc.StepFormDiscovery)
+}
+
+func (s *loginTest) requireLoginErrorAtStep(err error, step oidc.Step) {
+	var loginError *oidc.LoginError
+	s.Require().True(errors.As(err, &loginError), "expected a login error but got: %v", err)
+	s.Require().Equal(step, loginError.Step, err.Error())
+}
+
+func (s *loginTest) TestQueryProcessDefinitionsFromOperate() {
+	// given
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+	s.Require().NoError(it.doSessionBasedLogin(s.operate.URL()+"/", httpClient))
+
+	// when
+	responseBuf, err := it.queryApi(httpClient, s.operate.URL()+"/v1/process-definitions/search", bytes.NewBufferString("{}"))
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Contains(responseBuf.String(), "it-test-process")
+}
+
+func (s *loginTest) TestQueryAndCompleteTasksFromTasklist() {
+	// given
+	it := s.integrationSuite()
+	httpClient, _, err := it.createHttpClientWithJar()
+	s.Require().NoError(err)
+	s.Require().NoError(it.doSessionBasedLogin(s.tasklist.URL()+"/", httpClient))
+
+	// when
+	tasks, err := it.queryTasksFromTasklistWithClient(httpClient, s.tasklist.Endpoint(), "CREATED")
+	s.Require().NoError(err)
+	s.Require().Len(tasks, 1)
+	claimedTask, err := it.claimTaskFromTasklist(httpClient, s.tasklist.Endpoint(), tasks[0].Id)
+	s.Require().NoError(err)
+	completedTask, err := it.completeTaskFromTasklist(httpClient, s.tasklist.Endpoint(), tasks[0].Id, itTestTaskVariables)
+	s.Require().NoError(err)
+
+	// then
+	s.Require().Equal("It Test", tasks[0].Name)
+	s.Require().Equal(fake.DefaultUsername, claimedTask.Assignee)
+	s.Require().Equal("COMPLETED", completedTask.TaskState)
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics parses the Prometheus text exposition format, so the integration tests can check the metrics
+// endpoints of the components like Prometheus would scrape them, without running Prometheus.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sample is one line of a metric family.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Family is a metric family with its samples, Type is "untyped" if the family wasn't declared.
+type Family struct {
+	Name    string
+	Type    string
+	Help    string
+	Samples []Sample
+}
+
+// Families are the metric families of one scrape by name.
+type Families map[string]*Family
+
+// sampleSuffixes are appended to the family name by the samples of histograms, summaries and counters.
+var sampleSuffixes = []string{"_bucket", "_sum", "_count", "_total", "_created"}
+
+// Parse reads the metric families of a scrape in the text exposition format.
+func Parse(reader io.Reader) (Families, error) {
+	families := Families{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(line, "#") {
+			err = families.parseComment(line)
+		} else {
+			err = families.parseSample(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	return families, scanner.Err()
+}
+
+func (f Families) family(name string) *Family {
+	family, ok := f[name]
+	if !ok {
+		family = &Family{Name: name, Type: "untyped"}
+		f[name] = family
+	}
+	return family
+}
+
+// parseComment reads HELP and TYPE lines, other comments are ignored.
+func (f Families) parseComment(line string) error {
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "#")), " ", 3)
+	if len(fields) < 3 || (fields[0] != "HELP" && fields[0] != "TYPE") {
+		return nil
+	}
+	family := f.family(fields[1])
+	if fields[0] == "HELP" {
+		family.Help = fields[2]
+	} else {
+		family.Type = strings.TrimSpace(fields[2])
+	}
+	return nil
+}
+
+// parseSample reads a sample line "name{label="value",...} value [timestamp]" into its family.
+func (f Families) parseSample(line string) error {
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return fmt.Errorf("sample without value: %q", line)
+	}
+	sample := Sample{Name: line[:nameEnd], Labels: map[string]string{}}
+	rest := line[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		rest, err = parseLabels(rest[1:], sample.Labels)
+		if err != nil {
+			return fmt.Errorf("sample %s: %w", sample.Name, err)
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("sample %s: expected a value and an optional timestamp, got %q", sample.Name, rest)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return fmt.Errorf("sample %s: %w", sample.Name, err)
+	}
+	sample.Value = value
+
+	family := f.family(f.familyName(sample.Name))
+	family.Samples = append(family.Samples, sample)
+	return nil
+}
+
+// familyName returns the declared family of the sample, the sample name itself if none was declared.
+func (f Families) familyName(sampleName string) string {
+	if _, ok := f[sampleName]; ok {
+		return sampleName
+	}
+	for _, suffix := range sampleSuffixes {
+		name := strings.TrimSuffix(sampleName, suffix)
+		if _, ok := f[name]; ok && name != sampleName {
+			return name
+		}
+	}
+	return sampleName
+}
+
+// parseLabels reads the labels up to the closing brace and returns the remaining line.
+func parseLabels(line string, labels map[string]string) (string, error) {
+	for {
+		line = strings.TrimLeft(line, " \t,")
+		if strings.HasPrefix(line, "}") {
+			return line[1:], nil
+		}
+		equals := strings.Index(line, "=")
+		if equals <= 0 || len(line) < equals+2 || line[equals+1] != '"' {
+			return "", fmt.Errorf("malformed label in %q", line)
+		}
+		name := strings.TrimSpace(line[:equals])
+
+		var value strings.Builder
+		i := equals + 2
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] != '\\' || i+1 == len(line) {
+				value.WriteByte(line[i])
+				continue
+			}
+			i++
+			switch line[i] {
+			case 'n':
+				value.WriteByte('\n')
+			default:
+				value.WriteByte(line[i])
+			}
+		}
+		if i == len(line) {
+			return "", fmt.Errorf("unterminated value of label %s", name)
+		}
+		labels[name] = value.String()
+		line = line[i+1:]
+	}
+}
+
+// WithPrefix returns the names of the families which start with the prefix and have samples, sorted.
+func (f Families) WithPrefix(prefix string) []string {
+	var names []string
+	for name, family := range f {
+		if strings.HasPrefix(name, prefix) && len(family.Samples) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequirePrefixes returns an error naming the prefixes no family with samples starts with.
+func (f Families) RequirePrefixes(prefixes ...string) error {
+	var missing []string
+	for _, prefix := range prefixes {
+		if len(f.WithPrefix(prefix)) == 0 {
+			missing = append(missing, prefix)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no metric families with the prefixes %s among %d families", strings.Join(missing, ", "), len(f))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// brokerScrape is an excerpt of the metrics of a Zeebe broker, as written by Micrometer.
+const brokerScrape = `# HELP jvm_memory_used_bytes The amount of used memory
+# TYPE jvm_memory_used_bytes gauge
+jvm_memory_used_bytes{area="heap",id="G1 Eden Space",} 1.2582912E7
+jvm_memory_used_bytes{area="nonheap",id="Metaspace",} 7.0123456E7
+# HELP jvm_gc_pause_seconds Time spent in GC pause
+# TYPE jvm_gc_pause_seconds summary
+jvm_gc_pause_seconds_count{action="end of minor GC",cause="G1 Evacuation Pause",} 4.0
+jvm_gc_pause_seconds_sum{action="end of minor GC",cause="G1 Evacuation Pause",} 0.031
+# HELP zeebe_stream_processor_records_total Number of records processed by stream processor
+# TYPE zeebe_stream_processor_records_total counter
+zeebe_stream_processor_records_total{action="written",partition="1",} 42.0
+# HELP zeebe_health Shows current health of the partition (1 = healthy, 0 = unhealthy, -1 = dead)
+# TYPE zeebe_health gauge
+zeebe_health{partition="1",} 1.0
+zeebe_health{partition="2",} 1.0
+
+# HELP zeebe_empty_family Declared without samples
+# TYPE zeebe_empty_family gauge
+`
+
+func TestParseShouldGroupSamplesByFamily(t *testing.T) {
+	t.Parallel()
+
+	// when
+	families, err := Parse(strings.NewReader(brokerScrape))
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, "gauge", families["jvm_memory_used_bytes"].Type)
+	require.Len(t, families["jvm_memory_used_bytes"].Samples, 2)
+	require.Equal(t, "summary", families["jvm_gc_pause_seconds"].Type)
+	require.Len(t, families["jvm_gc_pause_seconds"].Samples, 2)
+	require.Equal(t, "jvm_gc_pause_seconds_count", families["jvm_gc_pause_seconds"].Samples[0].Name)
+	require.Equal(t, 42.0, families["zeebe_stream_processor_records_total"].Samples[0].Value)
+	require.Equal(t, map[string]string{"partition": "2"}, families["zeebe_health"].Samples[1].Labels)
+	require.Equal(t, "Shows current health of the partition (1 = healthy, 0 = unhealthy, -1 = dead)", families["zeebe_health"].Help)
+}
+
+func TestParseShouldReadUntypedSamplesWithTimestamp(t *testing.T) {
+	t.Parallel()
+
+	// when
+	families, err := Parse(strings.NewReader("process_uptime_seconds 12.5 1670000000000\nup 1\n"))
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, "untyped", families["process_uptime_seconds"].Type)
+	require.Equal(t, 12.5, families["process_uptime_seconds"].Samples[0].Value)
+	require.Equal(t, 1.0, families["up"].Samples[0].Value)
+}
+
+func TestParseShouldUnescapeLabelValues(t *testing.T) {
+	t.Parallel()
+
+	// when
+	families, err := Parse(strings.NewReader(`http_requests{uri="/a\"b",note="line\nbreak",path="C:\\tmp"} 1` + "\n"))
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"uri": `/a"b`, "note": "line\nbreak", "path": `C:\tmp`}, families["http_requests"].Samples[0].Labels)
+}
+
+func TestParseShouldReadSpecialValues(t *testing.T) {
+	t.Parallel()
+
+	// when
+	families, err := Parse(strings.NewReader("a NaN\nb +Inf\nc -Inf\n"))
+
+	// then
+	require.NoError(t, err)
+	require.True(t, families["b"].Samples[0].Value > 0)
+	require.True(t, families["c"].Samples[0].Value < 0)
+}
+
+func TestParseShouldRejectMalformedLines(t *testing.T) {
+	t.Parallel()
+
+	for _, line := range []string{
+		"no_value",
+		`unterminated{label="value} 1`,
+		`no_quotes{label=value} 1`,
+		"not_a_number abc",
+		"too_many_fields 1 2 3",
+	} {
+		_, err := Parse(strings.NewReader("# TYPE ok gauge\nok 1\n" + line + "\n"))
+		require.ErrorContains(t, err, "line 3", line)
+	}
+}
+
+func TestRequirePrefixesShouldIgnoreFamiliesWithoutSamples(t *testing.T) {
+	t.Parallel()
+
+	// given
+	families, err := Parse(strings.NewReader(brokerScrape))
+	require.NoError(t, err)
+
+	// then
+	require.NoError(t, families.RequirePrefixes("zeebe_", "jvm_"))
+	require.Equal(t, []string{"zeebe_health", "zeebe_stream_processor_records_total"}, families.WithPrefix("zeebe_"))
+	require.ErrorContains(t, families.RequirePrefixes("jvm_", "zeebe_empty", "operate_"), "zeebe_empty, operate_")
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Step is the part of the browser login flow which failed.
+type Step string
+
+const (
+	StepRedirect      Step = "redirect"
+	StepFormDiscovery Step = "form discovery"
+	StepCredentials   Step = "credentials"
+	StepCookie        Step = "cookie"
+)
+
+// LoginError reports at which step of the browser login flow a login failed.
+type LoginError struct {
+	Step Step
+	URL  string
+	Err  error
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("login failed at the %s step (%s): %v", e.Step, e.URL, e.Err)
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// Credentials of the user which logs in through the Keycloak login page.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Session describes a successful browser login.
+type Session struct {
+	// ContextPath of Keycloak, "/auth" for the legacy Keycloak v16 and "" for Keycloak v19 by default.
+	ContextPath string
+	// Realm the user logged in to.
+	Realm string
+	// LoginFormURL is the URL of the Keycloak login page.
+	LoginFormURL string
+	// FinalURL is the URL of the application page the browser ended on after the log in.
+	FinalURL string
+}
+
+// keycloakAuthPathRegex matches the authorization endpoint of a realm, with and without a context path.
+var keycloakAuthPathRegex = regexp.MustCompile(`^(.*)/realms/([^/]+)/protocol/openid-connect/auth$`)
+
+const defaultMaxRedirects = 10
+
+// BrowserFlow logs in to a Camunda Platform application the same way a browser does, by following the
+// redirects to Keycloak, submitting the login form and following the redirects back to the application.
+type BrowserFlow struct {
+	client       http.Client
+	credentials  Credentials
+	MaxRedirects int
+	Logf         func(format string, args ...interface{})
+}
+
+// NewBrowserFlow creates a flow on top of the given client, the cookie jar of the client is shared
+// but redirects are followed explicitly by the flow.
+func NewBrowserFlow(client http.Client, credentials Credentials) *BrowserFlow {
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &BrowserFlow{
+		client:       client,
+		credentials:  credentials,
+		MaxRedirects: defaultMaxRedirects,
+		Logf:         func(format string, args ...interface{}) {},
+	}
+}
+
+// ParseKeycloakAuthURL returns the context path and realm of a Keycloak authorization endpoint URL.
+func ParseKeycloakAuthURL(u *url.URL) (contextPath string, realm string, ok bool) {
+	match := keycloakAuthPathRegex.FindStringSubmatch(u.Path)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// ResolveLoginForm follows the redirects from the application login URL to the Keycloak login page
+// and returns the login form found on it.
+func (f *BrowserFlow) ResolveLoginForm(loginUrl string) (*LoginForm, *Session, error) {
+	session := &Session{}
+	response, body, err := f.follow(http.MethodGet, loginUrl, nil, session)
+	if err != nil {
+		return nil, nil, err
+	}
+	session.LoginFormURL = response.Request.URL.String()
+
+	form, err := FindLoginForm(strings.NewReader(body), response.Request.URL)
+	if err != nil {
+		return nil, nil, &LoginError{Step: StepFormDiscovery, URL: session.LoginFormURL, Err: err}
+	}
+	return form, session, nil
+}
+
+// Login runs the complete browser login flow, starting with the application login URL.
+func (f *BrowserFlow) Login(loginUrl string) (*Session, error) {
+	form, session, err := f.ResolveLoginForm(loginUrl)
+	if err != nil {
+		return nil, err
+	}
+	f.Logf("Send log in request to %s", form.Action)
+
+	values := form.Values(f.credentials)
+	response, body, err := f.follow(http.MethodPost, form.Action, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keycloak doesn't reject the credentials with an error status, it renders the login page again.
+	if rejectedForm, err := FindLoginForm(strings.NewReader(body), response.Request.URL); err == nil {
+		reason := rejectedForm.Error
+		if reason == "" {
+			reason = "the login page was returned again"
+		}
+		return nil, &LoginError{
+			Step: StepCredentials,
+			URL:  form.Action,
+			Err:  fmt.Errorf("credentials of user '%s' were rejected: %s", f.credentials.Username, reason),
+		}
+	}
+
+	session.FinalURL = response.Request.URL.String()
+	return session, nil
+}
+
+// follow sends the request and follows all redirects until a page is returned, the last response
+// and its body are returned.
+func (f *BrowserFlow) follow(method string, target string, form url.Values, session *Session) (*http.Response, string, error) {
+	for redirects := 0; ; redirects++ {
+		if redirects > f.MaxRedirects {
+			return nil, "", &LoginError{Step: StepRedirect, URL: target, Err: fmt.Errorf("stopped after %d redirects", f.MaxRedirects)}
+		}
+
+		response, body, err := f.send(method, target, form)
+		if err != nil {
+			return nil, "", &LoginError{Step: StepRedirect, URL: target, Err: err}
+		}
+		if contextPath, realm, ok := ParseKeycloakAuthURL(response.Request.URL); ok {
+			session.ContextPath = contextPath
+			session.Realm = realm
+		}
+
+		switch {
+		case response.StatusCode >= 300 && response.StatusCode < 400:
+			location, err := response.Location()
+			if err != nil {
+				return nil, "", &LoginError{Step: StepRedirect, URL: target, Err: err}
+			}
+			f.Logf("Follow redirect (%d) from '%s' to '%s'", response.StatusCode, target, location)
+			// like browsers, all redirects after a form post are followed with GET
+			method, target, form = http.MethodGet, location.String(), nil
+		case response.StatusCode == http.StatusOK:
+			return response, body, nil
+		default:
+			return nil, "", &LoginError{
+				Step: StepRedirect,
+				URL:  target,
+				Err:  fmt.Errorf("expected a redirect or a 200 status code, but got %d", response.StatusCode),
+			}
+		}
+	}
+}
+
+func (f *BrowserFlow) send(method string, target string, form url.Values) (*http.Response, string, error) {
+	var requestBody io.Reader
+	if form != nil {
+		requestBody = strings.NewReader(form.Encode())
+	}
+	request, err := http.NewRequest(method, target, requestBody)
+	if err != nil {
+		return nil, "", err
+	}
+	if form != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot read response body: %w", err)
+	}
+	return response, string(body), nil
+}
+
+// CookieValue returns the value of the named cookie the jar holds for the given URL.
+func CookieValue(jar http.CookieJar, u *url.URL, name string) (string, error) {
+	if jar == nil {
+		return "", &LoginError{Step: StepCookie, URL: u.String(), Err: errors.New("no cookie jar configured")}
+	}
+	for _, cookie := range jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie.Value, nil
+		}
+	}
+	return "", &LoginError{Step: StepCookie, URL: u.String(), Err: fmt.Errorf("no %s cookie found", name)}
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const keycloakLoginFormId = "kc-form-login"
+
+// LoginForm is the login form of a Keycloak login page.
+type LoginForm struct {
+	// Action is the absolute URL the form is posted to, including the session code.
+	Action string
+	// UsernameField and PasswordField are the names of the credential inputs.
+	UsernameField string
+	PasswordField string
+	// Hidden are the hidden inputs of the form, which are posted as they are.
+	Hidden url.Values
+	// Error is the feedback message Keycloak shows on the page, e.g. after rejected credentials.
+	Error string
+}
+
+// Values returns the form values to post for the given credentials.
+func (f *LoginForm) Values(credentials Credentials) url.Values {
+	values := url.Values{}
+	for name, value := range f.Hidden {
+		values[name] = value
+	}
+	values.Set(f.UsernameField, credentials.Username)
+	values.Set(f.PasswordField, credentials.Password)
+	return values
+}
+
+type formCandidate struct {
+	form          LoginForm
+	id            string
+	hasPassword   bool
+	hasActionAttr bool
+}
+
+// FindLoginForm tokenizes the page and returns the Keycloak login form. The form with the "kc-form-login" id is
+// preferred, otherwise the first form with a password input is used, so custom themes are supported as well.
+// The Keycloak pages are not always valid HTML, the tokenizer doesn't require that.
+func FindLoginForm(page io.Reader, pageUrl *url.URL) (*LoginForm, error) {
+	tokenizer := html.NewTokenizer(page)
+
+	var candidates []*formCandidate
+	var current *formCandidate
+	var feedback strings.Builder
+	inFeedback := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if !errors.Is(tokenizer.Err(), io.EOF) {
+				return nil, fmt.Errorf("cannot tokenize login page: %w", tokenizer.Err())
+			}
+			return selectLoginForm(candidates, pageUrl, strings.TrimSpace(feedback.String()))
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attributes := attributeMap(token)
+			switch token.Data {
+			case "form":
+				current = &formCandidate{id: attributes["id"], form: LoginForm{Hidden: url.Values{}}}
+				current.form.Action, current.hasActionAttr = attributes["action"], hasAttribute(token, "action")
+				candidates = append(candidates, current)
+			case "input":
+				if current != nil {
+					addInput(current, attributes)
+				}
+			case "span", "div":
+				if inFeedback > 0 {
+					inFeedback++
+				} else if strings.Contains(attributes["class"], "kc-feedback-text") && tokenType == html.StartTagToken {
+					inFeedback = 1
+				}
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "form":
+				current = nil
+			case "span", "div":
+				if inFeedback > 0 {
+					inFeedback--
+				}
+			}
+
+		case html.TextToken:
+			if inFeedback > 0 {
+				feedback.WriteString(tokenizer.Token().Data)
+			}
+		}
+	}
+}
+
+func addInput(candidate *formCandidate, attributes map[string]string) {
+	name := attributes["name"]
+	switch strings.ToLower(attributes["type"]) {
+	case "password":
+		candidate.hasPassword = true
+		candidate.form.PasswordField = name
+	case "hidden":
+		if name != "" {
+			candidate.form.Hidden.Add(name, attributes["value"])
+		}
+	case "submit", "button", "checkbox":
+	default:
+		if candidate.form.UsernameField == "" && name != "" {
+			candidate.form.UsernameField = name
+		}
+	}
+}
+
+func selectLoginForm(candidates []*formCandidate, pageUrl *url.URL, feedback string) (*LoginForm, error) {
+	var selected *formCandidate
+	for _, candidate := range candidates {
+		if candidate.id == keycloakLoginFormId {
+			selected = candidate
+			break
+		}
+		if selected == nil && candidate.hasPassword {
+			selected = candidate
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("no login form found on the page, found %d other forms", len(candidates))
+	}
+	if !selected.hasActionAttr || selected.form.Action == "" {
+		return nil, errors.New("the login form has no action")
+	}
+	if selected.form.PasswordField == "" || selected.form.UsernameField == "" {
+		return nil, errors.New("the login form has no username or password input")
+	}
+
+	// The tokenizer already unescapes entities like "&amp;", only relative actions need to be resolved.
+	action, err := url.Parse(selected.form.Action)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login form action: %w", err)
+	}
+	if pageUrl != nil {
+		action = pageUrl.ResolveReference(action)
+	}
+
+	form := selected.form
+	form.Action = action.String()
+	form.Error = feedback
+	return &form, nil
+}
+
+func attributeMap(token html.Token) map[string]string {
+	attributes := make(map[string]string, len(token.Attr))
+	for _, attribute := range token.Attr {
+		attributes[attribute.Key] = attribute.Val
+	}
+	return attributes
+}
+
+func hasAttribute(token html.Token, key string) bool {
+	for _, attribute := range token.Attr {
+		if attribute.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// TokenURL returns the token endpoint of a realm, the issuer URL is the URL of the realm.
+func TokenURL(issuerUrl string) string {
+	return strings.TrimSuffix(issuerUrl, "/") + "/protocol/openid-connect/token"
+}
+
+// ClientCredentials requests an access token with the client credentials grant, like the Camunda Platform
+// components do for machine-to-machine calls. A rejected client is reported as *TokenError.
+func ClientCredentials(ctx context.Context, client *http.Client, tokenUrl string, clientId string, clientSecret string) (string, error) {
+	config := clientcredentials.Config{
+		ClientID:     clientId,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenUrl,
+		AuthStyle:    oauth2.AuthStyleInParams,
+	}
+	if client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+
+	token, err := config.Token(ctx)
+	if err != nil {
+		var retrieveError *oauth2.RetrieveError
+		if errors.As(err, &retrieveError) {
+			return "", &TokenError{ClientId: clientId, StatusCode: retrieveError.Response.StatusCode, Body: string(retrieveError.Body)}
+		}
+		return "", fmt.Errorf("cannot request token for client '%s': %w", clientId, err)
+	}
+	return token.AccessToken, nil
+}
+
+// TokenError is returned when the token endpoint rejects a token request.
+type TokenError struct {
+	ClientId   string
+	StatusCode int
+	Body       string
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("token request of client '%s' was rejected with status %d: %s", e.ClientId, e.StatusCode, e.Body)
+}
+
+// Audience is the "aud" claim, which is either a single string or a list of strings.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid audience claim: %w", err)
+	}
+	*a = list
+	return nil
+}
+
+// Contains returns whether the audience includes the given value.
+func (a Audience) Contains(audience string) bool {
+	for _, value := range a {
+		if value == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims are the claims of an access token the tests look at.
+type Claims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        Audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	ExpiresAt       int64    `json:"exp"`
+}
+
+// ParseClaims decodes the claims of a JWT, the signature is not verified, that is the job of the components.
+func ParseClaims(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected a JWT with 3 parts, but got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode JWT payload: %w", err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("cannot parse JWT claims: %w", err)
+	}
+	return &claims, nil
+}
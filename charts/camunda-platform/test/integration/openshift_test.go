@@ -24,7 +24,6 @@ import (
 	openshiftv1 "github.com/openshift/api/project/v1"
 	projectv1 "github.com/openshift/client-go/project/clientset/versioned/typed/project/v1"
 	"github.com/stretchr/testify/require"
-	"github.com/stretchr/testify/suite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"path/filepath"
 	"testing"
@@ -42,16 +41,19 @@ func TestOpenShift(t *testing.T) {
 	oc, err := getOpenshiftProjectClient(t)
 	require.NoError(t, err)
 
-	suite.Run(t, &openshiftSuite{integrationSuite{
-		chartPath:      chartPath,
-		release:        "camunda-platform-it",
-		keycloakLegacy: true,
-	}, oc})
+	runParallel(t, parallelLimit(t), func() *openshiftSuite {
+		return &openshiftSuite{integrationSuite{
+			chartPath:      chartPath,
+			releasePrefix:  "camunda-platform-it",
+			keycloakLegacy: true,
+		}, oc}
+	})
 }
 
 func (s *openshiftSuite) SetupTest() {
-	nsMetadata := createNamespaceObjectMeta()
+	nsMetadata := createNamespaceObjectMeta(s.T().Name())
 	s.namespace = nsMetadata.Name
+	s.release = testReleaseName(s.releasePrefix, s.namespace)
 	s.kubeOptions = k8s.NewKubectlOptions("", "", s.namespace)
 
 	if !s.doesProjectExist() {
@@ -61,6 +63,7 @@ func (s *openshiftSuite) SetupTest() {
 }
 
 func (s *openshiftSuite) TearDownTest() {
+	s.closePortForwards()
 	if s.doesProjectExist() {
 		err := s.deleteProject()
 		if err != nil {
@@ -73,6 +76,7 @@ func (s *openshiftSuite) TestServicesEnd2End() {
 	// given
 	options := &helm.Options{
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 		ValuesFiles: []string{
 			"../../openshift/values.yaml",
 			"../../openshift/values-patch.yaml",
@@ -103,6 +107,7 @@ func (s *integrationSuite) TestServicesEnd2EndWithKeycloakV19() {
 	// given
 	options := &helm.Options{
 		KubectlOptions: s.kubeOptions,
+		SetValues:      s.publicUrlValues(),
 		ValuesFiles: []string{
 			"it-keycloak-v19-values.yaml",
 			"../../openshift/values.yaml",
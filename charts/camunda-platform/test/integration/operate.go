@@ -0,0 +1,122 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+type operateProcessInstance struct {
+	Key           int64  `json:"key"`
+	BpmnProcessId string `json:"bpmnProcessId"`
+	State         string `json:"state"`
+}
+
+type operateProcessInstanceSearchResponse struct {
+	Items []operateProcessInstance `json:"items"`
+	Total int64                    `json:"total"`
+}
+
+type operateVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	ProcessInstanceKey int64  `json:"processInstanceKey"`
+}
+
+type operateVariableSearchResponse struct {
+	Items []operateVariable `json:"items"`
+	Total int64             `json:"total"`
+}
+
+func (s *integrationSuite) searchProcessInstancesFromOperate(filter map[string]interface{}, size int) ([]operateProcessInstance, error) {
+	var response operateProcessInstanceSearchResponse
+	err := s.searchFromOperate("/v1/process-instances/search", filter, size, &response)
+	return response.Items, err
+}
+
+func (s *integrationSuite) searchVariablesFromOperate(processInstanceKey int64) ([]operateVariable, error) {
+	var response operateVariableSearchResponse
+	filter := map[string]interface{}{"processInstanceKey": processInstanceKey}
+	err := s.searchFromOperate("/v1/variables/search", filter, 100, &response)
+	return response.Items, err
+}
+
+func (s *integrationSuite) searchFromOperate(path string, filter map[string]interface{}, size int, response interface{}) error {
+	endpoint, httpClient, err := s.doLogin("operate", 8080)
+	if err != nil {
+		return err
+	}
+
+	request, err := json.Marshal(map[string]interface{}{
+		"filter": filter,
+		"size":   size,
+	})
+	if err != nil {
+		return err
+	}
+
+	responseBuf, err := s.queryApi(httpClient, s.webScheme()+"://"+endpoint+path, bytes.NewBuffer(request))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(responseBuf.Bytes(), response)
+}
+
+// assertProcessInstanceCompletedInOperate waits until Operate reports the given process instance as completed
+// and verifies that the instance holds the expected variables.
+func (s *integrationSuite) assertProcessInstanceCompletedInOperate(processInstanceKey int64, expectedVariables map[string]interface{}) {
+	message := retry.DoWithRetry(s.T(),
+		"Try to query and assert completed process instance from operate",
+		20,
+		10*time.Second,
+		func() (string, error) {
+			filter := map[string]interface{}{"key": processInstanceKey}
+			instances, err := s.searchProcessInstancesFromOperate(filter, 1)
+			if err != nil {
+				return "", err
+			}
+			if len(instances) != 1 || instances[0].State != "COMPLETED" {
+				return "", fmt.Errorf("expected process instance %d to be completed, got %+v", processInstanceKey, instances)
+			}
+
+			variables, err := s.searchVariablesFromOperate(processInstanceKey)
+			if err != nil {
+				return "", err
+			}
+			actualVariables := map[string]string{}
+			for _, variable := range variables {
+				actualVariables[variable.Name] = variable.Value
+			}
+			for name, value := range expectedVariables {
+				expectedValue, err := json.Marshal(value)
+				if err != nil {
+					return "", err
+				}
+				actualValue, exists := actualVariables[name]
+				if !exists {
+					return "", fmt.Errorf("expected variable '%s' on process instance %d", name, processInstanceKey)
+				}
+				s.Require().JSONEq(string(expectedValue), actualValue)
+			}
+
+			return fmt.Sprintf("Process instance %d completed with the expected variables in operate!", processInstanceKey), nil
+		})
+	s.T().Logf(message)
+}
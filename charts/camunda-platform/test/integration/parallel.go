@@ -0,0 +1,190 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// parallelSuite is a suite whose test methods can run in parallel, each on its own instance.
+type parallelSuite interface {
+	suite.TestingSuite
+	SetupTest()
+	TearDownTest()
+}
+
+// testSlots limits how many tests run at the same time.
+type testSlots chan struct{}
+
+func newTestSlots(limit int) testSlots {
+	return make(testSlots, limit)
+}
+
+// acquire blocks until a slot is free, the returned function frees the slot again.
+func (s testSlots) acquire() func() {
+	s <- struct{}{}
+	return func() { <-s }
+}
+
+// runParallel runs every Test method of the suite as a parallel subtest. Unlike suite.Run, every method gets its own
+// suite instance from newSuite, so the namespace, release and tunnels of a test are never shared. At most limit tests
+// run at the same time. Methods are selected with -run like "TestIntegration/TestServicesEnd2End$" or with -testify.m.
+func runParallel[S parallelSuite](t *testing.T, limit int, newSuite func() S) {
+	slots := newTestSlots(limit)
+	suiteType := reflect.TypeOf(newSuite())
+	methodFilter := testifyMethodFilter(t)
+
+	for i := 0; i < suiteType.NumMethod(); i++ {
+		method := suiteType.Method(i)
+		if !strings.HasPrefix(method.Name, "Test") || !methodFilter.MatchString(method.Name) {
+			continue
+		}
+		t.Run(method.Name, func(t *testing.T) {
+			t.Parallel()
+			release := slots.acquire()
+			defer release()
+
+			s := newSuite()
+			s.SetT(t)
+			// TearDownTest runs as well if the test fails with FailNow, which exits the goroutine
+			defer s.TearDownTest()
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("test panicked: %v\n%s", r, debug.Stack())
+				}
+			}()
+			s.SetupTest()
+			method.Func.Call([]reflect.Value{reflect.ValueOf(s)})
+		})
+	}
+}
+
+// testifyMethodFilter returns the -testify.m filter, which is still supported to select the test methods.
+func testifyMethodFilter(t *testing.T) *regexp.Regexp {
+	pattern := ""
+	if methodFlag := flag.Lookup("testify.m"); methodFlag != nil {
+		pattern = methodFlag.Value.String()
+	}
+	filter, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatalf("invalid -testify.m filter '%s': %v", pattern, err)
+	}
+	return filter
+}
+
+// parallelLimit returns how many tests may run at the same time, configured with CAMUNDA_DISTRO_TEST_PARALLEL.
+// The value "auto" derives the limit from the free capacity of the cluster and the resources a release requests,
+// configured with CAMUNDA_DISTRO_TEST_RELEASE_CPU and CAMUNDA_DISTRO_TEST_RELEASE_MEMORY.
+func parallelLimit(t *testing.T) int {
+	value := getEnv("CAMUNDA_DISTRO_TEST_PARALLEL", "1")
+	if value != "auto" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			t.Fatalf("CAMUNDA_DISTRO_TEST_PARALLEL must be a positive number or 'auto', but is '%s'", value)
+		}
+		return limit
+	}
+
+	perRelease := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(getEnv("CAMUNDA_DISTRO_TEST_RELEASE_CPU", "8")),
+		corev1.ResourceMemory: resource.MustParse(getEnv("CAMUNDA_DISTRO_TEST_RELEASE_MEMORY", "12Gi")),
+	}
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(t, k8s.NewKubectlOptions("", "", ""))
+	if err != nil {
+		t.Fatalf("Cannot create Kubernetes client to determine the cluster capacity: %v", err)
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Cannot list nodes to determine the cluster capacity: %v", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		t.Fatalf("Cannot list pods to determine the cluster capacity: %v", err)
+	}
+
+	limit := capacityLimit(nodes.Items, pods.Items, perRelease)
+	t.Logf("The free cluster capacity allows %d releases to be tested at the same time", limit)
+	return limit
+}
+
+// capacityLimit returns how many releases fit into the free capacity of the schedulable nodes, at least one.
+func capacityLimit(nodes []corev1.Node, pods []corev1.Pod, perRelease corev1.ResourceList) int {
+	free := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.Quantity{},
+		corev1.ResourceMemory: resource.Quantity{},
+	}
+	schedulable := map[string]bool{}
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !isNodeReady(node) {
+			continue
+		}
+		schedulable[node.Name] = true
+		for name, quantity := range free {
+			quantity.Add(node.Status.Allocatable[name])
+			free[name] = quantity
+		}
+	}
+	for _, pod := range pods {
+		if !schedulable[pod.Spec.NodeName] {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			for name, quantity := range free {
+				quantity.Sub(container.Resources.Requests[name])
+				free[name] = quantity
+			}
+		}
+	}
+
+	limit := -1
+	for name, quantity := range free {
+		required := perRelease[name]
+		if required.IsZero() {
+			continue
+		}
+		fits := int(quantity.MilliValue() / required.MilliValue())
+		if limit < 0 || fits < limit {
+			limit = fits
+		}
+	}
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func readyNode(name string, cpu string, memory string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestCapacityLimitShouldSubtractRequestsOfRunningPods(t *testing.T) {
+	t.Parallel()
+
+	// given
+	notReady := readyNode("node-c", "64", "256Gi")
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+	nodes := []corev1.Node{readyNode("node-a", "16", "64Gi"), readyNode("node-b", "16", "64Gi"), notReady}
+	pods := []corev1.Pod{{
+		Spec: corev1.PodSpec{
+			NodeName: "node-a",
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("8"),
+				}},
+			}},
+		},
+	}}
+	perRelease := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("8"),
+		corev1.ResourceMemory: resource.MustParse("12Gi"),
+	}
+
+	// when
+	limit := capacityLimit(nodes, pods, perRelease)
+
+	// then
+	require.Equal(t, 3, limit)
+}
+
+func TestCapacityLimitShouldBeAtLeastOne(t *testing.T) {
+	t.Parallel()
+
+	// given
+	nodes := []corev1.Node{readyNode("node-a", "2", "4Gi")}
+	perRelease := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")}
+
+	// when
+	limit := capacityLimit(nodes, nil, perRelease)
+
+	// then
+	require.Equal(t, 1, limit)
+}
+
+func TestNamespaceShouldBeUniquePerTest(t *testing.T) {
+	// given
+	t.Setenv("GITHUB_PR_NUMBER", "1234")
+	t.Setenv("GITHUB_PR_HEAD_SHA_SHORT", "abcdef0")
+	t.Setenv("GITHUB_WORKFLOW_RUN_ID", "4242424242")
+	t.Setenv("GITHUB_WORKFLOW_JOB_ID", "1010101010")
+
+	// when
+	first := createNamespaceObjectMeta("TestIntegration/TestServicesEnd2End")
+	second := createNamespaceObjectMeta("TestIntegration/TestCreateProcessInstanceViaAPI")
+
+	// then
+	require.NotEqual(t, first.Name, second.Name)
+	require.LessOrEqual(t, len(first.Name), 63)
+	require.True(t, strings.HasSuffix(first.Name, "-sfx-1010101010-"+testId("TestIntegration/TestServicesEnd2End")))
+	require.Equal(t, "TestIntegration_TestServicesEnd2End", first.Labels["test-name"])
+	require.Equal(t, first.Name, createNamespaceObjectMeta("TestIntegration/TestServicesEnd2End").Name,
+		"reruns in the same job use the same namespace")
+	require.NotEqual(t, testReleaseName("camunda-platform-it", first.Name), testReleaseName("camunda-platform-it", second.Name))
+}
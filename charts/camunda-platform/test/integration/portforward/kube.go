@@ -0,0 +1,47 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portforward
+
+import (
+	"io"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// KubeForwarder opens the tunnels to services with the port-forward of the Kubernetes API.
+type KubeForwarder struct {
+	T       testing.TestingT
+	Options *k8s.KubectlOptions
+}
+
+type kubeTunnel struct {
+	tunnel *k8s.Tunnel
+}
+
+func (t kubeTunnel) Close() error {
+	t.tunnel.Close()
+	return nil
+}
+
+// Forward selects a ready pod of the service and forwards the local port to the target port of that pod.
+func (f KubeForwarder) Forward(target Target, localPort int) (io.Closer, error) {
+	tunnel := k8s.NewTunnel(f.Options, k8s.ResourceTypeService, target.Service, localPort, target.Port)
+	if err := tunnel.ForwardPortE(f.T); err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return kubeTunnel{tunnel: tunnel}, nil
+}
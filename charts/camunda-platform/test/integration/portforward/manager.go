@@ -0,0 +1,212 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portforward
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Target is a port of a service inside the cluster, the port is the container port the tunnel connects to.
+type Target struct {
+	Service string
+	Port    int
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s:%d", t.Service, t.Port)
+}
+
+// Forwarder opens a tunnel from a local port to the target.
+type Forwarder interface {
+	Forward(target Target, localPort int) (io.Closer, error)
+}
+
+type tunnel struct {
+	localPort int
+	closer    io.Closer
+}
+
+func (t *tunnel) endpoint() string {
+	return fmt.Sprintf("localhost:%d", t.localPort)
+}
+
+const (
+	defaultAttempts = 10
+	defaultInterval = 3 * time.Second
+)
+
+// Manager owns the tunnels of a test. Tunnels are shared per target, health checked before they are handed out and
+// reopened on the same local port if the pod behind them was restarted. Local ports are free ports, unless the port
+// of the service is pinned, which is needed for services whose URL is part of the release, like the Keycloak issuer.
+type Manager struct {
+	forwarder Forwarder
+
+	// HealthCheck checks an open tunnel, by default a TCP connection is opened. The tunnel of a restarted pod
+	// stops listening, so the check fails and the tunnel is reopened.
+	HealthCheck func(endpoint string) error
+	// FreePort returns a free local port.
+	FreePort func() (int, error)
+	// Attempts and Interval define how often a tunnel is tried to be opened, the pod might not be ready yet.
+	Attempts int
+	Interval time.Duration
+	Logf     func(format string, args ...interface{})
+
+	mu      sync.Mutex
+	pinned  map[string]int
+	tunnels map[Target]*tunnel
+}
+
+// NewManager creates a manager which opens the tunnels with the given forwarder.
+func NewManager(forwarder Forwarder) *Manager {
+	return &Manager{
+		forwarder:   forwarder,
+		HealthCheck: dialTCP,
+		FreePort:    freePort,
+		Attempts:    defaultAttempts,
+		Interval:    defaultInterval,
+		Logf:        func(format string, args ...interface{}) {},
+		pinned:      map[string]int{},
+		tunnels:     map[Target]*tunnel{},
+	}
+}
+
+// Pin makes all tunnels to the service use the given local port.
+func (m *Manager) Pin(service string, localPort int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pinned[service] = localPort
+}
+
+// PinnedPort returns the local port the service is pinned to.
+func (m *Manager) PinnedPort(service string) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	port, ok := m.pinned[service]
+	return port, ok
+}
+
+// Endpoint returns the local endpoint of the tunnel to the target, the tunnel is opened on first use.
+func (m *Manager) Endpoint(target Target) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.tunnels[target]; ok {
+		err := m.HealthCheck(existing.endpoint())
+		if err == nil {
+			return existing.endpoint(), nil
+		}
+		m.Logf("Tunnel to %s on %s is broken, reconnecting: %v", target, existing.endpoint(), err)
+		_ = existing.closer.Close()
+		delete(m.tunnels, target)
+		return m.open(target, existing.localPort)
+	}
+
+	localPort, pinned := m.pinned[target.Service]
+	if pinned {
+		for other, existing := range m.tunnels {
+			if existing.localPort == localPort {
+				return "", fmt.Errorf("cannot open tunnel to %s on pinned port %d, it is used by the tunnel to %s", target, localPort, other)
+			}
+		}
+	} else {
+		port, err := m.FreePort()
+		if err != nil {
+			return "", fmt.Errorf("cannot find free local port for %s: %w", target, err)
+		}
+		localPort = port
+	}
+	return m.open(target, localPort)
+}
+
+func (m *Manager) open(target Target, localPort int) (string, error) {
+	var lastErr error
+	for attempt := 1; attempt <= m.Attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(m.Interval)
+		}
+
+		closer, err := m.forwarder.Forward(target, localPort)
+		if err != nil {
+			lastErr = err
+			m.Logf("Attempt %d/%d to open tunnel to %s on port %d failed: %v", attempt, m.Attempts, target, localPort, err)
+			continue
+		}
+		opened := &tunnel{localPort: localPort, closer: closer}
+		if err := m.HealthCheck(opened.endpoint()); err != nil {
+			_ = closer.Close()
+			lastErr = err
+			m.Logf("Attempt %d/%d, tunnel to %s on port %d is not healthy: %v", attempt, m.Attempts, target, localPort, err)
+			continue
+		}
+
+		m.Logf("Opened tunnel to %s on %s", target, opened.endpoint())
+		m.tunnels[target] = opened
+		return opened.endpoint(), nil
+	}
+	return "", fmt.Errorf("cannot open tunnel to %s after %d attempts: %w", target, m.Attempts, lastErr)
+}
+
+// Targets returns the targets of the open tunnels, sorted by service and port.
+func (m *Manager) Targets() []Target {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	targets := make([]Target, 0, len(m.tunnels))
+	for target := range m.tunnels {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Service != targets[j].Service {
+			return targets[i].Service < targets[j].Service
+		}
+		return targets[i].Port < targets[j].Port
+	})
+	return targets
+}
+
+// CloseAll closes all tunnels, the pinned ports are kept.
+func (m *Manager) CloseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for target, opened := range m.tunnels {
+		if err := opened.closer.Close(); err != nil {
+			m.Logf("Failed to close tunnel to %s: %v", target, err)
+		}
+		delete(m.tunnels, target)
+	}
+}
+
+func dialTCP(endpoint string) error {
+	connection, err := net.DialTimeout("tcp", endpoint, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	return connection.Close()
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
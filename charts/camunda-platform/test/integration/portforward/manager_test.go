@@ -0,0 +1,192 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portforward
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// localForwarder listens on the local port like a port-forward does, without forwarding anything.
+type localForwarder struct {
+	mu        sync.Mutex
+	forwarded []Target
+	failures  int
+	listeners map[Target]net.Listener
+}
+
+func (f *localForwarder) Forward(target Target, localPort int) (io.Closer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("pod is not running")
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", localPort))
+	if err != nil {
+		return nil, err
+	}
+	f.forwarded = append(f.forwarded, target)
+	f.listeners[target] = listener
+	return listener, nil
+}
+
+// restartPod closes the listener of the target, like the port-forward does when the pod is gone.
+func (f *localForwarder) restartPod(target Target) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.listeners[target].Close()
+}
+
+type managerTest struct {
+	suite.Suite
+	forwarder *localForwarder
+	manager   *Manager
+}
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	suite.Run(t, &managerTest{})
+}
+
+func (s *managerTest) SetupTest() {
+	s.forwarder = &localForwarder{listeners: map[Target]net.Listener{}}
+	s.manager = NewManager(s.forwarder)
+	s.manager.Interval = 0
+}
+
+func (s *managerTest) TearDownTest() {
+	s.manager.CloseAll()
+}
+
+func (s *managerTest) TestEndpointShouldShareTunnelPerTarget() {
+	// given
+	operate := Target{Service: "operate", Port: 8080}
+
+	// when
+	first, err := s.manager.Endpoint(operate)
+	s.Require().NoError(err)
+	second, err := s.manager.Endpoint(operate)
+	s.Require().NoError(err)
+
+	// then
+	s.Require().Equal(first, second)
+	s.Require().Equal([]Target{operate}, s.forwarder.forwarded)
+}
+
+func (s *managerTest) TestEndpointShouldUseDifferentFreePortsPerTarget() {
+	// given
+	operate := Target{Service: "operate", Port: 8080}
+	tasklist := Target{Service: "tasklist", Port: 8080}
+
+	// when
+	operateEndpoint, err := s.manager.Endpoint(operate)
+	s.Require().NoError(err)
+	tasklistEndpoint, err := s.manager.Endpoint(tasklist)
+	s.Require().NoError(err)
+
+	// then
+	s.Require().NotEqual(operateEndpoint, tasklistEndpoint)
+	s.Require().Equal([]Target{operate, tasklist}, s.manager.Targets())
+}
+
+func (s *managerTest) TestEndpointShouldUsePinnedPort() {
+	// given
+	port, err := freePort()
+	s.Require().NoError(err)
+	s.manager.Pin("keycloak", port)
+
+	// when
+	endpoint, err := s.manager.Endpoint(Target{Service: "keycloak", Port: 8080})
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Equal(fmt.Sprintf("localhost:%d", port), endpoint)
+}
+
+func (s *managerTest) TestEndpointShouldRejectSecondTargetOnPinnedPort() {
+	// given
+	port, err := freePort()
+	s.Require().NoError(err)
+	s.manager.Pin("keycloak", port)
+	_, err = s.manager.Endpoint(Target{Service: "keycloak", Port: 8080})
+	s.Require().NoError(err)
+
+	// when
+	_, err = s.manager.Endpoint(Target{Service: "keycloak", Port: 9990})
+
+	// then
+	s.Require().ErrorContains(err, "pinned port")
+}
+
+func (s *managerTest) TestEndpointShouldReconnectOnSamePortAfterPodRestart() {
+	// given
+	zeebe := Target{Service: "zeebe-gateway", Port: 26500}
+	before, err := s.manager.Endpoint(zeebe)
+	s.Require().NoError(err)
+
+	// when
+	s.forwarder.restartPod(zeebe)
+	after, err := s.manager.Endpoint(zeebe)
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Equal(before, after)
+	s.Require().Len(s.forwarder.forwarded, 2)
+}
+
+func (s *managerTest) TestEndpointShouldRetryUntilPodIsReady() {
+	// given
+	s.forwarder.failures = 2
+
+	// when
+	_, err := s.manager.Endpoint(Target{Service: "operate", Port: 8080})
+
+	// then
+	s.Require().NoError(err)
+}
+
+func (s *managerTest) TestEndpointShouldFailAfterAllAttempts() {
+	// given
+	s.manager.Attempts = 3
+	s.forwarder.failures = 3
+
+	// when
+	_, err := s.manager.Endpoint(Target{Service: "operate", Port: 8080})
+
+	// then
+	s.Require().ErrorContains(err, "after 3 attempts: pod is not running")
+}
+
+func (s *managerTest) TestCloseAllShouldCloseTunnels() {
+	// given
+	endpoint, err := s.manager.Endpoint(Target{Service: "operate", Port: 8080})
+	s.Require().NoError(err)
+
+	// when
+	s.manager.CloseAll()
+
+	// then
+	s.Require().Empty(s.manager.Targets())
+	s.Require().Error(dialTCP(endpoint))
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portforward
+
+import (
+	"fmt"
+	"sync"
+)
+
+const maxReserveAttempts = 100
+
+// Registry hands out local ports to the tests of a process. A free port is only free until it is bound, and tunnels
+// are opened long after their port was chosen, so the port stays reserved for its owner until it is released.
+type Registry struct {
+	mu     sync.Mutex
+	owners map[int]string
+	// FreePort returns a port which is currently not bound.
+	FreePort func() (int, error)
+}
+
+// Ports is the registry shared by all tests of the process.
+var Ports = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{owners: map[int]string{}, FreePort: freePort}
+}
+
+// Reserve returns a free port which isn't reserved by another owner yet.
+func (r *Registry) Reserve(owner string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		port, err := r.FreePort()
+		if err != nil {
+			return 0, err
+		}
+		if _, reserved := r.owners[port]; !reserved {
+			r.owners[port] = owner
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot find a free port which is not reserved after %d attempts", maxReserveAttempts)
+}
+
+// ReserveExact reserves the given port, it fails if the port is reserved by another owner.
+func (r *Registry) ReserveExact(owner string, port int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if other, reserved := r.owners[port]; reserved && other != owner {
+		return fmt.Errorf("port %d is already reserved by %s", port, other)
+	}
+	r.owners[port] = owner
+	return nil
+}
+
+// Release releases all ports of the owner.
+func (r *Registry) Release(owner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for port, other := range r.owners {
+		if other == owner {
+			delete(r.owners, port)
+		}
+	}
+}
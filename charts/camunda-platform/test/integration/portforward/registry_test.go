@@ -0,0 +1,49 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portforward
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ports := []int{40001, 40001, 40002, 40003}
+	registry := NewRegistry()
+	registry.FreePort = func() (int, error) {
+		port := ports[0]
+		ports = ports[1:]
+		return port, nil
+	}
+
+	// when
+	first, err := registry.Reserve("TestA")
+	require.NoError(t, err)
+	second, err := registry.Reserve("TestB")
+	require.NoError(t, err)
+	exactErr := registry.ReserveExact("TestB", first)
+	registry.Release("TestA")
+	exactAfterReleaseErr := registry.ReserveExact("TestB", first)
+
+	// then
+	require.Equal(t, 40001, first)
+	require.Equal(t, 40002, second, "a port reserved by another test must not be handed out again")
+	require.ErrorContains(t, exactErr, "already reserved by TestA")
+	require.NoError(t, exactAfterReleaseErr)
+}
@@ -0,0 +1,295 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/portforward"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const kScalingProbeInterval = 500 * time.Millisecond
+
+// scalableComponent is a component operators scale with "helm upgrade" by changing its replicas value.
+type scalableComponent struct {
+	name        string
+	replicasKey string
+	// statefulSet is set if the component runs as StatefulSet, otherwise as Deployment.
+	statefulSet bool
+	// workload is the name of the Deployment or StatefulSet, service the name of the Service in front of it.
+	workload string
+	service  string
+	port     int
+	// probe sends one request to the component through the endpoint of a tunnel to its Service.
+	probe func(endpoint string) error
+}
+
+func (s *integrationSuite) zeebeGatewayComponent() scalableComponent {
+	return scalableComponent{
+		name:        "zeebe-gateway",
+		replicasKey: "zeebe-gateway.replicas",
+		workload:    s.zeebeGatewayServiceName(),
+		service:     s.zeebeGatewayServiceName(),
+		port:        kZeebeGatewayPort,
+		probe:       s.probeZeebeGateway,
+	}
+}
+
+func (s *integrationSuite) connectorsComponent() scalableComponent {
+	return scalableComponent{
+		name:        "connectors",
+		replicasKey: "connectors.replicas",
+		workload:    s.release + "-connectors",
+		service:     s.release + "-connectors",
+		port:        8080,
+		probe:       probeHttpResponds,
+	}
+}
+
+func (s *integrationSuite) elasticsearchComponent() scalableComponent {
+	return scalableComponent{
+		name:        "elasticsearch",
+		replicasKey: "elasticsearch.replicas",
+		statefulSet: true,
+		workload:    "elasticsearch-master",
+		service:     "elasticsearch-master",
+		port:        9200,
+		probe:       probeElasticsearchHealth,
+	}
+}
+
+// probeZeebeGateway requests the topology, a new client is used since a reopened tunnel might use another port.
+func (s *integrationSuite) probeZeebeGateway(endpoint string) error {
+	config, err := s.zeebeClientConfig(endpoint, s.zeebeGatewaySecurity())
+	if err != nil {
+		return err
+	}
+	client, err := zbc.NewClient(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = s.sendTopology(client)
+	return err
+}
+
+// probeHttpResponds succeeds on any HTTP response, the probe only checks the component accepts requests.
+func probeHttpResponds(endpoint string) error {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	response, err := httpClient.Get("http://" + endpoint + "/")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	return nil
+}
+
+// probeElasticsearchHealth succeeds as long as the cluster health isn't red.
+func probeElasticsearchHealth(endpoint string) error {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	response, err := httpClient.Get("http://" + endpoint + "/_cluster/health")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.Status == "red" || health.Status == "" {
+		return fmt.Errorf("elasticsearch cluster health is '%s'", health.Status)
+	}
+	return nil
+}
+
+// probeResult counts the requests of a probe and how many of them failed.
+type probeResult struct {
+	requests int
+	failures int
+	// lastError is the last failure, to have a hint in the test output.
+	lastError error
+}
+
+func (r probeResult) failureRatio() float64 {
+	if r.requests == 0 {
+		return 0
+	}
+	return float64(r.failures) / float64(r.requests)
+}
+
+// startProbe probes the component in the background until the returned function is called, which returns the
+// result. Failing to reach the tunnel counts as failed request, the tunnel is reopened by the next request.
+func (s *integrationSuite) startProbe(component scalableComponent) func() probeResult {
+	target := portforward.Target{Service: component.service, Port: component.port}
+	ctx, cancelFn := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var result probeResult
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(kScalingProbeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			endpoint, err := s.portForwards().Endpoint(target)
+			if err == nil {
+				err = component.probe(endpoint)
+			}
+			mu.Lock()
+			result.requests++
+			if err != nil {
+				result.failures++
+				result.lastError = err
+			}
+			mu.Unlock()
+		}
+	}()
+	return func() probeResult {
+		cancelFn()
+		wg.Wait()
+		return result
+	}
+}
+
+// upgradeReplicas upgrades the release with the install values and the replicas of the component.
+func (s *integrationSuite) upgradeReplicas(installValues map[string]string, component scalableComponent, replicas int) {
+	s.T().Logf("Scaling %s to %d replicas", component.name, replicas)
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      mergeValues(installValues, map[string]string{component.replicasKey: strconv.Itoa(replicas)}),
+		SetStrValues:   s.upgradeSecretValues(),
+	}
+	helm.Upgrade(s.T(), options, s.chartPath, s.release)
+}
+
+// endpointsConverged checks that the Service has exactly the replicas as ready endpoints, and no pending ones.
+func endpointsConverged(endpoints *corev1.Endpoints, replicas int) error {
+	ready, notReady := 0, 0
+	for _, subset := range endpoints.Subsets {
+		ready += len(subset.Addresses)
+		notReady += len(subset.NotReadyAddresses)
+	}
+	if ready != replicas || notReady != 0 {
+		return fmt.Errorf("service %s has %d ready and %d not ready endpoints, expected %d ready",
+			endpoints.Name, ready, notReady, replicas)
+	}
+	return nil
+}
+
+// awaitReplicasConverged waits until the workload of the component runs the replicas, all of them ready and
+// updated, and the endpoints of its Service match.
+func (s *integrationSuite) awaitReplicasConverged(component scalableComponent, replicas int) {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err)
+
+	message := retry.DoWithRetry(s.T(), fmt.Sprintf("Wait for %s to converge to %d replicas", component.name, replicas), 60, 10*time.Second, func() (string, error) {
+		ctx := context.Background()
+		var current, ready, updated int32
+		if component.statefulSet {
+			statefulSet, err := clientset.AppsV1().StatefulSets(s.namespace).Get(ctx, component.workload, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			current, ready, updated = statefulSet.Status.Replicas, statefulSet.Status.ReadyReplicas, statefulSet.Status.UpdatedReplicas
+		} else {
+			deployment, err := clientset.AppsV1().Deployments(s.namespace).Get(ctx, component.workload, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			current, ready, updated = deployment.Status.Replicas, deployment.Status.ReadyReplicas, deployment.Status.UpdatedReplicas
+		}
+		if int(current) != replicas || int(ready) != replicas || int(updated) != replicas {
+			return "", fmt.Errorf("%s has %d replicas, %d ready and %d updated, expected %d",
+				component.workload, current, ready, updated, replicas)
+		}
+
+		endpoints, err := clientset.CoreV1().Endpoints(s.namespace).Get(ctx, component.service, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if err := endpointsConverged(endpoints, replicas); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s converged to %d replicas.", component.name, replicas), nil
+	})
+	s.T().Logf(message)
+}
+
+// assertScalingDuringWorkload scales the component up and back down with chart upgrades while a workload runs and
+// the component is probed. The probe failures have to stay within the threshold and the workload has to finish
+// without losing instances.
+func (s *integrationSuite) assertScalingDuringWorkload(component scalableComponent, installValues map[string]string, from int, to int) {
+	s.awaitReplicasConverged(component, from)
+	running := s.startWorkload(defaultWorkloadModels)
+	stopProbe := s.startProbe(component)
+
+	for _, replicas := range []int{to, from} {
+		s.upgradeReplicas(installValues, component, replicas)
+		s.awaitReplicasConverged(component, replicas)
+	}
+
+	result := stopProbe()
+	s.T().Logf("Probed %s with %d requests while scaling, %d failed (last error: %v)",
+		component.name, result.requests, result.failures, result.lastError)
+	s.Require().Positive(result.requests, "%s wasn't probed while scaling", component.name)
+	s.Require().LessOrEqual(result.failureRatio(), s.options.scalingFailureThreshold,
+		"too many requests to %s failed while scaling, last error: %v", component.name, result.lastError)
+
+	expectations := s.awaitWorkload(running)
+	s.assertWorkloadInOperate(expectations)
+}
+
+// assertZeebeClusterSizeChangeRejected checks that an upgrade which changes the cluster size fails, as documented
+// for "zeebe.clusterSize", and leaves the brokers untouched.
+func (s *integrationSuite) assertZeebeClusterSizeChangeRejected(installValues map[string]string) {
+	expected, err := s.expectedTopology()
+	s.Require().NoError(err)
+
+	options := &helm.Options{
+		KubectlOptions: s.kubeOptions,
+		SetValues:      mergeValues(installValues, map[string]string{"zeebe.clusterSize": strconv.Itoa(int(expected.clusterSize) + 1)}),
+		SetStrValues:   s.upgradeSecretValues(),
+	}
+	err = helm.UpgradeE(s.T(), options, s.chartPath, s.release)
+
+	s.Require().Error(err, "expected the upgrade changing zeebe.clusterSize to fail")
+	s.Require().Contains(err.Error(), `"zeebe.clusterSize" can't be changed on upgrade`)
+	actual, err := s.expectedTopology()
+	s.Require().NoError(err)
+	s.Require().Equal(expected, actual, "the rejected upgrade changed the brokers")
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func serviceEndpoints(ready int, notReady int) *corev1.Endpoints {
+	subset := corev1.EndpointSubset{}
+	for i := 0; i < ready; i++ {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: "10.0.0.1"})
+	}
+	for i := 0; i < notReady; i++ {
+		subset.NotReadyAddresses = append(subset.NotReadyAddresses, corev1.EndpointAddress{IP: "10.0.0.2"})
+	}
+	return &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "release-zeebe-gateway"}, Subsets: []corev1.EndpointSubset{subset}}
+}
+
+// newElasticsearchHealth starts a server which reports the cluster health status and returns its endpoint.
+func newElasticsearchHealth(t *testing.T, status string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"cluster_name":"elasticsearch","status":"` + status + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestEndpointsConvergedShouldAcceptReadyReplicas(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, endpointsConverged(serviceEndpoints(3, 0), 3))
+}
+
+func TestEndpointsConvergedShouldRejectPendingOrRemainingEndpoints(t *testing.T) {
+	t.Parallel()
+
+	require.ErrorContains(t, endpointsConverged(serviceEndpoints(2, 1), 3), "2 ready and 1 not ready endpoints, expected 3 ready")
+	require.ErrorContains(t, endpointsConverged(serviceEndpoints(3, 0), 2), "3 ready and 0 not ready endpoints, expected 2 ready")
+}
+
+func TestProbeResultFailureRatio(t *testing.T) {
+	t.Parallel()
+
+	require.Zero(t, probeResult{}.failureRatio())
+	require.Equal(t, 0.25, probeResult{requests: 8, failures: 2}.failureRatio())
+}
+
+func TestProbeElasticsearchHealthShouldFailOnlyForRedCluster(t *testing.T) {
+	t.Parallel()
+
+	for status, healthy := range map[string]bool{"green": true, "yellow": true, "red": false} {
+		endpoint := newElasticsearchHealth(t, status)
+
+		err := probeElasticsearchHealth(endpoint)
+
+		if healthy {
+			require.NoError(t, err, status)
+		} else {
+			require.ErrorContains(t, err, "'red'")
+		}
+	}
+}
+
+func TestProbeHttpRespondsShouldAcceptErrorResponses(t *testing.T) {
+	t.Parallel()
+
+	// given
+	server := httptest.NewServer(http.NotFoundHandler())
+	endpoint := strings.TrimPrefix(server.URL, "http://")
+
+	// when
+	err := probeHttpResponds(endpoint)
+	server.Close()
+
+	// then
+	require.NoError(t, err)
+	require.Error(t, probeHttpResponds(endpoint), "the closed server can't respond")
+}
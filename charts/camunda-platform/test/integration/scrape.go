@@ -0,0 +1,87 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/metrics"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+const kMetricsPath = "/actuator/prometheus"
+
+// metricsEndpoint is the Prometheus endpoint of a component, as the ServiceMonitor of the chart scrapes it.
+type metricsEndpoint struct {
+	component     string
+	service       string
+	containerPort int
+	// prefixes are the metric families which at least one family has to start with.
+	prefixes []string
+}
+
+func (s *integrationSuite) metricsEndpoints() []metricsEndpoint {
+	return []metricsEndpoint{
+		{component: "zeebe", service: s.release + "-zeebe", containerPort: 9600, prefixes: []string{"zeebe_", "jvm_"}},
+		{component: "zeebe-gateway", service: s.zeebeGatewayServiceName(), containerPort: 9600, prefixes: []string{"zeebe_", "jvm_"}},
+		{component: "operate", service: s.release + "-operate", containerPort: 8080, prefixes: []string{"jvm_"}},
+		{component: "tasklist", service: s.release + "-tasklist", containerPort: 8080, prefixes: []string{"jvm_"}},
+		{component: "optimize", service: s.release + "-optimize", containerPort: 8092, prefixes: []string{"jvm_"}},
+		{component: "identity", service: s.release + "-identity", containerPort: 8082, prefixes: []string{"jvm_"}},
+		{component: "connectors", service: s.release + "-connectors", containerPort: 8080, prefixes: []string{"jvm_"}},
+	}
+}
+
+// scrapeMetrics scrapes the Prometheus endpoint of the component through a tunnel and parses the families.
+func (s *integrationSuite) scrapeMetrics(endpoint metricsEndpoint) (metrics.Families, error) {
+	url := "http://" + s.portForward(endpoint.service, endpoint.containerPort) + kMetricsPath
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	response, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scraping %s returned status %d", url, response.StatusCode)
+	}
+	return metrics.Parse(response.Body)
+}
+
+// assertMetricsEndpoints scrapes every deployed component like Prometheus and checks the key metric families exist.
+// Components which aren't deployed by the release are skipped.
+func (s *integrationSuite) assertMetricsEndpoints() {
+	for _, endpoint := range s.metricsEndpoints() {
+		if _, err := k8s.GetServiceE(s.T(), s.kubeOptions, endpoint.service); err != nil {
+			s.T().Logf("Skip scraping %s, its service %s doesn't exist: %v", endpoint.component, endpoint.service, err)
+			continue
+		}
+
+		message := retry.DoWithRetry(s.T(), "Try to scrape the metrics of "+endpoint.component, 10, 10*time.Second, func() (string, error) {
+			families, err := s.scrapeMetrics(endpoint)
+			if err != nil {
+				return "", err
+			}
+			if err := families.RequirePrefixes(endpoint.prefixes...); err != nil {
+				return "", fmt.Errorf("%s: %w", endpoint.component, err)
+			}
+			return fmt.Sprintf("Scraped %d metric families of %s.", len(families), endpoint.component), nil
+		})
+		s.T().Logf(message)
+	}
+}
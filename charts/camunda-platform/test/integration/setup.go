@@ -15,6 +15,8 @@
 package integration
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -46,6 +48,24 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return boolValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	envValue := getEnv(key, strconv.Itoa(defaultValue))
+	intValue, err := strconv.Atoi(envValue)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return intValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	envValue := getEnv(key, strconv.FormatFloat(defaultValue, 'f', -1, 64))
+	floatValue, err := strconv.ParseFloat(envValue, 64)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return floatValue
+}
+
 func namespaceFormatWithEnvVars(nsBase string, nsSections []namespaceSection) string {
 	for _, nss := range nsSections {
 		if nssText, exist := os.LookupEnv(nss.textVar); exist {
@@ -63,7 +83,26 @@ func truncateString(str string, num int) string {
 	return shortenStr
 }
 
-func createNamespaceObjectMeta() metav1.ObjectMeta {
+// testId returns a short identifier of the test, it's the same for reruns of the test in the same CI job.
+func testId(testName string) string {
+	hash := sha256.Sum256([]byte(testName + "/" + getEnv("GITHUB_WORKFLOW_JOB_ID", "")))
+	return hex.EncodeToString(hash[:])[:6]
+}
+
+// testReleaseName returns the release name of a test, derived from its namespace, so tests running at the same time
+// never share a release, even if the chart creates cluster-scoped objects named after the release.
+func testReleaseName(prefix string, namespace string) string {
+	hash := sha256.Sum256([]byte(namespace))
+	return prefix + "-" + hex.EncodeToString(hash[:])[:6]
+}
+
+// labelValue turns a test name like "TestIntegration/TestServicesEnd2End" into a valid label value.
+func labelValue(value string) string {
+	value = strings.NewReplacer("/", "_", " ", "_").Replace(value)
+	return strings.Trim(truncateString(value, 63), "_-.")
+}
+
+func createNamespaceObjectMeta(testName string) metav1.ObjectMeta {
 	// if triggered by a github action the environment variable is set
 	// we use it to better identify the test
 
@@ -74,23 +113,52 @@ func createNamespaceObjectMeta() metav1.ObjectMeta {
 	}
 	namespace := namespaceFormatWithEnvVars("camunda-platform", namespaceSections)
 	// In case the tests are running locally not in the CI.
-	namespace += "-sfx-" + getEnv("GITHUB_WORKFLOW_JOB_ID", strings.ToLower(random.UniqueId()))
+	// The test id makes the namespace unique per test, since tests of the same job run at the same time.
+	suffix := "-sfx-" + getEnv("GITHUB_WORKFLOW_JOB_ID", strings.ToLower(random.UniqueId())) + "-" + testId(testName)
 
 	return metav1.ObjectMeta{
-		// max namespace length is 63 characters
+		// max namespace length is 63 characters, the suffix is kept since it makes the namespace unique
 		// https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-label-names
-		Name: truncateString(namespace, 63),
+		Name: truncateString(namespace, 63-len(suffix)) + suffix,
 		Labels: map[string]string{
 			"github-pr-id":  getEnv("GITHUB_PR_NUMBER", ""),
 			"git-sha-short": getEnv("GITHUB_PR_HEAD_SHA_SHORT", ""),
 			"github-run-id": getEnv("GITHUB_WORKFLOW_RUN_ID", ""),
 			"github-job-id": getEnv("GITHUB_WORKFLOW_JOB_ID", ""),
+			"test-name":     labelValue(testName),
 		},
 	}
 }
 
 func getIntegrationSuiteOptions() integrationSuiteOptions {
 	return integrationSuiteOptions{
-		deleteNamespace: getEnvBool("CAMUNDA_DISTRO_TEST_DELETE_NAMESPACE", true),
+		deleteNamespace:   getEnvBool("CAMUNDA_DISTRO_TEST_DELETE_NAMESPACE", true),
+		workloadInstances: getEnvInt("CAMUNDA_DISTRO_TEST_WORKLOAD_INSTANCES", 10),
+		workloadRate:      getEnvFloat("CAMUNDA_DISTRO_TEST_WORKLOAD_RATE", 5),
+		loginUsername:     getEnv("CAMUNDA_DISTRO_TEST_LOGIN_USERNAME", ""),
+		loginPassword:     getEnv("CAMUNDA_DISTRO_TEST_LOGIN_PASSWORD", ""),
+		keycloakPort:      getEnvInt("CAMUNDA_DISTRO_TEST_KEYCLOAK_PORT", 0),
+		connectivity:      getEnv("CAMUNDA_DISTRO_TEST_CONNECTIVITY", kConnectivityPortForward),
+		ingressClass:      getEnv("CAMUNDA_DISTRO_TEST_INGRESS_CLASS", ""),
+		ingressDomain:     getEnv("CAMUNDA_DISTRO_TEST_INGRESS_DOMAIN", "camunda-platform.test"),
+		ingressControllerSelector: getEnv("CAMUNDA_DISTRO_TEST_INGRESS_CONTROLLER_SELECTOR",
+			"app.kubernetes.io/name=ingress-nginx,app.kubernetes.io/component=controller"),
+		tls:                     getEnvBool("CAMUNDA_DISTRO_TEST_TLS", false),
+		zeebeTLS:                getEnvBool("CAMUNDA_DISTRO_TEST_ZEEBE_TLS", false),
+		zeebeAuth:               getEnvBool("CAMUNDA_DISTRO_TEST_ZEEBE_AUTH", false),
+		zeebeClientId:           getEnv("CAMUNDA_DISTRO_TEST_ZEEBE_CLIENT_ID", "operate"),
+		zeebeAudience:           getEnv("CAMUNDA_DISTRO_TEST_ZEEBE_AUDIENCE", "zeebe-api"),
+		scalingFailureThreshold: getEnvFloat("CAMUNDA_DISTRO_TEST_SCALING_FAILURE_THRESHOLD", 0.05),
+	}
+}
+
+// mergeValues merges the helm values, later values overwrite earlier ones.
+func mergeValues(values ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, v := range values {
+		for key, value := range v {
+			merged[key] = value
+		}
 	}
+	return merged
 }
@@ -0,0 +1,201 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+const (
+	tasklistTasksQuery = `query tasks($state: TaskState) {
+  tasks(query: {state: $state}) { id name taskState assignee processInstanceId }
+}`
+	tasklistClaimTaskMutation = `mutation claimTask($taskId: String!) {
+  claimTask(taskId: $taskId) { id name taskState assignee processInstanceId }
+}`
+	tasklistCompleteTaskMutation = `mutation completeTask($taskId: String!, $variables: [VariableInput!]!) {
+  completeTask(taskId: $taskId, variables: $variables) { id name taskState assignee processInstanceId }
+}`
+)
+
+// itTestTaskVariables are the variables the "It Test" user task is completed with.
+var itTestTaskVariables = map[string]interface{}{
+	"itTestApproved": true,
+	"itTestComment":  "completed by the integration test",
+}
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+type tasklistTask struct {
+	Id                string `json:"id"`
+	Name              string `json:"name"`
+	TaskState         string `json:"taskState"`
+	Assignee          string `json:"assignee"`
+	ProcessInstanceId string `json:"processInstanceId"`
+}
+
+// tasklistVariableInput is the Tasklist VariableInput type, the value has to be a JSON encoded string.
+type tasklistVariableInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type tasklistTasksResponse struct {
+	Data struct {
+		Tasks []tasklistTask `json:"tasks"`
+	} `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+type tasklistClaimTaskResponse struct {
+	Data struct {
+		ClaimTask tasklistTask `json:"claimTask"`
+	} `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+type tasklistCompleteTaskResponse struct {
+	Data struct {
+		CompleteTask tasklistTask `json:"completeTask"`
+	} `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+func graphqlErrorsToError(errs []graphqlError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return errors.New("graphql request failed: " + strings.Join(messages, "; "))
+}
+
+func (s *integrationSuite) queryTasklistGraphql(httpClient http.Client, endpoint string, request graphqlRequest, response interface{}) error {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	responseBuf, err := s.queryApi(httpClient, s.webScheme()+"://"+endpoint+"/graphql", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(responseBuf.Bytes(), response)
+}
+
+func (s *integrationSuite) queryTasksFromTasklistWithClient(httpClient http.Client, endpoint string, state string) ([]tasklistTask, error) {
+	request := graphqlRequest{Query: tasklistTasksQuery, Variables: map[string]interface{}{"state": state}}
+	var response tasklistTasksResponse
+	if err := s.queryTasklistGraphql(httpClient, endpoint, request, &response); err != nil {
+		return nil, err
+	}
+	return response.Data.Tasks, graphqlErrorsToError(response.Errors)
+}
+
+func (s *integrationSuite) claimTaskFromTasklist(httpClient http.Client, endpoint string, taskId string) (tasklistTask, error) {
+	request := graphqlRequest{Query: tasklistClaimTaskMutation, Variables: map[string]interface{}{"taskId": taskId}}
+	var response tasklistClaimTaskResponse
+	if err := s.queryTasklistGraphql(httpClient, endpoint, request, &response); err != nil {
+		return tasklistTask{}, err
+	}
+	return response.Data.ClaimTask, graphqlErrorsToError(response.Errors)
+}
+
+func (s *integrationSuite) completeTaskFromTasklist(httpClient http.Client, endpoint string, taskId string, variables map[string]interface{}) (tasklistTask, error) {
+	variableInputs := make([]tasklistVariableInput, 0, len(variables))
+	for name, value := range variables {
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			return tasklistTask{}, err
+		}
+		variableInputs = append(variableInputs, tasklistVariableInput{Name: name, Value: string(encodedValue)})
+	}
+
+	request := graphqlRequest{
+		Query:     tasklistCompleteTaskMutation,
+		Variables: map[string]interface{}{"taskId": taskId, "variables": variableInputs},
+	}
+	var response tasklistCompleteTaskResponse
+	if err := s.queryTasklistGraphql(httpClient, endpoint, request, &response); err != nil {
+		return tasklistTask{}, err
+	}
+	return response.Data.CompleteTask, graphqlErrorsToError(response.Errors)
+}
+
+// completeUserTaskFromTasklist claims and completes the user task of the given process instance
+// through the Tasklist GraphQL API, which should make Zeebe continue the process instance.
+func (s *integrationSuite) completeUserTaskFromTasklist(processInstanceKey int64, variables map[string]interface{}) {
+	processInstanceId := strconv.FormatInt(processInstanceKey, 10)
+	message := retry.DoWithRetry(s.T(),
+		"Try to claim and complete user task from tasklist",
+		10,
+		10*time.Second,
+		func() (string, error) {
+			endpoint, httpClient, err := s.doLogin("tasklist", 8080)
+			if err != nil {
+				return "", err
+			}
+
+			tasks, err := s.queryTasksFromTasklistWithClient(httpClient, endpoint, "CREATED")
+			if err != nil {
+				return "", err
+			}
+
+			var task *tasklistTask
+			for i := range tasks {
+				if tasks[i].ProcessInstanceId == processInstanceId {
+					task = &tasks[i]
+				}
+			}
+			if task == nil {
+				return "", fmt.Errorf("no open user task found for process instance %s", processInstanceId)
+			}
+
+			if task.Assignee == "" {
+				claimedTask, err := s.claimTaskFromTasklist(httpClient, endpoint, task.Id)
+				if err != nil {
+					return "", err
+				}
+				s.Require().NotEmpty(claimedTask.Assignee, "claimed task should have an assignee")
+			}
+
+			completedTask, err := s.completeTaskFromTasklist(httpClient, endpoint, task.Id, variables)
+			if err != nil {
+				return "", err
+			}
+			s.Require().Equal("COMPLETED", completedTask.TaskState)
+
+			return fmt.Sprintf("User Task '%s' of process instance %s completed in tasklist!", task.Name, processInstanceId), nil
+		})
+	s.T().Logf(message)
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testca generates a throwaway certificate authority and the server certificates it signs, so the
+// integration tests can serve the release over TLS without any external PKI.
+package testca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// validity of the certificates, long enough for reruns against an existing release.
+const validity = 7 * 24 * time.Hour
+
+// CA is a self-signed certificate authority.
+type CA struct {
+	Certificate *x509.Certificate
+	CertPEM     []byte
+	KeyPEM      []byte
+	key         *ecdsa.PrivateKey
+}
+
+// KeyPair is a certificate with its private key, both PEM encoded like in a kubernetes.io/tls secret.
+type KeyPair struct {
+	Certificate *x509.Certificate
+	CertPEM     []byte
+	KeyPEM      []byte
+}
+
+// New generates a CA with the common name.
+func New(commonName string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := newTemplate(commonName)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	certificate, certPEM, err := sign(template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Certificate: certificate, CertPEM: certPEM, KeyPEM: keyPEM, key: key}, nil
+}
+
+// Load reads a CA generated by New from its PEM encoded certificate and key.
+func Load(certPEM []byte, keyPEM []byte) (*CA, error) {
+	keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keyPair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the CA key is not an ECDSA key")
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if !certificate.IsCA {
+		return nil, fmt.Errorf("certificate %s is not a CA", certificate.Subject.CommonName)
+	}
+	return &CA{Certificate: certificate, CertPEM: certPEM, KeyPEM: keyPEM, key: key}, nil
+}
+
+// IssueServerCert issues a server certificate for the hosts, the first host is the common name.
+func (ca *CA) IssueServerCert(hosts ...string) (*KeyPair, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("a server certificate needs at least one host")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := newTemplate(hosts[0])
+	if err != nil {
+		return nil, err
+	}
+	template.DNSNames = hosts
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	certificate, certPEM, err := sign(template, ca.Certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{Certificate: certificate, CertPEM: certPEM, KeyPEM: keyPEM}, nil
+}
+
+// CertPool returns a pool which only trusts the CA.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// VerifyChain checks that the server of the connection presented a certificate for the host which chains up to the CA.
+func (ca *CA) VerifyChain(state tls.ConnectionState, host string) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%s presented no certificate", host)
+	}
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         ca.CertPool(),
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return fmt.Errorf("%s presented the certificate '%s' issued by '%s': %w",
+			host, leaf.Subject.CommonName, leaf.Issuer.CommonName, err)
+	}
+	for _, chain := range chains {
+		if chain[len(chain)-1].Equal(ca.Certificate) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the certificate of %s doesn't chain up to the CA '%s'", host, ca.Certificate.Subject.CommonName)
+}
+
+func newTemplate(commonName string) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Camunda Platform Helm integration tests"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func sign(template *x509.Certificate, parent *x509.Certificate, publicKey *ecdsa.PublicKey, signer *ecdsa.PrivateKey) (*x509.Certificate, []byte, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// encodeKey encodes the key as PKCS #8, the Zeebe gateway (Netty) doesn't read SEC 1 encoded keys.
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testca
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSServer starts a server with a certificate for the host, clients have to use the host as server name.
+func newTLSServer(t *testing.T, ca *CA, host string) *httptest.Server {
+	keyPair, err := ca.IssueServerCert(host)
+	require.NoError(t, err)
+	certificate, err := tls.X509KeyPair(keyPair.CertPEM, keyPair.KeyPEM)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func connect(t *testing.T, server *httptest.Server, host string) tls.ConnectionState {
+	// the chain is verified by the CA, the handshake only has to succeed
+	conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.ConnectionState()
+}
+
+func TestVerifyChainShouldAcceptCertificateOfCA(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := New("it-ca")
+	require.NoError(t, err)
+	server := newTLSServer(t, ca, "operate.camunda.test")
+
+	// when
+	err = ca.VerifyChain(connect(t, server, "operate.camunda.test"), "operate.camunda.test")
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyChainShouldRejectCertificateOfOtherCA(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := New("it-ca")
+	require.NoError(t, err)
+	other, err := New("other-ca")
+	require.NoError(t, err)
+	server := newTLSServer(t, other, "operate.camunda.test")
+
+	// when
+	err = ca.VerifyChain(connect(t, server, "operate.camunda.test"), "operate.camunda.test")
+
+	// then
+	require.ErrorContains(t, err, "issued by 'other-ca'")
+}
+
+func TestVerifyChainShouldRejectCertificateOfOtherHost(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := New("it-ca")
+	require.NoError(t, err)
+	server := newTLSServer(t, ca, "tasklist.camunda.test")
+
+	// when
+	err = ca.VerifyChain(connect(t, server, "operate.camunda.test"), "operate.camunda.test")
+
+	// then
+	require.ErrorContains(t, err, "not operate.camunda.test")
+}
+
+func TestLoadShouldRestoreCA(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := New("it-ca")
+	require.NoError(t, err)
+
+	// when
+	loaded, err := Load(ca.CertPEM, ca.KeyPEM)
+	require.NoError(t, err)
+	keyPair, err := loaded.IssueServerCert("identity.camunda.test")
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, keyPair.Certificate.CheckSignatureFrom(ca.Certificate))
+}
@@ -0,0 +1,284 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/testca"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tlsEndpoint is a UI or API path of a component, which has to be served with the certificate of the test CA.
+type tlsEndpoint struct {
+	component string
+	path      string
+}
+
+var tlsEndpoints = []tlsEndpoint{
+	{component: "keycloak", path: kRealmPath + "/.well-known/openid-configuration"},
+	{component: "identity", path: "/"},
+	{component: "identity", path: "/api/clients"},
+	{component: "operate", path: "/"},
+	{component: "operate", path: "/v1/process-definitions/search"},
+	{component: "tasklist", path: "/"},
+	{component: "tasklist", path: "/graphql"},
+	{component: "optimize", path: "/"},
+	{component: "optimize", path: "/api/public/dashboard"},
+}
+
+// loginStartPaths are the paths which start the Keycloak login of a component.
+var loginStartPaths = map[string]string{
+	"identity": "/auth/login",
+	"operate":  "/",
+	"tasklist": "/",
+	"optimize": "/",
+}
+
+// usesTLS returns true if the ingresses serve the release over HTTPS with certificates of a test CA.
+func (s *integrationSuite) usesTLS() bool {
+	return s.usesIngress() && s.options.tls
+}
+
+func (s *integrationSuite) webScheme() string {
+	if s.usesTLS() {
+		return "https"
+	}
+	return "http"
+}
+
+// tlsSecretName returns the secret with the server certificate for all hosts of the release, empty without TLS.
+func (s *integrationSuite) tlsSecretName() string {
+	if !s.usesTLS() {
+		return ""
+	}
+	return s.release + "-tls"
+}
+
+// tlsValues returns the values which enable TLS on all ingresses, the secret is created before the release.
+func (s *integrationSuite) tlsValues() map[string]string {
+	s.ensureTLSSecret()
+	return map[string]string{
+		"global.ingress.tls.enabled":                    "true",
+		"global.ingress.tls.secretName":                 s.tlsSecretName(),
+		"identity.ingress.tls.enabled":                  "true",
+		"identity.ingress.tls.secretName":               s.tlsSecretName(),
+		"web-modeler.ingress.webapp.tls.enabled":        "true",
+		"web-modeler.ingress.webapp.tls.secretName":     s.tlsSecretName(),
+		"web-modeler.ingress.websockets.tls.enabled":    "true",
+		"web-modeler.ingress.websockets.tls.secretName": s.tlsSecretName(),
+	}
+}
+
+// testCA returns the CA of the release. It's stored in the namespace, so reruns against an existing release trust
+// the certificates it was installed with.
+func (s *integrationSuite) testCA() *testca.CA {
+	if s.ca != nil {
+		return s.ca
+	}
+
+	secretName := s.release + "-tls-ca"
+	if secret, err := k8s.GetSecretE(s.T(), s.kubeOptions, secretName); err == nil {
+		ca, err := testca.Load(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		s.Require().NoError(err, "cannot load the test CA from secret %s", secretName)
+		s.ca = ca
+		return s.ca
+	}
+
+	ca, err := testca.New("Camunda Platform IT CA " + s.release)
+	s.Require().NoError(err)
+	s.createTLSSecret(secretName, ca.CertPEM, ca.KeyPEM)
+	s.ca = ca
+	return s.ca
+}
+
+// ensureTLSSecret creates the secret with the server certificate for all hosts of the release, if it doesn't exist.
+func (s *integrationSuite) ensureTLSSecret() {
+	if _, err := k8s.GetSecretE(s.T(), s.kubeOptions, s.tlsSecretName()); err == nil {
+		return
+	}
+	keyPair, err := s.testCA().IssueServerCert(s.ingressHosts()...)
+	s.Require().NoError(err)
+	s.createTLSSecret(s.tlsSecretName(), keyPair.CertPEM, keyPair.KeyPEM)
+}
+
+func (s *integrationSuite) createTLSSecret(name string, certPEM []byte, keyPEM []byte) {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+	_, err = clientset.CoreV1().Secrets(s.namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		s.Require().NoError(err, "cannot create TLS secret %s", name)
+	}
+}
+
+// newNonRedirectingClient returns a client which returns redirects instead of following them.
+func (s *integrationSuite) newNonRedirectingClient() *http.Client {
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: s.httpTransport(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// assertTLSCertificateChains checks that every UI and API endpoint is served with a certificate for its host, which
+// chains up to the test CA.
+func (s *integrationSuite) assertTLSCertificateChains() {
+	endpoints := tlsEndpoints
+	if s.hasWebModeler() {
+		endpoints = append(endpoints, tlsEndpoint{component: "web-modeler", path: "/"})
+	}
+
+	httpClient := s.newNonRedirectingClient()
+	for _, endpoint := range endpoints {
+		endpointUrl := s.webScheme() + "://" + s.webEndpoint(endpoint.component, 8080) + endpoint.path
+		message := retry.DoWithRetry(s.T(),
+			"Verify the certificate chain of "+endpointUrl,
+			10,
+			10*time.Second,
+			func() (string, error) {
+				if err := verifyCertificateChain(httpClient, endpointUrl, s.testCA()); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s is served with a certificate of the test CA", endpointUrl), nil
+			})
+		s.T().Logf(message)
+	}
+}
+
+func verifyCertificateChain(httpClient *http.Client, endpointUrl string, ca *testca.CA) error {
+	response, err := httpClient.Get(endpointUrl)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.TLS == nil {
+		return fmt.Errorf("%s wasn't served over TLS", endpointUrl)
+	}
+	return ca.VerifyChain(*response.TLS, response.Request.URL.Hostname())
+}
+
+// assertKeycloakRedirectsUseHttps checks that the login of every component redirects over HTTPS only, including the
+// redirect URL back from Keycloak, and that Keycloak announces HTTPS endpoints.
+func (s *integrationSuite) assertKeycloakRedirectsUseHttps() {
+	httpClient := s.newNonRedirectingClient()
+	issuerUrl := s.webScheme() + "://" + s.webEndpoint("keycloak", 8080) + kRealmPath
+	message := retry.DoWithRetry(s.T(),
+		"Verify that the Keycloak logins use HTTPS",
+		10,
+		10*time.Second,
+		func() (string, error) {
+			if err := verifyDiscoveryUsesHttps(httpClient, issuerUrl); err != nil {
+				return "", err
+			}
+			for component, path := range loginStartPaths {
+				startUrl := s.webScheme() + "://" + s.webEndpoint(component, 8080) + path
+				if err := verifyHttpsRedirects(httpClient, startUrl); err != nil {
+					return "", err
+				}
+			}
+			return "All Keycloak logins use HTTPS", nil
+		})
+	s.T().Logf(message)
+}
+
+// verifyHttpsRedirects follows the redirects of the start URL to the Keycloak authorization endpoint, every redirect
+// and the redirect_uri back to the component have to use HTTPS.
+func verifyHttpsRedirects(httpClient *http.Client, startUrl string) error {
+	next := startUrl
+	for i := 0; i < 10; i++ {
+		response, err := httpClient.Get(next)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+
+		location, err := response.Location()
+		if err != nil {
+			return fmt.Errorf("expected %s to redirect to the Keycloak login, but got status code %d", next, response.StatusCode)
+		}
+		if location.Scheme != "https" {
+			return fmt.Errorf("%s redirected to %s, which doesn't use https", next, location)
+		}
+		if strings.HasSuffix(location.Path, "/protocol/openid-connect/auth") {
+			redirectUri, err := url.Parse(location.Query().Get("redirect_uri"))
+			if err != nil || redirectUri.Scheme != "https" {
+				return fmt.Errorf("the login of %s redirects back to '%s', which doesn't use https", startUrl, location.Query().Get("redirect_uri"))
+			}
+			return nil
+		}
+		next = location.String()
+	}
+	return fmt.Errorf("the login of %s didn't reach the Keycloak authorization endpoint", startUrl)
+}
+
+// verifyDiscoveryUsesHttps checks the endpoints Keycloak announces, they are derived from the forwarded scheme.
+func verifyDiscoveryUsesHttps(httpClient *http.Client, issuerUrl string) error {
+	response, err := httpClient.Get(issuerUrl + "/.well-known/openid-configuration")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("expected the discovery of %s to succeed, but got status code %d", issuerUrl, response.StatusCode)
+	}
+
+	var discovery map[string]interface{}
+	if err := json.NewDecoder(response.Body).Decode(&discovery); err != nil {
+		return err
+	}
+	for _, key := range []string{"issuer", "authorization_endpoint", "token_endpoint", "jwks_uri"} {
+		value, _ := discovery[key].(string)
+		if !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("expected Keycloak to announce an https %s, but got '%s'", key, value)
+		}
+	}
+	return nil
+}
+
+// assertWebModelerHttpsOnly checks that the Web Modeler webapp only accepts HTTPS, since its public URL uses https.
+func (s *integrationSuite) assertWebModelerHttpsOnly() {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	s.Require().NoError(err)
+	deployment, err := clientset.AppsV1().Deployments(s.namespace).Get(context.Background(), s.serviceName("web-modeler-webapp"), metav1.GetOptions{})
+	s.Require().NoError(err)
+	s.Require().Contains(deployment.Spec.Template.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: "SERVER_HTTPS_ONLY", Value: "true"})
+	s.T().Logf("Web Modeler webapp is configured for HTTPS only")
+}
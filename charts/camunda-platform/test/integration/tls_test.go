@@ -0,0 +1,110 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/testca"
+
+	"github.com/stretchr/testify/require"
+)
+
+const kTestIngressHost = "release.camunda-platform.test"
+
+// newTLSIngress starts a server with a certificate of the CA for the hosts of the release, like the stand-in
+// controller serves them, and returns a client which reaches all hosts through it.
+func newTLSIngress(t *testing.T, ca *testca.CA, handler http.Handler) *http.Client {
+	keyPair, err := ca.IssueServerCert(kTestIngressHost, "identity."+kTestIngressHost)
+	require.NoError(t, err)
+	certificate, err := tls.X509KeyPair(keyPair.CertPEM, keyPair.KeyPEM)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return &http.Client{
+		Transport: ingressTransport(kTestIngressHost, ca.CertPool(), func() string {
+			return strings.TrimPrefix(server.URL, "https://")
+		}),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// loginRedirects redirects the component to the Keycloak login with the redirect URI.
+func loginRedirects(redirectUri string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/operate/" {
+			http.Redirect(w, r, "https://"+kTestIngressHost+"/operate/login", http.StatusFound)
+			return
+		}
+		query := url.Values{"client_id": {"operate"}, "redirect_uri": {redirectUri}}
+		http.Redirect(w, r, "https://"+kTestIngressHost+kRealmPath+"/protocol/openid-connect/auth?"+query.Encode(), http.StatusFound)
+	})
+}
+
+func TestVerifyCertificateChainShouldAcceptCertificateOfTestCA(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := testca.New("it-ca")
+	require.NoError(t, err)
+	httpClient := newTLSIngress(t, ca, http.NotFoundHandler())
+
+	// when
+	err = verifyCertificateChain(httpClient, "https://identity."+kTestIngressHost+"/api/clients", ca)
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyHttpsRedirectsShouldAcceptHttpsLogin(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := testca.New("it-ca")
+	require.NoError(t, err)
+	httpClient := newTLSIngress(t, ca, loginRedirects("https://"+kTestIngressHost+"/operate/identity-callback"))
+
+	// when
+	err = verifyHttpsRedirects(httpClient, "https://"+kTestIngressHost+"/operate/")
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyHttpsRedirectsShouldRejectHttpRedirectUri(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := testca.New("it-ca")
+	require.NoError(t, err)
+	httpClient := newTLSIngress(t, ca, loginRedirects("http://"+kTestIngressHost+"/operate/identity-callback"))
+
+	// when
+	err = verifyHttpsRedirects(httpClient, "https://"+kTestIngressHost+"/operate/")
+
+	// then
+	require.ErrorContains(t, err, "redirects back to 'http://release.camunda-platform.test/operate/identity-callback'")
+}
@@ -0,0 +1,151 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// expectedTopology is the Zeebe topology the release was installed with.
+type expectedTopology struct {
+	clusterSize       int32
+	partitionsCount   int32
+	replicationFactor int32
+	// version is the tag of the broker image, empty if the image is referenced by digest only.
+	version string
+}
+
+func (e expectedTopology) String() string {
+	return fmt.Sprintf("clusterSize=%d partitionsCount=%d replicationFactor=%d version=%q",
+		e.clusterSize, e.partitionsCount, e.replicationFactor, e.version)
+}
+
+// expectedTopology reads the topology from the Zeebe StatefulSet of the release, so it follows the values the
+// release was installed with.
+func (s *integrationSuite) expectedTopology() (expectedTopology, error) {
+	clientset, err := k8s.GetKubernetesClientFromOptionsE(s.T(), s.kubeOptions)
+	if err != nil {
+		return expectedTopology{}, err
+	}
+	statefulSet, err := clientset.AppsV1().StatefulSets(s.namespace).Get(context.Background(), s.release+"-zeebe", metav1.GetOptions{})
+	if err != nil {
+		return expectedTopology{}, err
+	}
+	return topologyFromStatefulSet(statefulSet)
+}
+
+// topologyFromStatefulSet takes the topology from the env of the broker container, which the chart renders from
+// zeebe.clusterSize, zeebe.partitionCount and zeebe.replicationFactor, and the version from the image tag.
+func topologyFromStatefulSet(statefulSet *appsv1.StatefulSet) (expectedTopology, error) {
+	containers := statefulSet.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return expectedTopology{}, fmt.Errorf("statefulset %s has no containers", statefulSet.Name)
+	}
+	broker := containers[0]
+
+	topology := expectedTopology{version: imageTag(broker.Image)}
+	settings := map[string]*int32{
+		"ZEEBE_BROKER_CLUSTER_CLUSTERSIZE":       &topology.clusterSize,
+		"ZEEBE_BROKER_CLUSTER_PARTITIONSCOUNT":   &topology.partitionsCount,
+		"ZEEBE_BROKER_CLUSTER_REPLICATIONFACTOR": &topology.replicationFactor,
+	}
+	for _, env := range broker.Env {
+		setting, ok := settings[env.Name]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(env.Value, 10, 32)
+		if err != nil {
+			return expectedTopology{}, fmt.Errorf("cannot parse %s of statefulset %s: %w", env.Name, statefulSet.Name, err)
+		}
+		*setting = int32(value)
+		delete(settings, env.Name)
+	}
+	if len(settings) > 0 {
+		missing := make([]string, 0, len(settings))
+		for name := range settings {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return expectedTopology{}, fmt.Errorf("statefulset %s has no env %s", statefulSet.Name, strings.Join(missing, ", "))
+	}
+	return topology, nil
+}
+
+// imageTag returns the tag of the image reference, without a digest.
+func imageTag(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
+
+// verifyTopology checks the topology reported by the gateway against the expected one: all brokers joined with the
+// expected version, and every partition has exactly one healthy leader and followers for the remaining replicas.
+func verifyTopology(topology *pb.TopologyResponse, expected expectedTopology) error {
+	if topology.ClusterSize != expected.clusterSize || topology.PartitionsCount != expected.partitionsCount ||
+		topology.ReplicationFactor != expected.replicationFactor {
+		return fmt.Errorf("expected topology %s, but got clusterSize=%d partitionsCount=%d replicationFactor=%d",
+			expected, topology.ClusterSize, topology.PartitionsCount, topology.ReplicationFactor)
+	}
+	if len(topology.Brokers) != int(expected.clusterSize) {
+		return fmt.Errorf("expected %d brokers in the topology, but got %d", expected.clusterSize, len(topology.Brokers))
+	}
+
+	var errs []string
+	leaders := map[int32][]int32{}
+	followers := map[int32]int{}
+	for _, broker := range topology.Brokers {
+		if expected.version != "" && broker.Version != expected.version {
+			errs = append(errs, fmt.Sprintf("broker %d reports version %q, expected %q", broker.NodeId, broker.Version, expected.version))
+		}
+		for _, partition := range broker.Partitions {
+			switch partition.Role {
+			case pb.Partition_LEADER:
+				leaders[partition.PartitionId] = append(leaders[partition.PartitionId], broker.NodeId)
+				if partition.Health != pb.Partition_HEALTHY {
+					errs = append(errs, fmt.Sprintf("leader %d of partition %d is %s", broker.NodeId, partition.PartitionId, partition.Health))
+				}
+			case pb.Partition_FOLLOWER:
+				followers[partition.PartitionId]++
+			}
+		}
+	}
+
+	for partitionId := int32(1); partitionId <= expected.partitionsCount; partitionId++ {
+		if len(leaders[partitionId]) != 1 {
+			errs = append(errs, fmt.Sprintf("partition %d has leaders %v, expected exactly one", partitionId, leaders[partitionId]))
+		}
+		if followers[partitionId] != int(expected.replicationFactor)-1 {
+			errs = append(errs, fmt.Sprintf("partition %d has %d followers, expected %d", partitionId, followers[partitionId], expected.replicationFactor-1))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func zeebeStatefulSet(image string, env map[string]string) *appsv1.StatefulSet {
+	container := corev1.Container{Name: "zeebe", Image: image}
+	for name, value := range env {
+		container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+	}
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "release-zeebe"},
+		Spec: appsv1.StatefulSetSpec{
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{container}}},
+		},
+	}
+}
+
+// balancedTopology returns a topology where the partitions are spread round-robin over the brokers, the first
+// replica of each partition is the leader.
+func balancedTopology(clusterSize int32, partitionsCount int32, replicationFactor int32, version string) *pb.TopologyResponse {
+	topology := &pb.TopologyResponse{ClusterSize: clusterSize, PartitionsCount: partitionsCount, ReplicationFactor: replicationFactor}
+	for nodeId := int32(0); nodeId < clusterSize; nodeId++ {
+		topology.Brokers = append(topology.Brokers, &pb.BrokerInfo{NodeId: nodeId, Version: version})
+	}
+	for partitionId := int32(1); partitionId <= partitionsCount; partitionId++ {
+		for replica := int32(0); replica < replicationFactor; replica++ {
+			role := pb.Partition_FOLLOWER
+			if replica == 0 {
+				role = pb.Partition_LEADER
+			}
+			broker := topology.Brokers[(partitionId-1+replica)%clusterSize]
+			broker.Partitions = append(broker.Partitions, &pb.Partition{PartitionId: partitionId, Role: role, Health: pb.Partition_HEALTHY})
+		}
+	}
+	return topology
+}
+
+func TestTopologyFromStatefulSetShouldReadBrokerEnvAndImageTag(t *testing.T) {
+	t.Parallel()
+
+	// given
+	statefulSet := zeebeStatefulSet("camunda/zeebe:8.1.7", map[string]string{
+		"ZEEBE_BROKER_CLUSTER_CLUSTERSIZE":       "1",
+		"ZEEBE_BROKER_CLUSTER_PARTITIONSCOUNT":   "2",
+		"ZEEBE_BROKER_CLUSTER_REPLICATIONFACTOR": "1",
+		"ZEEBE_BROKER_THREADS_CPUTHREADCOUNT":    "3",
+	})
+
+	// when
+	topology, err := topologyFromStatefulSet(statefulSet)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, expectedTopology{clusterSize: 1, partitionsCount: 2, replicationFactor: 1, version: "8.1.7"}, topology)
+}
+
+func TestTopologyFromStatefulSetShouldFailWithoutClusterEnv(t *testing.T) {
+	t.Parallel()
+
+	// given
+	statefulSet := zeebeStatefulSet("camunda/zeebe:8.1.7", map[string]string{
+		"ZEEBE_BROKER_CLUSTER_CLUSTERSIZE": "3",
+	})
+
+	// when
+	_, err := topologyFromStatefulSet(statefulSet)
+
+	// then
+	require.ErrorContains(t, err, "ZEEBE_BROKER_CLUSTER_PARTITIONSCOUNT, ZEEBE_BROKER_CLUSTER_REPLICATIONFACTOR")
+}
+
+func TestImageTagShouldIgnoreRegistryPortAndDigest(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "8.1.7", imageTag("camunda/zeebe:8.1.7"))
+	require.Equal(t, "8.1.7", imageTag("registry.local:5000/camunda/zeebe:8.1.7"))
+	require.Equal(t, "8.1.7", imageTag("camunda/zeebe:8.1.7@sha256:abc"))
+	require.Equal(t, "", imageTag("registry.local:5000/camunda/zeebe@sha256:abc"))
+}
+
+func TestVerifyTopologyShouldAcceptBalancedTopology(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 3, partitionsCount: 3, replicationFactor: 3, version: "8.1.7"}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyTopologyShouldAcceptSingleBroker(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 1, partitionsCount: 2, replicationFactor: 1, version: "8.1.7"}
+	topology := balancedTopology(1, 2, 1, "8.1.7")
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyTopologyShouldRejectDifferentSizes(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 1, partitionsCount: 2, replicationFactor: 1}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.ErrorContains(t, err, "clusterSize=3 partitionsCount=3 replicationFactor=3")
+}
+
+func TestVerifyTopologyShouldRejectMissingBroker(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 3, partitionsCount: 3, replicationFactor: 3}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+	topology.Brokers = topology.Brokers[:2]
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.ErrorContains(t, err, "expected 3 brokers")
+}
+
+func TestVerifyTopologyShouldRejectPartitionWithoutLeader(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 3, partitionsCount: 3, replicationFactor: 3}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+	topology.Brokers[0].Partitions[0].Role = pb.Partition_FOLLOWER
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.ErrorContains(t, err, "partition 1 has leaders [], expected exactly one")
+}
+
+func TestVerifyTopologyShouldRejectUnhealthyLeader(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 3, partitionsCount: 3, replicationFactor: 3}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+	topology.Brokers[0].Partitions[0].Health = pb.Partition_UNHEALTHY
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.ErrorContains(t, err, "leader 0 of partition 1 is UNHEALTHY")
+}
+
+func TestVerifyTopologyShouldRejectTwoLeaders(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 3, partitionsCount: 3, replicationFactor: 3}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+	for _, partition := range topology.Brokers[1].Partitions {
+		if partition.PartitionId == 1 {
+			partition.Role = pb.Partition_LEADER
+		}
+	}
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.ErrorContains(t, err, "partition 1 has leaders [0 1], expected exactly one")
+	require.ErrorContains(t, err, "partition 1 has 1 followers, expected 2")
+}
+
+func TestVerifyTopologyShouldRejectOtherBrokerVersion(t *testing.T) {
+	t.Parallel()
+
+	// given
+	expected := expectedTopology{clusterSize: 3, partitionsCount: 3, replicationFactor: 3, version: "8.1.7"}
+	topology := balancedTopology(3, 3, 3, "8.1.7")
+	topology.Brokers[2].Version = "8.1.6"
+
+	// when
+	err := verifyTopology(topology, expected)
+
+	// then
+	require.ErrorContains(t, err, `broker 2 reports version "8.1.6", expected "8.1.7"`)
+}
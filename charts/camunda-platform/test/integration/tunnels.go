@@ -0,0 +1,217 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/portforward"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	// kIdentityDefaultPort is the port Identity expects to be reachable on if identity.fullURL isn't set.
+	kIdentityDefaultPort = 8080
+	kRealmPath           = "/auth/realms/camunda-platform"
+)
+
+// publicUrlEnvs are the env vars of the Identity deployment which hold the public URL of a service,
+// the tunnels to these services have to use the port of that URL, otherwise redirects and token issuers don't match.
+var publicUrlEnvs = map[string]string{
+	"IDENTITY_AUTH_PROVIDER_ISSUER_URL": "keycloak",
+	"KEYCLOAK_INIT_OPERATE_ROOT_URL":    "operate",
+	"KEYCLOAK_INIT_TASKLIST_ROOT_URL":   "tasklist",
+	"KEYCLOAK_INIT_OPTIMIZE_ROOT_URL":   "optimize",
+	"IDENTITY_URL":                      "identity",
+}
+
+// portForwards returns the tunnel manager of the current test, the tunnels are closed in TearDownTest.
+func (s *integrationSuite) portForwards() *portforward.Manager {
+	if s.tunnels == nil {
+		s.tunnels = portforward.NewManager(portforward.KubeForwarder{T: s.T(), Options: s.kubeOptions})
+		s.tunnels.Logf = s.T().Logf
+		// tests running at the same time must not pick the same local ports
+		s.tunnels.FreePort = func() (int, error) {
+			return portforward.Ports.Reserve(s.namespace)
+		}
+		for service, port := range s.resolvePublicPorts() {
+			if err := portforward.Ports.ReserveExact(s.namespace, port); err != nil {
+				s.T().Fatalf("Cannot pin the tunnel to %s: %v", service, err)
+			}
+			s.tunnels.Pin(s.serviceName(service), port)
+		}
+	}
+	return s.tunnels
+}
+
+func (s *integrationSuite) closePortForwards() {
+	if s.tunnels != nil {
+		s.tunnels.CloseAll()
+		s.tunnels = nil
+	}
+	s.publicPorts = nil
+	portforward.Ports.Release(s.namespace)
+}
+
+// portForward returns the local endpoint of the shared tunnel to the container port of the service,
+// it waits until the tunnel can be opened.
+func (s *integrationSuite) portForward(serviceName string, containerPort int) string {
+	s.waitUntilServiceAvailable(serviceName)
+
+	target := portforward.Target{Service: serviceName, Port: containerPort}
+	var endpoint string
+	tunnelIsReady := func() bool {
+		var err error
+		endpoint, err = s.portForwards().Endpoint(target)
+		if err != nil {
+			s.T().Logf("Tunnel to %s is not ready: %v", target, err)
+			return false
+		}
+		return true
+	}
+	s.waitUntil("PortForward", serviceName, tunnelIsReady, "Ready")
+	return endpoint
+}
+
+// portForwardKeycloak returns the local endpoint of Keycloak, which is always the port of the public issuer URL.
+func (s *integrationSuite) portForwardKeycloak() string {
+	return s.portForward(s.resolveKeycloakServiceName(), 8080)
+}
+
+// serviceName returns the name of the service of a component of the release.
+func (s *integrationSuite) serviceName(component string) string {
+	if component == "keycloak" {
+		return s.resolveKeycloakServiceName()
+	}
+	return fmt.Sprintf("%s-%s", s.release, component)
+}
+
+// allocatePublicPorts returns the local ports the public URLs of the release are set to. Keycloak uses the configured
+// port if there is one, all other ports are free ports. Through the ingress only the stand-in controller gets a port.
+// The ports are allocated once per test and stay reserved.
+func (s *integrationSuite) allocatePublicPorts() map[string]int {
+	if s.publicPorts == nil {
+		s.publicPorts = map[string]int{}
+		if s.usesIngress() {
+			// all public URLs point to the ingress, only the tunnel to the stand-in controller needs a port
+			if s.usesIngressStandIn() {
+				port, err := portforward.Ports.Reserve(s.namespace)
+				s.Require().NoError(err)
+				s.publicPorts["ingress"] = port
+			}
+			return s.publicPorts
+		}
+		if s.options.keycloakPort > 0 {
+			s.Require().NoError(portforward.Ports.ReserveExact(s.namespace, s.options.keycloakPort),
+				"a fixed Keycloak port can't be shared by tests running at the same time")
+			s.publicPorts["keycloak"] = s.options.keycloakPort
+		}
+		for _, component := range []string{"keycloak", "operate", "tasklist", "optimize", "identity"} {
+			if _, allocated := s.publicPorts[component]; allocated {
+				continue
+			}
+			port, err := portforward.Ports.Reserve(s.namespace)
+			s.Require().NoError(err)
+			s.publicPorts[component] = port
+		}
+	}
+	return s.publicPorts
+}
+
+// publicUrlValues returns the values which point the public URLs of the release to the local tunnels, or to the
+// ingress paths if the test connects through the ingress.
+func (s *integrationSuite) publicUrlValues() map[string]string {
+	if s.usesIngress() {
+		return s.ingressValues()
+	}
+	ports := s.allocatePublicPorts()
+	localUrl := func(component string) string {
+		return fmt.Sprintf("http://localhost:%d", ports[component])
+	}
+	return map[string]string{
+		"global.identity.auth.publicIssuerUrl":      localUrl("keycloak") + kRealmPath,
+		"global.identity.auth.operate.redirectUrl":  localUrl("operate"),
+		"global.identity.auth.tasklist.redirectUrl": localUrl("tasklist"),
+		"global.identity.auth.optimize.redirectUrl": localUrl("optimize"),
+		"identity.fullURL":                          localUrl("identity"),
+	}
+}
+
+// resolvePublicPorts reads the ports of the public URLs from the Identity deployment of the release, so reruns against
+// an existing release use the ports it was installed with.
+func (s *integrationSuite) resolvePublicPorts() map[string]int {
+	ports := map[string]int{}
+	if s.options.keycloakPort > 0 {
+		ports["keycloak"] = s.options.keycloakPort
+	}
+
+	deployment, err := s.getIdentityDeployment()
+	if err != nil {
+		s.T().Logf("Cannot get Identity deployment to resolve the public ports, using defaults: %v", err)
+		return ports
+	}
+	if s.usesIngress() {
+		return resolveIngressPort(deployment, s.ingressHost())
+	}
+	ports["identity"] = kIdentityDefaultPort
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			component, ok := publicUrlEnvs[env.Name]
+			if !ok {
+				continue
+			}
+			if port, ok := localhostPort(env.Value); ok {
+				ports[component] = port
+			}
+		}
+	}
+	return ports
+}
+
+// resolveIngressPort returns the port of the stand-in controller tunnel, which is the port of the public issuer URL.
+func resolveIngressPort(deployment *appsv1.Deployment, ingressHost string) map[string]int {
+	ports := map[string]int{}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name != "IDENTITY_AUTH_PROVIDER_ISSUER_URL" {
+				continue
+			}
+			u, err := url.Parse(env.Value)
+			if err != nil || u.Hostname() != ingressHost {
+				continue
+			}
+			if port, err := strconv.Atoi(u.Port()); err == nil {
+				ports["ingress"] = port
+			}
+		}
+	}
+	return ports
+}
+
+// localhostPort returns the port of a URL pointing to localhost, other URLs are not reached through tunnels.
+func localhostPort(rawUrl string) (int, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Hostname() != "localhost" {
+		return 0, false
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return 0, false
+	}
+	return port, true
+}
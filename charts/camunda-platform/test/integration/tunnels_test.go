@@ -0,0 +1,61 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalhostPort(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		url     string
+		port    int
+		isLocal bool
+	}{
+		{url: "http://localhost:18080/auth/realms/camunda-platform", port: 18080, isLocal: true},
+		{url: "http://localhost:8081", port: 8081, isLocal: true},
+		{url: "http://localhost", isLocal: false},
+		{url: "https://camunda.example.com/operate", isLocal: false},
+		{url: "", isLocal: false},
+	} {
+		// when
+		port, isLocal := localhostPort(testCase.url)
+
+		// then
+		require.Equal(t, testCase.isLocal, isLocal, testCase.url)
+		require.Equal(t, testCase.port, port, testCase.url)
+	}
+}
+
+func TestPublicUrlValuesShouldUseKeycloakPortAndStablePorts(t *testing.T) {
+	t.Parallel()
+
+	// given
+	it := &integrationSuite{options: integrationSuiteOptions{keycloakPort: 28080}}
+	it.SetT(t)
+
+	// when
+	first := it.publicUrlValues()
+	second := it.publicUrlValues()
+
+	// then
+	require.Equal(t, "http://localhost:28080/auth/realms/camunda-platform", first["global.identity.auth.publicIssuerUrl"])
+	require.Equal(t, first, second)
+	require.NotEqual(t, first["global.identity.auth.operate.redirectUrl"], first["global.identity.auth.tasklist.redirectUrl"])
+}
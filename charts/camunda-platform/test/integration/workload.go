@@ -0,0 +1,148 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/workload"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+var defaultWorkloadModels = []workload.Model{
+	{
+		ResourceFile:  "it-service-task-process.bpmn",
+		BpmnProcessId: "it-service-task-process",
+		JobTypes:      []string{"it-service-task"},
+		ExpectedState: workload.StateCompleted,
+	},
+	{
+		ResourceFile:  "it-message-process.bpmn",
+		BpmnProcessId: "it-message-process",
+		JobTypes:      []string{"it-message-task"},
+		MessageName:   "it-message",
+		ExpectedState: workload.StateCompleted,
+	},
+}
+
+// runningWorkload is a workload which starts its instances in the background, see startWorkload.
+type runningWorkload struct {
+	generator    *workload.Generator
+	expectedJobs int64
+	done         chan error
+	close        func()
+}
+
+// runWorkload deploys the workload models, starts the configured instances and waits until the
+// workers completed all service tasks. It returns the expected final state of every started instance.
+func (s *integrationSuite) runWorkload(models []workload.Model) []workload.Expectation {
+	return s.awaitWorkload(s.startWorkload(models))
+}
+
+// startWorkload deploys the workload models, opens the workers and starts the configured instances in the
+// background, so the cluster can be disrupted while the workload runs.
+func (s *integrationSuite) startWorkload(models []workload.Model) *runningWorkload {
+	serviceName := s.zeebeGatewayServiceName()
+	client, closeFn, err := s.createPortForwardedClient(serviceName)
+	s.Require().NoError(err, "failed to create Zeebe client")
+
+	generator := workload.NewGenerator(client, workload.Config{
+		Models:    models,
+		Instances: s.options.workloadInstances,
+		Rate:      s.options.workloadRate,
+	})
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Minute)
+	var closeOnce sync.Once
+	running := &runningWorkload{
+		generator: generator,
+		done:      make(chan error, 1),
+		close: func() {
+			closeOnce.Do(func() {
+				cancelFn()
+				generator.Close()
+				closeFn()
+			})
+		},
+	}
+	s.T().Cleanup(running.close)
+
+	s.Require().NoError(generator.Deploy(ctx), "failed to deploy workload models")
+	generator.OpenWorkers()
+	go func() { running.done <- generator.Run(ctx) }()
+
+	for _, model := range models {
+		running.expectedJobs += int64(len(model.JobTypes) * s.options.workloadInstances)
+	}
+	return running
+}
+
+// awaitWorkload waits until all instances of the workload are started and the workers completed all service tasks.
+func (s *integrationSuite) awaitWorkload(running *runningWorkload) []workload.Expectation {
+	defer running.close()
+	s.Require().NoError(<-running.done, "failed to run workload")
+
+	generator := running.generator
+	jobsCompleted := func() bool {
+		return generator.CompletedJobs() >= running.expectedJobs
+	}
+	s.waitUntil("Workload", "job workers", jobsCompleted, "Completed")
+
+	expectations := generator.Expectations()
+	s.T().Logf("Workload started %d process instances and completed %d jobs", len(expectations), generator.CompletedJobs())
+	return expectations
+}
+
+// assertWorkloadInOperate reconciles the expected final states of the workload with the process instances
+// exported to Elasticsearch, as reported by the Operate API. Each process instance is looked up by its key, so
+// instances of earlier runs in the same namespace don't interfere.
+func (s *integrationSuite) assertWorkloadInOperate(expectations []workload.Expectation) {
+	reconciled := map[int64]bool{}
+
+	message := retry.DoWithRetry(s.T(),
+		"Try to reconcile the workload process instances with operate",
+		30,
+		10*time.Second,
+		func() (string, error) {
+			for _, expectation := range expectations {
+				if reconciled[expectation.ProcessInstanceKey] {
+					continue
+				}
+
+				filter := map[string]interface{}{"key": expectation.ProcessInstanceKey}
+				instances, err := s.searchProcessInstancesFromOperate(filter, 1)
+				if err != nil {
+					return "", err
+				}
+
+				actualState := ""
+				if len(instances) == 1 {
+					actualState = instances[0].State
+				}
+				if actualState != string(expectation.State) {
+					return "", fmt.Errorf("expected process instance %d of %s to be %s but got '%s' (%d of %d reconciled)",
+						expectation.ProcessInstanceKey, expectation.BpmnProcessId, expectation.State, actualState,
+						len(reconciled), len(expectations))
+				}
+				reconciled[expectation.ProcessInstanceKey] = true
+			}
+
+			return fmt.Sprintf("All %d workload process instances reconciled with operate!", len(expectations)), nil
+		})
+	s.T().Logf(message)
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/entities"
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/worker"
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+	"github.com/cenkalti/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// State is the process instance state as reported by Operate.
+type State string
+
+const (
+	StateActive    State = "ACTIVE"
+	StateCompleted State = "COMPLETED"
+)
+
+// correlationKeyVariable is the process variable the message subscriptions of the models correlate on.
+const correlationKeyVariable = "correlationKey"
+
+// Model describes a BPMN model which is deployed by the workload and the way its instances are driven.
+type Model struct {
+	// ResourceFile is the path of the BPMN file which is deployed.
+	ResourceFile string
+	// BpmnProcessId is the id of the process inside the resource file.
+	BpmnProcessId string
+	// JobTypes are the service task types which the workload workers complete.
+	JobTypes []string
+	// MessageName is published once per instance, correlated on the "correlationKey" variable.
+	MessageName string
+	// ExpectedState is the state the instances of this model should end up in.
+	ExpectedState State
+}
+
+// Config defines which models are used and how many instances are started at which rate.
+type Config struct {
+	Models []Model
+	// Instances is the number of process instances started per model.
+	Instances int
+	// Rate is the number of process instances started per second over all models.
+	Rate float64
+	// MessageTimeToLive is the time a published message is buffered until it's correlated.
+	MessageTimeToLive time.Duration
+}
+
+// Expectation is the final state one started process instance should reach.
+type Expectation struct {
+	ProcessInstanceKey int64
+	BpmnProcessId      string
+	State              State
+}
+
+// Generator creates load on a Zeebe cluster and keeps track of the expected outcome.
+type Generator struct {
+	client        zbc.Client
+	config        Config
+	workers       []worker.JobWorker
+	completedJobs int64
+
+	mu           sync.Mutex
+	expectations []Expectation
+}
+
+func NewGenerator(client zbc.Client, config Config) *Generator {
+	if config.Rate <= 0 {
+		config.Rate = 1
+	}
+	if config.MessageTimeToLive == 0 {
+		config.MessageTimeToLive = 10 * time.Minute
+	}
+	return &Generator{client: client, config: config}
+}
+
+// Deploy deploys all models of the workload.
+func (g *Generator) Deploy(ctx context.Context) error {
+	for _, model := range g.config.Models {
+		response, err := g.client.NewDeployProcessCommand().AddResourceFile(model.ResourceFile).Send(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to deploy %s: %w", model.ResourceFile, err)
+		}
+		if len(response.Processes) != 1 || response.Processes[0].BpmnProcessId != model.BpmnProcessId {
+			return fmt.Errorf("expected %s to contain only the process %s", model.ResourceFile, model.BpmnProcessId)
+		}
+	}
+	return nil
+}
+
+// OpenWorkers opens one job worker per job type, which completes every activated job.
+func (g *Generator) OpenWorkers() {
+	for _, model := range g.config.Models {
+		for _, jobType := range model.JobTypes {
+			jobWorker := g.client.NewJobWorker().
+				JobType(jobType).
+				Handler(g.completeJob).
+				Name("it-workload").
+				Timeout(30 * time.Second).
+				Open()
+			g.workers = append(g.workers, jobWorker)
+		}
+	}
+}
+
+func (g *Generator) completeJob(client worker.JobClient, job entities.Job) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelFn()
+
+	command, err := client.NewCompleteJobCommand().JobKey(job.GetKey()).VariablesFromMap(map[string]interface{}{
+		job.GetType() + "Completed": true,
+	})
+	if err == nil {
+		_, err = command.Send(ctx)
+	}
+	if isTransient(err) {
+		// the job is activated again after its timeout, failing it would only use up its retries
+		log.Printf("Failed to complete job %d of type %s, it's retried after its timeout: %v", job.GetKey(), job.GetType(), err)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to complete job %d of type %s: %v", job.GetKey(), job.GetType(), err)
+		_, _ = client.NewFailJobCommand().JobKey(job.GetKey()).Retries(job.GetRetries() - 1).Send(ctx)
+		return
+	}
+	atomic.AddInt64(&g.completedJobs, 1)
+}
+
+// Run starts the configured number of instances for each model at the configured rate,
+// and publishes the messages the instances are waiting for.
+func (g *Generator) Run(ctx context.Context) error {
+	if len(g.config.Models) == 0 {
+		return errors.New("no models configured for the workload")
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / g.config.Rate))
+	defer ticker.Stop()
+
+	for i := 0; i < g.config.Instances; i++ {
+		for _, model := range g.config.Models {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			if err := g.startInstance(ctx, model, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Generator) startInstance(ctx context.Context, model Model, index int) error {
+	correlationKey := fmt.Sprintf("%s-%d", model.BpmnProcessId, index)
+	command, err := g.client.NewCreateInstanceCommand().
+		BPMNProcessId(model.BpmnProcessId).
+		LatestVersion().
+		VariablesFromMap(map[string]interface{}{correlationKeyVariable: correlationKey})
+	if err != nil {
+		return err
+	}
+
+	var response *pb.CreateProcessInstanceResponse
+	err = sendWithRetry(ctx, func() (err error) {
+		response, err = command.Send(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create instance of %s: %w", model.BpmnProcessId, err)
+	}
+
+	if model.MessageName != "" {
+		err = sendWithRetry(ctx, func() error {
+			_, err := g.client.NewPublishMessageCommand().
+				MessageName(model.MessageName).
+				CorrelationKey(correlationKey).
+				TimeToLive(g.config.MessageTimeToLive).
+				Send(ctx)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish message %s for %s: %w", model.MessageName, correlationKey, err)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.expectations = append(g.expectations, Expectation{
+		ProcessInstanceKey: response.ProcessInstanceKey,
+		BpmnProcessId:      model.BpmnProcessId,
+		State:              model.ExpectedState,
+	})
+	return nil
+}
+
+// isTransient reports whether a command was rejected without being processed, because its partition has no
+// leader at the moment, e.g. while a broker restarts. Such commands can be sent again.
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// sendWithRetry sends the command again as long as it's rejected as transient, until the context is done.
+func sendWithRetry(ctx context.Context, send func() error) error {
+	return backoff.Retry(func() error {
+		err := send()
+		if err != nil && !isTransient(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
+}
+
+// Expectations returns the expected final states of all instances started so far.
+func (g *Generator) Expectations() []Expectation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Expectation(nil), g.expectations...)
+}
+
+// CompletedJobs returns the number of jobs completed by the workload workers.
+func (g *Generator) CompletedJobs() int64 {
+	return atomic.LoadInt64(&g.completedJobs)
+}
+
+// Close closes all job workers, the client itself is owned by the caller.
+func (g *Generator) Close() {
+	for _, jobWorker := range g.workers {
+		jobWorker.Close()
+	}
+	g.workers = nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/oidc"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	kZeebeGatewayPort      = 26500
+	kZeebeGatewayCertsPath = "/usr/local/zeebe/certs"
+	kZeebeGatewayCertsName = "zeebe-gateway-certs"
+)
+
+// zeebeGatewaySecurity describes how the Zeebe gateway of the release is secured, and so how clients connect to it.
+type zeebeGatewaySecurity struct {
+	// tls serves the gateway with a certificate of the test CA stored in secretName.
+	tls        bool
+	secretName string
+	// auth lets the gateway only accept calls with an access token of the realm for the audience.
+	auth             bool
+	issuerBackendUrl string
+	audience         string
+}
+
+func (s *integrationSuite) zeebeGatewayServiceName() string {
+	return fmt.Sprintf("%s-zeebe-gateway", s.release)
+}
+
+// zeebeGatewaySecurity returns the security settings the gateway of the release is installed with.
+func (s *integrationSuite) zeebeGatewaySecurity() zeebeGatewaySecurity {
+	security := zeebeGatewaySecurity{tls: s.options.zeebeTLS, auth: s.options.zeebeAuth}
+	if security.tls {
+		security.secretName = s.zeebeGatewayServiceName() + "-tls"
+	}
+	if security.auth {
+		security.issuerBackendUrl = fmt.Sprintf("http://%s:80%s", s.resolveKeycloakServiceName(), kRealmPath)
+		security.audience = s.options.zeebeAudience
+	}
+	return security
+}
+
+// zeebeGatewayHosts are the names the gateway certificate is valid for, localhost for the tunnel of the suite.
+func (s *integrationSuite) zeebeGatewayHosts() []string {
+	service := s.zeebeGatewayServiceName()
+	return []string{
+		"localhost",
+		service,
+		service + "." + s.namespace + ".svc",
+		service + "." + s.namespace + ".svc.cluster.local",
+	}
+}
+
+// zeebeGatewayValues returns the values which install the gateway with the security settings of the suite,
+// the certificate secret is created before the release.
+func (s *integrationSuite) zeebeGatewayValues() map[string]string {
+	security := s.zeebeGatewaySecurity()
+	if security.tls {
+		s.ensureZeebeGatewayTLSSecret(security.secretName)
+	}
+	return security.values()
+}
+
+func (s *integrationSuite) ensureZeebeGatewayTLSSecret(secretName string) {
+	if _, err := k8s.GetSecretE(s.T(), s.kubeOptions, secretName); err == nil {
+		return
+	}
+	keyPair, err := s.testCA().IssueServerCert(s.zeebeGatewayHosts()...)
+	s.Require().NoError(err)
+	s.createTLSSecret(secretName, keyPair.CertPEM, keyPair.KeyPEM)
+}
+
+// values returns the zeebe-gateway values of the security settings. The gateway has no dedicated security values,
+// so they are set as env vars, the certificate is mounted as extra volume. The connectors are disabled since they
+// only connect in plaintext without credentials.
+func (security zeebeGatewaySecurity) values() map[string]string {
+	values := map[string]string{}
+	env := [][2]string{}
+	if security.tls {
+		env = append(env,
+			[2]string{"ZEEBE_GATEWAY_SECURITY_ENABLED", "true"},
+			[2]string{"ZEEBE_GATEWAY_SECURITY_CERTIFICATECHAINPATH", kZeebeGatewayCertsPath + "/tls.crt"},
+			[2]string{"ZEEBE_GATEWAY_SECURITY_PRIVATEKEYPATH", kZeebeGatewayCertsPath + "/tls.key"},
+		)
+		values["zeebe-gateway.extraVolumes[0].name"] = kZeebeGatewayCertsName
+		values["zeebe-gateway.extraVolumes[0].secret.secretName"] = security.secretName
+		values["zeebe-gateway.extraVolumeMounts[0].name"] = kZeebeGatewayCertsName
+		values["zeebe-gateway.extraVolumeMounts[0].mountPath"] = kZeebeGatewayCertsPath
+		values["zeebe-gateway.extraVolumeMounts[0].readOnly"] = "true"
+	}
+	if security.auth {
+		env = append(env,
+			[2]string{"ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_MODE", "identity"},
+			[2]string{"ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_ISSUERBACKENDURL", security.issuerBackendUrl},
+			[2]string{"ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_AUDIENCE", security.audience},
+		)
+	}
+	for i, nameValue := range env {
+		values[fmt.Sprintf("zeebe-gateway.env[%d].name", i)] = nameValue[0]
+		values[fmt.Sprintf("zeebe-gateway.env[%d].value", i)] = nameValue[1]
+	}
+	if len(env) > 0 {
+		values["connectors.enabled"] = "false"
+	}
+	return values
+}
+
+// zeebeClientApi returns the Identity client the suite requests access tokens for the gateway with.
+func (s *integrationSuite) zeebeClientApi() (componentApi, error) {
+	for _, api := range componentApis {
+		if api.clientId == s.options.zeebeClientId {
+			return componentApi{
+				clientId:     api.clientId,
+				secretSuffix: api.secretSuffix,
+				secretKey:    api.secretKey,
+				audience:     s.options.zeebeAudience,
+			}, nil
+		}
+	}
+	return componentApi{}, fmt.Errorf("no Identity client '%s' is known to request Zeebe access tokens with", s.options.zeebeClientId)
+}
+
+// zeebeClientConfig returns the client config for the gateway at the endpoint, matching its security settings.
+// The CA certificate and the token cache are written to a directory of the test.
+func (s *integrationSuite) zeebeClientConfig(endpoint string, security zeebeGatewaySecurity) (*zbc.ClientConfig, error) {
+	config := &zbc.ClientConfig{
+		GatewayAddress:         endpoint,
+		DialOpts:               []grpc.DialOption{},
+		UsePlaintextConnection: !security.tls,
+	}
+	if security.tls {
+		caPath := filepath.Join(s.T().TempDir(), "ca.crt")
+		if err := os.WriteFile(caPath, s.testCA().CertPEM, 0o600); err != nil {
+			return nil, err
+		}
+		config.CaCertificatePath = caPath
+	}
+	if security.auth {
+		credentialsProvider, err := s.zeebeCredentialsProvider()
+		if err != nil {
+			return nil, err
+		}
+		config.CredentialsProvider = credentialsProvider
+	}
+	return config, nil
+}
+
+// zeebeCredentialsProvider requests the access tokens of the Identity client from the Keycloak realm. A token is
+// requested upfront, to fail with a clear message if the client isn't granted the audience of the gateway.
+func (s *integrationSuite) zeebeCredentialsProvider() (zbc.CredentialsProvider, error) {
+	api, err := s.zeebeClientApi()
+	if err != nil {
+		return nil, err
+	}
+	clientSecret := s.getSecret(api.secretSuffix, api.secretKey)
+	issuerUrl := "http://" + s.webEndpoint("keycloak", 8080) + kRealmPath
+	if _, err := s.requestClientToken(issuerUrl, api, clientSecret); err != nil {
+		return nil, err
+	}
+
+	// the default cache is shared in the home directory, the tests use their own
+	cache, err := zbc.NewOAuthYamlCredentialsCache(filepath.Join(s.T().TempDir(), "credentials"))
+	if err != nil {
+		return nil, err
+	}
+	return zbc.NewOAuthCredentialsProvider(&zbc.OAuthProviderConfig{
+		ClientID:               api.clientId,
+		ClientSecret:           clientSecret,
+		Audience:               api.audience,
+		AuthorizationServerURL: oidc.TokenURL(issuerUrl),
+		Cache:                  cache,
+	})
+}
+
+// assertUnauthenticatedZeebeClientRejected checks that the gateway rejects calls without an access token.
+func (s *integrationSuite) assertUnauthenticatedZeebeClientRejected() {
+	security := s.zeebeGatewaySecurity()
+	security.auth = false
+	endpoint := s.portForward(s.zeebeGatewayServiceName(), kZeebeGatewayPort)
+	config, err := s.zeebeClientConfig(endpoint, security)
+	s.Require().NoError(err)
+	client, err := zbc.NewClient(config)
+	s.Require().NoError(err, "failed to create Zeebe client")
+	defer client.Close()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelFn()
+	_, err = client.NewTopologyCommand().Send(ctx)
+
+	s.Require().Error(err, "expected the gateway to reject a client without credentials")
+	s.Require().Equal(codes.Unauthenticated, status.Code(err), "expected the gateway to reject a client without credentials as unauthenticated, but got: %v", err)
+	s.T().Logf("Zeebe client without credentials was rejected: %v", err)
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"camunda-platform-helm/charts/camunda-platform/test/integration/testca"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type topologyGateway struct {
+	pb.UnimplementedGatewayServer
+}
+
+func (*topologyGateway) Topology(context.Context, *pb.TopologyRequest) (*pb.TopologyResponse, error) {
+	return &pb.TopologyResponse{ClusterSize: 3, PartitionsCount: 3, ReplicationFactor: 3}, nil
+}
+
+// newTLSGateway starts a gRPC gateway on localhost with a certificate of the CA and returns its address.
+func newTLSGateway(t *testing.T, ca *testca.CA) string {
+	keyPair, err := ca.IssueServerCert("localhost")
+	require.NoError(t, err)
+	certificate, err := tls.X509KeyPair(keyPair.CertPEM, keyPair.KeyPEM)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&certificate)))
+	pb.RegisterGatewayServer(server, &topologyGateway{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return strings.Replace(listener.Addr().String(), "127.0.0.1", "localhost", 1)
+}
+
+func TestZeebeGatewayValuesShouldBeEmptyForPlaintext(t *testing.T) {
+	t.Parallel()
+
+	// given
+	security := zeebeGatewaySecurity{}
+
+	// when
+	values := security.values()
+
+	// then
+	require.Empty(t, values)
+}
+
+func TestZeebeGatewayValuesShouldMountCertificateForTLS(t *testing.T) {
+	t.Parallel()
+
+	// given
+	security := zeebeGatewaySecurity{tls: true, secretName: "release-zeebe-gateway-tls"}
+
+	// when
+	values := security.values()
+
+	// then
+	require.Equal(t, "ZEEBE_GATEWAY_SECURITY_ENABLED", values["zeebe-gateway.env[0].name"])
+	require.Equal(t, "true", values["zeebe-gateway.env[0].value"])
+	require.Equal(t, kZeebeGatewayCertsPath+"/tls.crt", values["zeebe-gateway.env[1].value"])
+	require.Equal(t, kZeebeGatewayCertsPath+"/tls.key", values["zeebe-gateway.env[2].value"])
+	require.Equal(t, "release-zeebe-gateway-tls", values["zeebe-gateway.extraVolumes[0].secret.secretName"])
+	require.Equal(t, values["zeebe-gateway.extraVolumes[0].name"], values["zeebe-gateway.extraVolumeMounts[0].name"])
+	require.Equal(t, kZeebeGatewayCertsPath, values["zeebe-gateway.extraVolumeMounts[0].mountPath"])
+	require.Equal(t, "false", values["connectors.enabled"])
+	require.NotContains(t, values, "zeebe-gateway.env[3].name")
+}
+
+func TestZeebeGatewayValuesShouldConfigureIdentityAuthAfterTLS(t *testing.T) {
+	t.Parallel()
+
+	// given
+	security := zeebeGatewaySecurity{
+		tls:              true,
+		secretName:       "release-zeebe-gateway-tls",
+		auth:             true,
+		issuerBackendUrl: "http://release-keycloak:80" + kRealmPath,
+		audience:         "zeebe-api",
+	}
+
+	// when
+	values := security.values()
+
+	// then
+	require.Equal(t, "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_MODE", values["zeebe-gateway.env[3].name"])
+	require.Equal(t, "identity", values["zeebe-gateway.env[3].value"])
+	require.Equal(t, "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_ISSUERBACKENDURL", values["zeebe-gateway.env[4].name"])
+	require.Equal(t, "http://release-keycloak:80"+kRealmPath, values["zeebe-gateway.env[4].value"])
+	require.Equal(t, "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_AUDIENCE", values["zeebe-gateway.env[5].name"])
+	require.Equal(t, "zeebe-api", values["zeebe-gateway.env[5].value"])
+}
+
+func TestZeebeGatewaySecurityShouldUseInternalKeycloakAsIssuerBackend(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := &integrationSuite{
+		release:        "camunda-platform-it-abc",
+		keycloakLegacy: true,
+		options:        integrationSuiteOptions{zeebeTLS: true, zeebeAuth: true, zeebeAudience: "zeebe-api"},
+	}
+
+	// when
+	security := s.zeebeGatewaySecurity()
+
+	// then
+	require.Equal(t, "camunda-platform-it-abc-zeebe-gateway-tls", security.secretName)
+	require.Equal(t, "http://camunda-platform-it:80"+kRealmPath, security.issuerBackendUrl)
+	require.Equal(t, "zeebe-api", security.audience)
+}
+
+func TestZeebeClientApiShouldUseSecretOfIdentityClient(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := &integrationSuite{options: integrationSuiteOptions{zeebeClientId: "operate", zeebeAudience: "zeebe-api"}}
+
+	// when
+	api, err := s.zeebeClientApi()
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, "operate", api.clientId)
+	require.Equal(t, "-operate-identity-secret", api.secretSuffix)
+	require.Equal(t, "operate-secret", api.secretKey)
+	require.Equal(t, "zeebe-api", api.audience)
+}
+
+func TestZeebeClientApiShouldFailForUnknownClient(t *testing.T) {
+	t.Parallel()
+
+	// given
+	s := &integrationSuite{options: integrationSuiteOptions{zeebeClientId: "zeebe"}}
+
+	// when
+	_, err := s.zeebeClientApi()
+
+	// then
+	require.ErrorContains(t, err, "'zeebe'")
+}
+
+func TestZeebeClientConfigShouldConnectWithTestCA(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := testca.New("IT CA")
+	require.NoError(t, err)
+	s := &integrationSuite{ca: ca}
+	s.SetT(t)
+	endpoint := newTLSGateway(t, ca)
+
+	// when
+	config, err := s.zeebeClientConfig(endpoint, zeebeGatewaySecurity{tls: true})
+	require.NoError(t, err)
+	client, err := zbc.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	topology, err := client.NewTopologyCommand().Send(ctx)
+
+	// then
+	require.NoError(t, err)
+	require.EqualValues(t, 3, topology.ClusterSize)
+}
+
+func TestZeebeClientConfigShouldRejectGatewayOfOtherCA(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ca, err := testca.New("IT CA")
+	require.NoError(t, err)
+	otherCA, err := testca.New("Other CA")
+	require.NoError(t, err)
+	s := &integrationSuite{ca: ca}
+	s.SetT(t)
+	endpoint := newTLSGateway(t, otherCA)
+
+	// when
+	config, err := s.zeebeClientConfig(endpoint, zeebeGatewaySecurity{tls: true})
+	require.NoError(t, err)
+	client, err := zbc.NewClient(config)
+	require.NoError(t, err)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.NewTopologyCommand().Send(ctx)
+
+	// then
+	require.Error(t, err)
+}
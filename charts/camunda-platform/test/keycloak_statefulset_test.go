@@ -23,10 +23,16 @@ package test
 import (
 	"camunda-platform-helm/charts/camunda-platform/test/golden"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestGoldenKeycloakDefaults(t *testing.T) {
@@ -49,3 +55,96 @@ func TestGoldenKeycloakDefaults(t *testing.T) {
 		ExtraHelmArgs: []string{"--show-only", "charts/identity/charts/keycloak/templates/statefulset.yaml"},
 	})
 }
+
+// externalDatabaseValues configure external PostgreSQL databases for Keycloak and Identity.
+func externalDatabaseValues() map[string]string {
+	return map[string]string{
+		"identity.keycloak.postgresql.enabled":                         "false",
+		"identity.keycloak.externalDatabase.host":                      "keycloak-postgres.example.com",
+		"identity.keycloak.externalDatabase.existingSecret":            "keycloak-database",
+		"identity.keycloak.externalDatabase.existingSecretPasswordKey": "db-password",
+		"identity.keycloak.externalDatabase.sslMode":                   "require",
+		"identity.externalDatabase.enabled":                            "true",
+		"identity.externalDatabase.host":                               "identity-postgres.example.com",
+		"identity.externalDatabase.existingSecret":                     "identity-database",
+	}
+}
+
+func TestKeycloakWithExternalDatabase(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	// given
+	options := &helm.Options{
+		SetValues:      externalDatabaseValues(),
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-test"),
+	}
+
+	// when
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", nil,
+		"--show-only", "charts/identity/charts/keycloak/templates/statefulset.yaml")
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(t, output, &statefulSet)
+
+	// then
+	env := statefulSet.Spec.Template.Spec.Containers[0].Env
+	require.Contains(t, env, corev1.EnvVar{Name: "KEYCLOAK_JDBC_PARAMS", Value: "sslmode=require"})
+	for _, variable := range env {
+		if variable.Name == "KEYCLOAK_DATABASE_PASSWORD" {
+			require.Equal(t, "keycloak-database", variable.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+}
+
+func TestExternalDatabasesShouldReplaceEmbeddedPostgresql(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, externalDatabaseValues())
+
+	// then
+	env := release.podSpecs["camunda-platform-test-identity"].Containers[0].Env
+	require.Contains(t, env, corev1.EnvVar{
+		Name:  "SPRING_DATASOURCE_URL",
+		Value: "jdbc:postgresql://identity-postgres.example.com:5432/identity?sslmode=prefer",
+	})
+	require.Contains(t, env, corev1.EnvVar{Name: "SPRING_DATASOURCE_USERNAME", Value: "identity"})
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	options := &helm.Options{
+		SetValues:      externalDatabaseValues(),
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-test"),
+	}
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", nil)
+	for _, document := range strings.Split(output, "\n---\n") {
+		var object metav1.PartialObjectMetadata
+		helm.UnmarshalK8SYaml(t, document, &object)
+		require.NotEqual(t, "postgresql", object.Labels["app.kubernetes.io/name"], "%s %s", object.Kind, object.Name)
+	}
+}
+
+func TestExternalDatabaseShouldFailIfEmbeddedPostgresqlIsEnabled(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"identity.keycloak.externalDatabase.host": "keycloak-postgres.example.com",
+			"identity.externalDatabase.enabled":       "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-test"),
+	}
+
+	// when
+	_, err = helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", nil)
+
+	// then
+	require.ErrorContains(t, err, `[camunda][constraint] The vars "identity.externalDatabase.host" and "identity.externalDatabase.existingSecret" have to be set`)
+	require.ErrorContains(t, err, `[camunda][constraint] The PostgreSQL dependency of Keycloak can't be enabled together with "identity.keycloak.externalDatabase.host".`)
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// multiRegionValues configure one Zeebe cluster of six brokers, three in each of the two regions.
+func multiRegionValues(region int, nodeIdOffset string) map[string]string {
+	return map[string]string{
+		"zeebe.clusterSize":                      "3",
+		"zeebe.multiRegion.enabled":              "true",
+		"zeebe.multiRegion.nodeIdOffset":         nodeIdOffset,
+		"zeebe.multiRegion.clusterSize":          "6",
+		"zeebe.multiRegion.initialContactPoints": "{camunda-platform-test-zeebe-0.camunda-platform-test-zeebe.region0.svc:26502,camunda-platform-test-zeebe-0.camunda-platform-test-zeebe.region1.svc:26502}",
+		"zeebe.multiRegion.exporters[0].name":    "region0",
+		"zeebe.multiRegion.exporters[0].url":     "http://elasticsearch.region0.svc:9200",
+		"zeebe.multiRegion.exporters[1].name":    "region1",
+		"zeebe.multiRegion.exporters[1].url":     "http://elasticsearch.region1.svc:9200",
+		"global.elasticsearch.url":               []string{"http://elasticsearch.region0.svc:9200", "http://elasticsearch.region1.svc:9200"}[region],
+	}
+}
+
+// brokerEnv returns the values of the environment variables of the broker container.
+func brokerEnv(podSpec corev1.PodSpec) map[string]string {
+	env := map[string]string{}
+	for _, variable := range podSpec.Containers[0].Env {
+		env[variable.Name] = variable.Value
+	}
+	return env
+}
+
+// runStartupScript runs the part of the broker startup script, which configures the cluster, for the pod and returns the resulting environment variable.
+func runStartupScript(t *testing.T, configMap corev1.ConfigMap, pod string, variable string) string {
+	script, _, found := strings.Cut(configMap.Data["startup.sh"], `if [ "$(ls -A /exporters/)" ]; then`)
+	require.True(t, found)
+	command := exec.Command("bash", "-c", script+"\necho -n \"${"+variable+"}\"")
+	command.Env = []string{"K8S_NAME=" + pod}
+	output, err := command.Output()
+	require.NoError(t, err)
+	return string(output)
+}
+
+func TestMultiRegionReleasesShouldFormOneConsistentCluster(t *testing.T) {
+	t.Parallel()
+
+	// given
+	regions := []map[string]string{multiRegionValues(0, "0"), multiRegionValues(1, "3")}
+
+	// when
+	var nodeIds, contactPoints []string
+	var clusterEnv []map[string]string
+	for region, values := range regions {
+		release := renderRelease(t, values)
+		configMap := release.configMaps["camunda-platform-test-zeebe"]
+		env := brokerEnv(release.podSpecs["camunda-platform-test-zeebe"])
+		clusterEnv = append(clusterEnv, env)
+
+		// then
+		require.NotContains(t, env, "ZEEBE_BROKER_CLUSTER_INITIALCONTACTPOINTS", "region %d", region)
+		require.NotContains(t, env, "ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_URL", "region %d", region)
+		for _, pod := range []string{"camunda-platform-test-zeebe-0", "camunda-platform-test-zeebe-1", "camunda-platform-test-zeebe-2"} {
+			nodeIds = append(nodeIds, runStartupScript(t, configMap, pod, "ZEEBE_BROKER_CLUSTER_NODEID"))
+			contactPoints = append(contactPoints, runStartupScript(t, configMap, pod, "ZEEBE_BROKER_CLUSTER_INITIALCONTACTPOINTS"))
+		}
+	}
+
+	// then
+	sort.Strings(nodeIds)
+	require.Equal(t, []string{"0", "1", "2", "3", "4", "5"}, nodeIds)
+	for _, contactPoint := range contactPoints {
+		require.Equal(t, "camunda-platform-test-zeebe-0.camunda-platform-test-zeebe.region0.svc:26502,camunda-platform-test-zeebe-0.camunda-platform-test-zeebe.region1.svc:26502", contactPoint)
+	}
+	for _, name := range []string{
+		"ZEEBE_BROKER_CLUSTER_CLUSTERNAME",
+		"ZEEBE_BROKER_CLUSTER_CLUSTERSIZE",
+		"ZEEBE_BROKER_CLUSTER_PARTITIONSCOUNT",
+		"ZEEBE_BROKER_CLUSTER_REPLICATIONFACTOR",
+		"ZEEBE_BROKER_EXPORTERS_ELASTICSEARCHREGION0_ARGS_URL",
+		"ZEEBE_BROKER_EXPORTERS_ELASTICSEARCHREGION1_ARGS_URL",
+	} {
+		require.NotEmpty(t, clusterEnv[0][name], name)
+		require.Equal(t, clusterEnv[0][name], clusterEnv[1][name], name)
+	}
+	require.Equal(t, "6", clusterEnv[0]["ZEEBE_BROKER_CLUSTER_CLUSTERSIZE"])
+	require.Equal(t, "http://elasticsearch.region0.svc:9200", clusterEnv[0]["ZEEBE_BROKER_EXPORTERS_ELASTICSEARCHREGION0_ARGS_URL"])
+	require.Equal(t, "http://elasticsearch.region1.svc:9200", clusterEnv[0]["ZEEBE_BROKER_EXPORTERS_ELASTICSEARCHREGION1_ARGS_URL"])
+}
+
+func TestMultiRegionShouldFailOnInvalidConfiguration(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		name     string
+		values   map[string]string
+		expected string
+	}{
+		{
+			name:     "node ids exceeding the cluster size",
+			values:   map[string]string{"zeebe.multiRegion.nodeIdOffset": "4"},
+			expected: `[zeebe][constraint] The node ids 4 to 6 of the brokers exceed the var "zeebe.multiRegion.clusterSize" 6.`,
+		},
+		{
+			name:     "exporter names with the same exporter id",
+			values:   map[string]string{"zeebe.multiRegion.exporters[1].name": "region-0"},
+			expected: `since they form the exporter ids, but the exporter ids are elasticsearchregion0, elasticsearchregion0.`,
+		},
+		{
+			name:     "network policies",
+			values:   map[string]string{"global.networkPolicy.enabled": "true"},
+			expected: `[zeebe][constraint] The var "global.networkPolicy.enabled" can't be true together with "zeebe.multiRegion.enabled",`,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// given
+			values := multiRegionValues(1, "3")
+			for key, value := range testCase.values {
+				values[key] = value
+			}
+			options := &helm.Options{
+				SetValues:      values,
+				KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-test"),
+			}
+
+			// when
+			_, err := helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", nil)
+
+			// then
+			require.ErrorContains(t, err, testCase.expected)
+		})
+	}
+}
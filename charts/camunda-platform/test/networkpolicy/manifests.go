@@ -0,0 +1,128 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Manifests are the NetworkPolicies and pods of rendered manifests.
+type Manifests struct {
+	Policies []networkingv1.NetworkPolicy
+	Pods     []Pod
+}
+
+// Model returns the model of the policies of the manifests.
+func (m Manifests) Model() *Model {
+	return NewModel(m.Policies...)
+}
+
+// ParseManifests reads the NetworkPolicies and the pods of the workloads of a multi document YAML stream, like the
+// output of "helm template". Objects without namespace are placed into the namespace. Workloads result in one pod
+// with the labels and ports of their pod template, named after the workload.
+func ParseManifests(manifests string, namespace string) (Manifests, error) {
+	var parsed Manifests
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		var document json.RawMessage
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return parsed, nil
+		}
+		if err != nil {
+			return parsed, err
+		}
+		if len(document) == 0 || string(document) == "null" {
+			continue
+		}
+		if err := parsed.add(document, namespace); err != nil {
+			return parsed, err
+		}
+	}
+}
+
+func (m *Manifests) add(document json.RawMessage, namespace string) error {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(document, &typeMeta); err != nil {
+		return err
+	}
+
+	var objectMeta metav1.ObjectMeta
+	var template corev1.PodTemplateSpec
+	switch typeMeta.Kind {
+	case "NetworkPolicy":
+		var policy networkingv1.NetworkPolicy
+		if err := json.Unmarshal(document, &policy); err != nil {
+			return fmt.Errorf("parsing NetworkPolicy: %w", err)
+		}
+		if policy.Namespace == "" {
+			policy.Namespace = namespace
+		}
+		m.Policies = append(m.Policies, policy)
+		return nil
+	case "Deployment", "StatefulSet":
+		// Both have the same metadata and pod template, which is all the model needs.
+		var workload appsv1.Deployment
+		if err := json.Unmarshal(document, &workload); err != nil {
+			return fmt.Errorf("parsing %s: %w", typeMeta.Kind, err)
+		}
+		objectMeta, template = workload.ObjectMeta, workload.Spec.Template
+	case "Job":
+		var job batchv1.Job
+		if err := json.Unmarshal(document, &job); err != nil {
+			return fmt.Errorf("parsing Job: %w", err)
+		}
+		objectMeta, template = job.ObjectMeta, job.Spec.Template
+	case "CronJob":
+		var cronJob batchv1.CronJob
+		if err := json.Unmarshal(document, &cronJob); err != nil {
+			return fmt.Errorf("parsing CronJob: %w", err)
+		}
+		objectMeta, template = cronJob.ObjectMeta, cronJob.Spec.JobTemplate.Spec.Template
+	case "Pod":
+		var pod corev1.Pod
+		if err := json.Unmarshal(document, &pod); err != nil {
+			return fmt.Errorf("parsing Pod: %w", err)
+		}
+		objectMeta, template = pod.ObjectMeta, corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}
+	default:
+		return nil
+	}
+
+	pod := Pod{
+		Name:      objectMeta.Name,
+		Namespace: objectMeta.Namespace,
+		Labels:    template.Labels,
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = namespace
+	}
+	for _, container := range template.Spec.Containers {
+		pod.Ports = append(pod.Ports, container.Ports...)
+	}
+	m.Pods = append(m.Pods, pod)
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package networkpolicy evaluates Kubernetes NetworkPolicies, so the tests can check which pods of a rendered chart
+// can reach each other, without a cluster and a network plugin which enforces the policies.
+package networkpolicy
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Pod is a pod which sends or receives traffic. NamespaceLabels are the labels of its namespace, the
+// kubernetes.io/metadata.name label is added like Kubernetes does.
+type Pod struct {
+	Name            string
+	Namespace       string
+	Labels          map[string]string
+	NamespaceLabels map[string]string
+	Ports           []corev1.ContainerPort
+}
+
+// Model is the set of NetworkPolicies of a cluster.
+type Model struct {
+	policies []networkingv1.NetworkPolicy
+}
+
+// NewModel creates a model of the policies, policies without namespace have to be defaulted by the caller.
+func NewModel(policies ...networkingv1.NetworkPolicy) *Model {
+	return &Model{policies: policies}
+}
+
+// CanReach reports whether the pod "from" can open a TCP connection to the port of the pod "to", which requires the
+// egress policies of "from" and the ingress policies of "to" to allow it. Pods which no policy selects aren't
+// isolated. Peers with an ipBlock never match, since the model has no pod IPs.
+func (m *Model) CanReach(from Pod, to Pod, port int32) bool {
+	return m.allowed(from, to, port, networkingv1.PolicyTypeEgress) && m.allowed(from, to, port, networkingv1.PolicyTypeIngress)
+}
+
+// allowed evaluates the policies of the policy type, which select the pod "to" for ingress and "from" for egress.
+func (m *Model) allowed(from Pod, to Pod, port int32, policyType networkingv1.PolicyType) bool {
+	isolated := to
+	peer := from
+	if policyType == networkingv1.PolicyTypeEgress {
+		isolated, peer = from, to
+	}
+
+	isolating := false
+	for _, policy := range m.policies {
+		if policy.Namespace != isolated.Namespace || !hasPolicyType(policy, policyType) ||
+			!selectorMatches(&policy.Spec.PodSelector, isolated.Labels) {
+			continue
+		}
+		isolating = true
+		if policyType == networkingv1.PolicyTypeIngress {
+			for _, rule := range policy.Spec.Ingress {
+				if portsMatch(rule.Ports, to, port) && peersMatch(rule.From, peer, policy.Namespace) {
+					return true
+				}
+			}
+		} else {
+			for _, rule := range policy.Spec.Egress {
+				if portsMatch(rule.Ports, to, port) && peersMatch(rule.To, peer, policy.Namespace) {
+					return true
+				}
+			}
+		}
+	}
+	return !isolating
+}
+
+// hasPolicyType applies the defaults of Kubernetes: without policy types a policy isolates for ingress, and for
+// egress if it has egress rules.
+func hasPolicyType(policy networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+// peersMatch reports whether one of the peers matches the pod, an empty list of peers matches all pods.
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, pod Pod, policyNamespace string) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peerMatches(peer, pod, policyNamespace) {
+			return true
+		}
+	}
+	return false
+}
+
+func peerMatches(peer networkingv1.NetworkPolicyPeer, pod Pod, policyNamespace string) bool {
+	if peer.IPBlock != nil || (peer.PodSelector == nil && peer.NamespaceSelector == nil) {
+		return false
+	}
+	if peer.NamespaceSelector == nil {
+		if pod.Namespace != policyNamespace {
+			return false
+		}
+	} else {
+		namespaceLabels := map[string]string{"kubernetes.io/metadata.name": pod.Namespace}
+		for key, value := range pod.NamespaceLabels {
+			namespaceLabels[key] = value
+		}
+		if !selectorMatches(peer.NamespaceSelector, namespaceLabels) {
+			return false
+		}
+	}
+	return peer.PodSelector == nil || selectorMatches(peer.PodSelector, pod.Labels)
+}
+
+// selectorMatches reports whether the selector matches the labels, invalid selectors match nothing since Kubernetes
+// rejects them.
+func selectorMatches(selector *metav1.LabelSelector, podLabels map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(podLabels))
+}
+
+// portsMatch reports whether one of the ports allows the TCP port of the pod, an empty list of ports allows all ports.
+// Named ports are resolved with the container ports of the pod.
+func portsMatch(ports []networkingv1.NetworkPolicyPort, pod Pod, port int32) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		if p.Protocol != nil && *p.Protocol != corev1.ProtocolTCP {
+			continue
+		}
+		if p.Port == nil {
+			return true
+		}
+		if p.Port.Type == intstr.String {
+			for _, containerPort := range pod.Ports {
+				if containerPort.Name == p.Port.StrVal && containerPort.ContainerPort == port &&
+					(containerPort.Protocol == "" || containerPort.Protocol == corev1.ProtocolTCP) {
+					return true
+				}
+			}
+			continue
+		}
+		if port == p.Port.IntVal || (p.EndPort != nil && port >= p.Port.IntVal && port <= *p.EndPort) {
+			return true
+		}
+	}
+	return false
+}
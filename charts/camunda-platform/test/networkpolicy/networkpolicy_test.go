@@ -0,0 +1,275 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// parseModel parses the manifests into the namespace "camunda" and returns the model with the pods by name.
+func parseModel(t *testing.T, manifests string) (*Model, map[string]Pod) {
+	parsed, err := ParseManifests(manifests, "camunda")
+	require.NoError(t, err)
+	pods := map[string]Pod{}
+	for _, pod := range parsed.Pods {
+		pods[pod.Name] = pod
+	}
+	return parsed.Model(), pods
+}
+
+const workloads = `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: broker
+spec:
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/component: zeebe-broker
+    spec:
+      containers:
+        - name: zeebe
+          ports:
+            - containerPort: 9600
+              name: http
+            - containerPort: 26501
+              name: command
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: gateway
+spec:
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/component: zeebe-gateway
+    spec:
+      containers:
+        - name: zeebe-gateway
+          ports:
+            - containerPort: 26500
+              name: gateway
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: curator
+  namespace: jobs
+spec:
+  jobTemplate:
+    spec:
+      template:
+        metadata:
+          labels:
+            app.kubernetes.io/component: curator
+        spec:
+          containers:
+            - name: curator
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: broker
+`
+
+func TestParseManifestsShouldReadPodTemplatesOfWorkloads(t *testing.T) {
+	t.Parallel()
+
+	// when
+	parsed, err := ParseManifests(workloads, "camunda")
+
+	// then
+	require.NoError(t, err)
+	require.Empty(t, parsed.Policies)
+	require.Equal(t, []Pod{
+		{
+			Name:      "broker",
+			Namespace: "camunda",
+			Labels:    map[string]string{"app.kubernetes.io/component": "zeebe-broker"},
+			Ports:     []corev1.ContainerPort{{Name: "http", ContainerPort: 9600}, {Name: "command", ContainerPort: 26501}},
+		},
+		{
+			Name:      "gateway",
+			Namespace: "camunda",
+			Labels:    map[string]string{"app.kubernetes.io/component": "zeebe-gateway"},
+			Ports:     []corev1.ContainerPort{{Name: "gateway", ContainerPort: 26500}},
+		},
+		{
+			Name:      "curator",
+			Namespace: "jobs",
+			Labels:    map[string]string{"app.kubernetes.io/component": "curator"},
+		},
+	}, parsed.Pods)
+}
+
+func TestCanReachShouldAllowEverythingWithoutPolicies(t *testing.T) {
+	t.Parallel()
+
+	// given
+	model, pods := parseModel(t, workloads)
+
+	// then
+	require.True(t, model.CanReach(pods["gateway"], pods["broker"], 26501))
+	require.True(t, model.CanReach(pods["curator"], pods["broker"], 12345))
+}
+
+func TestCanReachShouldOnlyAllowMatchingPeersAndPortsOfIsolatedPods(t *testing.T) {
+	t.Parallel()
+
+	// given
+	model, pods := parseModel(t, workloads+`
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: broker
+spec:
+  podSelector:
+    matchLabels:
+      app.kubernetes.io/component: zeebe-broker
+  policyTypes:
+    - Ingress
+  ingress:
+    - from:
+        - podSelector:
+            matchLabels:
+              app.kubernetes.io/component: zeebe-gateway
+      ports:
+        - port: command
+    - ports:
+        - port: 9600
+          protocol: TCP
+`)
+
+	// then
+	require.True(t, model.CanReach(pods["gateway"], pods["broker"], 26501), "gateway to named command port")
+	require.False(t, model.CanReach(pods["broker"], pods["broker"], 26501), "broker isn't a peer")
+	require.False(t, model.CanReach(pods["gateway"], pods["broker"], 26502), "port isn't allowed")
+	require.True(t, model.CanReach(pods["curator"], pods["broker"], 9600), "rule without peers")
+	require.True(t, model.CanReach(pods["broker"], pods["gateway"], 26500), "gateway isn't isolated")
+}
+
+func TestCanReachShouldMatchPeersByNamespace(t *testing.T) {
+	t.Parallel()
+
+	// given
+	model, pods := parseModel(t, workloads+`
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: gateway
+spec:
+  podSelector: {}
+  ingress:
+    - from:
+        - namespaceSelector:
+            matchLabels:
+              purpose: monitoring
+          podSelector:
+            matchLabels:
+              app: prometheus
+        - namespaceSelector:
+            matchLabels:
+              kubernetes.io/metadata.name: workers
+        - podSelector:
+            matchLabels:
+              app.kubernetes.io/component: curator
+`)
+	prometheus := Pod{Namespace: "observability", NamespaceLabels: map[string]string{"purpose": "monitoring"}, Labels: map[string]string{"app": "prometheus"}}
+	grafana := Pod{Namespace: "observability", NamespaceLabels: map[string]string{"purpose": "monitoring"}, Labels: map[string]string{"app": "grafana"}}
+	worker := Pod{Namespace: "workers"}
+	curatorInNamespace := Pod{Namespace: "camunda", Labels: pods["curator"].Labels}
+
+	// then
+	require.True(t, model.CanReach(prometheus, pods["gateway"], 26500))
+	require.False(t, model.CanReach(grafana, pods["gateway"], 26500))
+	require.True(t, model.CanReach(worker, pods["gateway"], 26500))
+	require.True(t, model.CanReach(curatorInNamespace, pods["gateway"], 26500))
+	require.False(t, model.CanReach(pods["curator"], pods["gateway"], 26500), "pod selectors only match the namespace of the policy")
+}
+
+func TestCanReachShouldCombinePoliciesAndPortRanges(t *testing.T) {
+	t.Parallel()
+
+	// given
+	model, pods := parseModel(t, workloads+`
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+spec:
+  podSelector: {}
+  policyTypes:
+    - Ingress
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: gateway
+spec:
+  podSelector:
+    matchLabels:
+      app.kubernetes.io/component: zeebe-gateway
+  ingress:
+    - ports:
+        - port: 26500
+          endPort: 26502
+        - port: 26500
+          protocol: UDP
+`)
+
+	// then
+	require.True(t, model.CanReach(pods["broker"], pods["gateway"], 26502))
+	require.False(t, model.CanReach(pods["broker"], pods["gateway"], 26503))
+	require.False(t, model.CanReach(pods["gateway"], pods["broker"], 26501), "denied by the empty policy")
+}
+
+func TestCanReachShouldRequireEgressOfTheSource(t *testing.T) {
+	t.Parallel()
+
+	// given
+	model, pods := parseModel(t, workloads+`
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: gateway
+spec:
+  podSelector:
+    matchLabels:
+      app.kubernetes.io/component: zeebe-gateway
+  policyTypes:
+    - Egress
+  egress:
+    - to:
+        - podSelector:
+            matchLabels:
+              app.kubernetes.io/component: zeebe-broker
+      ports:
+        - port: command
+`)
+
+	// then
+	require.True(t, model.CanReach(pods["gateway"], pods["broker"], 26501))
+	require.False(t, model.CanReach(pods["gateway"], pods["broker"], 9600))
+	require.True(t, model.CanReach(pods["broker"], pods["gateway"], 26500), "egress policies don't isolate ingress")
+}
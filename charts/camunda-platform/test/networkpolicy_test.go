@@ -0,0 +1,341 @@
+package test
+
+import (
+	"camunda-platform-helm/charts/camunda-platform/test/golden"
+	"camunda-platform-helm/charts/camunda-platform/test/networkpolicy"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGoldenNetworkPolicyDefaults(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	suite.Run(t, &golden.TemplateGoldenTest{
+		ChartPath:      chartPath,
+		Release:        "camunda-platform-test",
+		Namespace:      "camunda-platform-" + strings.ToLower(random.UniqueId()),
+		GoldenFileName: "network-policy",
+		Templates:      []string{"templates/network-policy.yaml"},
+		SetValues:      map[string]string{"global.networkPolicy.enabled": "true"},
+	})
+}
+
+const networkPolicyNamespace = "camunda"
+
+// restrictedPeerValues restrict the open ports to the ingress controller, Prometheus and the job workers of
+// peerPods.
+var restrictedPeerValues = map[string]string{
+	"global.networkPolicy.ingressFrom[0].namespaceSelector.matchLabels.purpose":      "ingress",
+	"global.networkPolicy.metricsFrom[0].namespaceSelector.matchLabels.purpose":      "monitoring",
+	"global.networkPolicy.metricsFrom[0].podSelector.matchLabels.app":                "prometheus",
+	"global.networkPolicy.zeebeClientsFrom[0].namespaceSelector.matchLabels.purpose": "workers",
+}
+
+// peerPods are pods outside of the release, which are named by their role.
+var peerPods = []networkpolicy.Pod{
+	{Name: "ingress-controller", Namespace: "ingress-nginx", NamespaceLabels: map[string]string{"purpose": "ingress"}},
+	{Name: "prometheus", Namespace: "monitoring", NamespaceLabels: map[string]string{"purpose": "monitoring"}, Labels: map[string]string{"app": "prometheus"}},
+	{Name: "grafana", Namespace: "monitoring", NamespaceLabels: map[string]string{"purpose": "monitoring"}, Labels: map[string]string{"app": "grafana"}},
+	{Name: "worker", Namespace: "workers", NamespaceLabels: map[string]string{"purpose": "workers"}},
+	{Name: "stranger", Namespace: networkPolicyNamespace},
+	{Name: "other-release", Namespace: networkPolicyNamespace, Labels: map[string]string{
+		"app.kubernetes.io/instance":  "other",
+		"app.kubernetes.io/component": "zeebe-broker",
+	}},
+}
+
+// dependencyPods returns the pods of the dependency charts, which are named by their role. They are added
+// independently of the rendered dependencies, since their labels and ports are given by the dependency charts.
+func dependencyPods(dependencies []string) []networkpolicy.Pod {
+	all := map[string]networkpolicy.Pod{
+		"elasticsearch": {Labels: map[string]string{"app": "elasticsearch-master", "release": "camunda-platform-test"},
+			Ports: tcpPorts(9200, 9300)},
+		"keycloak": {Labels: map[string]string{"app.kubernetes.io/name": "keycloak", "app.kubernetes.io/instance": "camunda-platform-test"},
+			Ports: tcpPorts(8080, 8443, 7600)},
+		"postgresql": {Labels: map[string]string{"app.kubernetes.io/name": "postgresql", "app.kubernetes.io/instance": "camunda-platform-test"},
+			Ports: tcpPorts(5432)},
+		"postgresql-web-modeler": {Labels: map[string]string{"app.kubernetes.io/name": "postgresql-web-modeler", "app.kubernetes.io/instance": "camunda-platform-test"},
+			Ports: tcpPorts(5432)},
+	}
+	var pods []networkpolicy.Pod
+	for _, dependency := range dependencies {
+		pod := all[dependency]
+		pod.Name = dependency
+		pod.Namespace = networkPolicyNamespace
+		pods = append(pods, pod)
+	}
+	return pods
+}
+
+func tcpPorts(ports ...int32) []corev1.ContainerPort {
+	var containerPorts []corev1.ContainerPort
+	for _, port := range ports {
+		containerPorts = append(containerPorts, corev1.ContainerPort{ContainerPort: port, Protocol: corev1.ProtocolTCP})
+	}
+	return containerPorts
+}
+
+// renderNetworkPolicies renders the whole chart and returns the model of its NetworkPolicies with the pods of the
+// release by their role, which is their component label. The pods of the "helm test" hooks have the role of their
+// component.
+func renderNetworkPolicies(t *testing.T, values map[string]string) (*networkpolicy.Model, []networkpolicy.Pod) {
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	options := &helm.Options{
+		SetValues:      values,
+		KubectlOptions: k8s.NewKubectlOptions("", "", networkPolicyNamespace),
+	}
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", nil)
+
+	manifests, err := networkpolicy.ParseManifests(output, networkPolicyNamespace)
+	require.NoError(t, err)
+	var pods []networkpolicy.Pod
+	for _, pod := range manifests.Pods {
+		// Pods of the dependency charts are added by dependencyPods.
+		if pod.Labels["app.kubernetes.io/part-of"] != "camunda-platform" {
+			continue
+		}
+		pod.Name = pod.Labels["app.kubernetes.io/component"]
+		pods = append(pods, pod)
+	}
+	return manifests.Model(), pods
+}
+
+// flow is a TCP connection between the pods of two roles.
+type flow struct {
+	from string
+	to   string
+	port int32
+}
+
+// expectedFlows returns the flows between the roles, which the NetworkPolicies have to allow. The web, metrics and
+// Zeebe client ports are open to every role, unless their peers are restricted.
+func expectedFlows(roles []string, restricted bool) map[flow]bool {
+	present := map[string]bool{}
+	for _, role := range roles {
+		present[role] = true
+	}
+	flows := map[flow]bool{}
+	allow := func(from []string, to string, ports ...int32) {
+		for _, source := range from {
+			for _, port := range ports {
+				if present[source] && present[to] {
+					flows[flow{source, to, port}] = true
+				}
+			}
+		}
+	}
+	open := func(peer string, from []string, to string, ports ...int32) {
+		if restricted {
+			allow(append([]string{peer, to}, from...), to, ports...)
+		} else {
+			allow(roles, to, ports...)
+		}
+	}
+	// The pods with the instance label of the release.
+	var release []string
+	for _, role := range roles {
+		switch role {
+		case "elasticsearch", "ingress-controller", "prometheus", "grafana", "worker", "stranger", "other-release":
+		default:
+			release = append(release, role)
+		}
+	}
+
+	allow([]string{"zeebe-broker", "zeebe-gateway"}, "zeebe-broker", 26501, 26502)
+	open("prometheus", nil, "zeebe-broker", 9600)
+	open("worker", []string{"zeebe-broker", "operate", "tasklist", "connectors", "restapi"}, "zeebe-gateway", 26500)
+	allow([]string{"zeebe-broker", "zeebe-gateway"}, "zeebe-gateway", 26502)
+	open("prometheus", []string{"backup"}, "zeebe-gateway", 9600)
+	open("ingress-controller", []string{"connectors"}, "operate", 8080)
+	open("prometheus", []string{"backup"}, "operate", 8080)
+	open("ingress-controller", nil, "tasklist", 8080)
+	open("prometheus", []string{"backup"}, "tasklist", 8080)
+	open("ingress-controller", nil, "optimize", 8090)
+	open("prometheus", []string{"backup"}, "optimize", 8092)
+	open("ingress-controller", release, "identity", 8080)
+	open("prometheus", nil, "identity", 8082)
+	open("ingress-controller", release, "keycloak", 8080, 8443)
+	allow([]string{"keycloak"}, "keycloak", 8080, 8443, 7600)
+	allow([]string{"keycloak"}, "postgresql", 5432)
+	open("ingress-controller", nil, "connectors", 8080)
+	open("prometheus", nil, "connectors", 8080)
+	allow([]string{"webapp"}, "restapi", 8081)
+	open("prometheus", []string{"webapp", "websockets", "web-modeler"}, "restapi", 8091)
+	open("ingress-controller", nil, "webapp", 8070)
+	open("prometheus", nil, "webapp", 8071)
+	open("ingress-controller", []string{"restapi", "webapp"}, "websockets", 8060)
+	allow([]string{"restapi"}, "postgresql-web-modeler", 5432)
+	allow([]string{"zeebe-broker", "operate", "tasklist", "optimize", "curator", "retention-setup", "backup"}, "elasticsearch", 9200)
+	allow([]string{"elasticsearch"}, "elasticsearch", 9300)
+	return flows
+}
+
+// requireExactFlows checks every pod can reach exactly the expected container ports of the other pods.
+func requireExactFlows(t *testing.T, model *networkpolicy.Model, pods []networkpolicy.Pod, expected map[flow]bool) {
+	var roles []string
+	for _, pod := range pods {
+		roles = append(roles, pod.Name)
+	}
+	checked := map[flow]bool{}
+	for _, from := range pods {
+		for _, to := range pods {
+			for _, port := range to.Ports {
+				f := flow{from.Name, to.Name, port.ContainerPort}
+				require.Equal(t, expected[f], model.CanReach(from, to, port.ContainerPort), "%+v", f)
+				checked[f] = true
+			}
+		}
+	}
+	for f := range expected {
+		require.True(t, checked[f], "expected flow %+v to a port, which no pod of %v has", f, roles)
+	}
+}
+
+func TestNetworkPoliciesShouldNotBeRenderedByDefault(t *testing.T) {
+	t.Parallel()
+
+	// given
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	options := &helm.Options{KubectlOptions: k8s.NewKubectlOptions("", "", networkPolicyNamespace)}
+
+	// when
+	_, err = helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", []string{"templates/network-policy.yaml"})
+
+	// then
+	require.ErrorContains(t, err, "could not find template")
+}
+
+func TestNetworkPoliciesShouldOnlyAllowTheFlowsOfTheComponents(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		name         string
+		values       map[string]string
+		dependencies []string
+	}{
+		{
+			name:         "default components",
+			dependencies: []string{"elasticsearch", "keycloak", "postgresql"},
+		},
+		{
+			name: "all components",
+			values: map[string]string{
+				"connectors.enabled":                   "true",
+				"web-modeler.enabled":                  "true",
+				"web-modeler.restapi.mail.fromAddress": "modeler@example.com",
+				"retentionPolicy.enabled":              "true",
+				"identity.keycloak.postgresql.enabled": "false",
+			},
+			dependencies: []string{"elasticsearch", "keycloak", "postgresql-web-modeler"},
+		},
+		{
+			name: "retention policy with index lifecycle policies",
+			values: map[string]string{
+				"retentionPolicy.enabled": "true",
+				"retentionPolicy.mode":    "ilm",
+			},
+			dependencies: []string{"elasticsearch", "keycloak", "postgresql"},
+		},
+		{
+			name: "backup cronjob",
+			values: map[string]string{
+				"backup.enabled":               "true",
+				"zeebe.backup.store":           "S3",
+				"zeebe.backup.s3.bucketName":   "camunda-backups",
+				"global.backup.repositoryName": "camunda-backups",
+			},
+			dependencies: []string{"elasticsearch", "keycloak", "postgresql"},
+		},
+		{
+			name: "zeebe without web apps",
+			values: map[string]string{
+				"operate.enabled":              "false",
+				"tasklist.enabled":             "false",
+				"optimize.enabled":             "false",
+				"identity.enabled":             "false",
+				"global.identity.auth.enabled": "false",
+			},
+			dependencies: []string{"elasticsearch"},
+		},
+	} {
+		testCase := testCase
+		for _, restricted := range []bool{false, true} {
+			restricted := restricted
+			name := testCase.name
+			if restricted {
+				name += " with restricted peers"
+			}
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				// given
+				values := map[string]string{"global.networkPolicy.enabled": "true"}
+				for key, value := range testCase.values {
+					values[key] = value
+				}
+				if restricted {
+					for key, value := range restrictedPeerValues {
+						values[key] = value
+					}
+				}
+
+				// when
+				model, pods := renderNetworkPolicies(t, values)
+
+				// then
+				pods = append(pods, dependencyPods(testCase.dependencies)...)
+				pods = append(pods, peerPods...)
+				var roles []string
+				for _, pod := range pods {
+					roles = append(roles, pod.Name)
+				}
+				requireExactFlows(t, model, pods, expectedFlows(roles, restricted))
+			})
+		}
+	}
+}
+
+func TestNetworkPoliciesShouldFollowConfiguredPorts(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"global.networkPolicy.enabled":       "true",
+		"zeebe.service.commandPort":          "36501",
+		"zeebe-gateway.service.gatewayPort":  "36500",
+		"zeebe-gateway.service.internalPort": "36502",
+		"zeebe.service.internalPort":         "36502",
+		"connectors.enabled":                 "true",
+		"connectors.service.serverPort":      "9090",
+	}
+
+	// when
+	model, pods := renderNetworkPolicies(t, values)
+
+	// then
+	byRole := map[string]networkpolicy.Pod{}
+	for _, pod := range pods {
+		byRole[pod.Name] = pod
+	}
+	gateway, broker, connectors := byRole["zeebe-gateway"], byRole["zeebe-broker"], byRole["connectors"]
+	stranger := networkpolicy.Pod{Name: "stranger", Namespace: networkPolicyNamespace}
+	require.True(t, model.CanReach(gateway, broker, 36501))
+	require.True(t, model.CanReach(broker, gateway, 36502))
+	require.True(t, model.CanReach(connectors, gateway, 36500))
+	require.True(t, model.CanReach(stranger, connectors, 9090))
+	require.False(t, model.CanReach(stranger, broker, 36501))
+	require.False(t, model.CanReach(gateway, broker, 26501))
+}
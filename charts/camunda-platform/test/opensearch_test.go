@@ -0,0 +1,119 @@
+package test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpensearchConstraints(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		name     string
+		values   map[string]string
+		messages []string
+	}{
+		{
+			name: "elasticsearch and retention policy enabled",
+			values: map[string]string{
+				"global.opensearch.enabled": "true",
+				"global.opensearch.host":    "search.example.com",
+				"retentionPolicy.enabled":   "true",
+			},
+			messages: []string{
+				`[camunda][constraint] The Elasticsearch dependency can't be enabled together with "global.opensearch.enabled".`,
+				`[camunda][constraint] The retention policy can't be enabled together with "global.opensearch.enabled",`,
+			},
+		},
+		{
+			name: "index lifecycle retention policy with AWS request signing",
+			values: map[string]string{
+				"elasticsearch.enabled":         "false",
+				"global.opensearch.enabled":     "true",
+				"global.opensearch.host":        "search.example.com",
+				"global.opensearch.aws.enabled": "true",
+				"retentionPolicy.enabled":       "true",
+				"retentionPolicy.mode":          "ilm",
+			},
+			messages: []string{
+				`[camunda][constraint] The retention policy can't be enabled together with "global.opensearch.aws.enabled",`,
+			},
+		},
+		{
+			name: "missing host and password secret",
+			values: map[string]string{
+				"elasticsearch.enabled":           "false",
+				"global.opensearch.enabled":       "true",
+				"global.opensearch.auth.username": "camunda",
+			},
+			messages: []string{
+				`[camunda][constraint] The var "global.opensearch.host" or "global.opensearch.url" has to be set to use OpenSearch.`,
+				`[camunda][constraint] The var "global.opensearch.auth.existingSecret" has to be set together with`,
+			},
+		},
+		{
+			name: "url without host for Optimize",
+			values: map[string]string{
+				"elasticsearch.enabled":     "false",
+				"global.opensearch.enabled": "true",
+				"global.opensearch.url":     "https://search.example.com",
+			},
+			messages: []string{
+				`[camunda][constraint] The var "global.opensearch.host" has to be set when Optimize is enabled,`,
+			},
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// given
+			options := &helm.Options{
+				SetValues:      testCase.values,
+				KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+			}
+
+			// when
+			_, err := helm.RenderTemplateE(t, options, chartPath, "camunda-platform-test", nil)
+
+			// then
+			require.Error(t, err)
+			for _, message := range testCase.messages {
+				require.ErrorContains(t, err, message)
+			}
+		})
+	}
+}
+
+func TestOpensearchShouldNotCheckIndexTemplatesInTestHooks(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"elasticsearch.enabled":     "false",
+		"global.opensearch.enabled": "true",
+		"global.opensearch.host":    "search.example.com",
+	}
+
+	// when
+	hooks := renderTestHooks(t, values)
+
+	// then
+	require.ElementsMatch(t, []string{"zeebe-broker", "zeebe-gateway", "operate", "tasklist", "optimize", "identity"}, hookComponents(hooks))
+	for component, pod := range hooks {
+		for _, arg := range testRunnerArgs(t, pod) {
+			require.NotContains(t, arg, "-elasticsearch=", component)
+			require.NotContains(t, arg, "-index-template=", component)
+		}
+	}
+	require.Contains(t, testRunnerArgs(t, hooks["zeebe-broker"]), "-zeebe-brokers=3")
+}
@@ -71,3 +71,66 @@ func (s *configMapTemplateTest) TestConfigMapElasticsearchURL() {
 	// then
 	s.Require().Equal("elasticsearch-master-test", elasticsearchURL)
 }
+
+func (s *configMapTemplateTest) TestConfigMapElasticsearchByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var configmap corev1.ConfigMap
+	var configmapApplication map[string]interface{}
+	helm.UnmarshalK8SYaml(s.T(), output, &configmap)
+	helm.UnmarshalK8SYaml(s.T(), configmap.Data["application.yml"], &configmapApplication)
+
+	// then
+	operateConfig := configmapApplication["camunda.operate"].(map[string]interface{})
+	s.Require().NotContains(operateConfig, "database")
+	s.Require().NotContains(operateConfig, "opensearch")
+	s.Require().NotContains(operateConfig, "zeebeOpensearch")
+	s.Require().Equal("elasticsearch-master", operateConfig["elasticsearch"].(map[string]interface{})["host"])
+	s.Require().Equal("zeebe-record", operateConfig["zeebeElasticsearch"].(map[string]interface{})["prefix"])
+}
+
+func (s *configMapTemplateTest) TestConfigMapOpensearch() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"elasticsearch.enabled":                 "false",
+			"global.opensearch.enabled":             "true",
+			"global.opensearch.host":                "search.example.com",
+			"global.opensearch.prefix":              "camunda-record",
+			"global.opensearch.auth.username":       "camunda",
+			"global.opensearch.auth.existingSecret": "opensearch-credentials",
+			"global.opensearch.aws.enabled":         "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var configmap corev1.ConfigMap
+	var configmapApplication map[string]interface{}
+	helm.UnmarshalK8SYaml(s.T(), output, &configmap)
+	helm.UnmarshalK8SYaml(s.T(), configmap.Data["application.yml"], &configmapApplication)
+
+	// then
+	operateConfig := configmapApplication["camunda.operate"].(map[string]interface{})
+	s.Require().Equal("opensearch", operateConfig["database"])
+	s.Require().NotContains(operateConfig, "elasticsearch")
+	s.Require().NotContains(operateConfig, "zeebeElasticsearch")
+	s.Require().Equal(map[string]interface{}{
+		"url":        "https://search.example.com:443",
+		"username":   "camunda",
+		"awsEnabled": true,
+	}, operateConfig["opensearch"])
+	s.Require().Equal(map[string]interface{}{
+		"url":        "https://search.example.com:443",
+		"prefix":     "camunda-record",
+		"username":   "camunda",
+		"awsEnabled": true,
+	}, operateConfig["zeebeOpensearch"])
+	s.Require().Contains(operateConfig, "zeebe")
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type deploymentTemplateTest struct {
@@ -754,3 +755,111 @@ func (s *deploymentTemplateTest) TestContainerLivenessProbe() {
 	s.Require().EqualValues(5, probe.FailureThreshold)
 	s.Require().EqualValues(1, probe.TimeoutSeconds)
 }
+
+func (s *deploymentTemplateTest) TestContainerShouldSetOpensearchCredentials() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"elasticsearch.enabled":                    "false",
+			"global.opensearch.enabled":                "true",
+			"global.opensearch.host":                   "search.example.com",
+			"global.opensearch.auth.username":          "camunda",
+			"global.opensearch.auth.existingSecret":    "opensearch-credentials",
+			"global.opensearch.auth.existingSecretKey": "opensearch-password",
+			"global.opensearch.aws.enabled":            "true",
+			"global.opensearch.aws.region":             "eu-west-1",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	env := deployment.Spec.Template.Spec.Containers[0].Env
+	for _, name := range []string{"CAMUNDA_OPERATE_OPENSEARCH_PASSWORD", "CAMUNDA_OPERATE_ZEEBEOPENSEARCH_PASSWORD"} {
+		s.Require().Contains(env,
+			corev1.EnvVar{
+				Name: name,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "opensearch-credentials"},
+						Key:                  "opensearch-password",
+					},
+				},
+			})
+	}
+	s.Require().Contains(env, corev1.EnvVar{Name: "AWS_REGION", Value: "eu-west-1"})
+}
+
+func (s *deploymentTemplateTest) TestContainerShouldNotSetOpensearchCredentialsByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	for _, envVar := range deployment.Spec.Template.Spec.Containers[0].Env {
+		s.Require().NotContains(envVar.Name, "OPENSEARCH")
+		s.Require().NotEqual("AWS_REGION", envVar.Name)
+	}
+}
+
+// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+func (s *deploymentTemplateTest) TestContainerSetTopologySpreadConstraints() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"operate.topologySpreadConstraints[0].maxSkew":           "2",
+			"operate.topologySpreadConstraints[0].topologyKey":       "kubernetes.io/hostname",
+			"operate.topologySpreadConstraints[0].whenUnsatisfiable": "ScheduleAnyway",
+			"operate.zoneAwareness.enabled":                          "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Equal([]corev1.TopologySpreadConstraint{
+		{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.ScheduleAnyway},
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				"app":                          "camunda-platform",
+				"app.kubernetes.io/name":       "operate",
+				"app.kubernetes.io/instance":   "camunda-platform-test",
+				"app.kubernetes.io/managed-by": "Helm",
+				"app.kubernetes.io/part-of":    "camunda-platform",
+				"app.kubernetes.io/component":  "operate",
+			}},
+		},
+	}, deployment.Spec.Template.Spec.TopologySpreadConstraints)
+}
+
+func (s *deploymentTemplateTest) TestContainerShouldNotSetTopologySpreadConstraintsByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Empty(deployment.Spec.Template.Spec.TopologySpreadConstraints)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type deploymentTemplateTest struct {
@@ -728,3 +729,127 @@ func (s *deploymentTemplateTest) TestContainerLivenessProbe() {
 	s.Require().EqualValues(5, probe.FailureThreshold)
 	s.Require().EqualValues(1, probe.TimeoutSeconds)
 }
+
+func (s *deploymentTemplateTest) TestContainerShouldSetElasticsearchByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	env := deployment.Spec.Template.Spec.Containers[0].Env
+	s.Require().Contains(env, corev1.EnvVar{Name: "OPTIMIZE_ELASTICSEARCH_HOST", Value: "elasticsearch-master"})
+	s.Require().Contains(env, corev1.EnvVar{Name: "OPTIMIZE_ELASTICSEARCH_HTTP_PORT", Value: "9200"})
+	for _, envVar := range env {
+		s.Require().NotContains(envVar.Name, "OPENSEARCH")
+		s.Require().NotEqual("CAMUNDA_OPTIMIZE_DATABASE", envVar.Name)
+	}
+}
+
+func (s *deploymentTemplateTest) TestContainerShouldSetOpensearch() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"elasticsearch.enabled":                 "false",
+			"global.opensearch.enabled":             "true",
+			"global.opensearch.host":                "search.example.com",
+			"global.opensearch.port":                "9200",
+			"global.opensearch.protocol":            "http",
+			"global.opensearch.prefix":              "camunda-record",
+			"global.opensearch.auth.username":       "camunda",
+			"global.opensearch.auth.existingSecret": "opensearch-credentials",
+			"global.opensearch.aws.enabled":         "true",
+			"global.opensearch.aws.region":          "eu-west-1",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	env := deployment.Spec.Template.Spec.Containers[0].Env
+	for _, expected := range []corev1.EnvVar{
+		{Name: "CAMUNDA_OPTIMIZE_DATABASE", Value: "opensearch"},
+		{Name: "CAMUNDA_OPTIMIZE_OPENSEARCH_HOST", Value: "search.example.com"},
+		{Name: "CAMUNDA_OPTIMIZE_OPENSEARCH_HTTP_PORT", Value: "9200"},
+		{Name: "CAMUNDA_OPTIMIZE_OPENSEARCH_SECURITY_SSL_ENABLED", Value: "false"},
+		{Name: "CAMUNDA_OPTIMIZE_ZEEBE_NAME", Value: "camunda-record"},
+		{Name: "CAMUNDA_OPTIMIZE_OPENSEARCH_SECURITY_USERNAME", Value: "camunda"},
+		{Name: "CAMUNDA_OPTIMIZE_OPENSEARCH_AWS_ENABLED", Value: "true"},
+		{Name: "AWS_REGION", Value: "eu-west-1"},
+		{
+			Name: "CAMUNDA_OPTIMIZE_OPENSEARCH_SECURITY_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "opensearch-credentials"},
+					Key:                  "password",
+				},
+			},
+		},
+	} {
+		s.Require().Contains(env, expected)
+	}
+	for _, envVar := range env {
+		s.Require().NotContains(envVar.Name, "ELASTICSEARCH")
+	}
+}
+
+// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+func (s *deploymentTemplateTest) TestContainerSetTopologySpreadConstraints() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"optimize.topologySpreadConstraints[0].maxSkew":           "2",
+			"optimize.topologySpreadConstraints[0].topologyKey":       "kubernetes.io/hostname",
+			"optimize.topologySpreadConstraints[0].whenUnsatisfiable": "ScheduleAnyway",
+			"optimize.zoneAwareness.enabled":                          "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Equal([]corev1.TopologySpreadConstraint{
+		{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.ScheduleAnyway},
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				"app":                          "camunda-platform",
+				"app.kubernetes.io/name":       "optimize",
+				"app.kubernetes.io/instance":   "camunda-platform-test",
+				"app.kubernetes.io/managed-by": "Helm",
+				"app.kubernetes.io/part-of":    "camunda-platform",
+				"app.kubernetes.io/component":  "optimize",
+			}},
+		},
+	}, deployment.Spec.Template.Spec.TopologySpreadConstraints)
+}
+
+func (s *deploymentTemplateTest) TestContainerShouldNotSetTopologySpreadConstraintsByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Empty(deployment.Spec.Template.Spec.TopologySpreadConstraints)
+}
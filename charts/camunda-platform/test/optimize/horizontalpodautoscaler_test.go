@@ -0,0 +1,110 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package optimize
+
+import (
+	"camunda-platform-helm/charts/camunda-platform/test/golden"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// autoscalingEnv are the string values of the environment, which disable the import of every replica.
+func autoscalingEnv() map[string]string {
+	return map[string]string{
+		"optimize.env[0].name":  "CAMUNDA_OPTIMIZE_ZEEBE_ENABLED",
+		"optimize.env[0].value": "false",
+	}
+}
+
+func TestGoldenHorizontalPodAutoscalerDefaults(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../../")
+	require.NoError(t, err)
+
+	suite.Run(t, &golden.TemplateGoldenTest{
+		ChartPath:      chartPath,
+		Release:        "camunda-platform-test",
+		Namespace:      "camunda-platform-" + strings.ToLower(random.UniqueId()),
+		GoldenFileName: "horizontalpodautoscaler",
+		Templates:      []string{"charts/optimize/templates/horizontalpodautoscaler.yaml"},
+		SetValues:      map[string]string{"optimize.autoscaling.enabled": "true"},
+		SetStrValues:   autoscalingEnv(),
+	})
+}
+
+type horizontalPodAutoscalerTest struct {
+	suite.Suite
+	chartPath string
+	release   string
+	namespace string
+}
+
+func TestHorizontalPodAutoscalerTemplate(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../../")
+	require.NoError(t, err)
+
+	suite.Run(t, &horizontalPodAutoscalerTest{
+		chartPath: chartPath,
+		release:   "camunda-platform-test",
+		namespace: "camunda-platform-" + strings.ToLower(random.UniqueId()),
+	})
+}
+
+func (s *horizontalPodAutoscalerTest) TestContainerShouldRequireDisabledImportWhenAutoscaled() {
+	// given
+	options := &helm.Options{
+		SetValues:      map[string]string{"optimize.autoscaling.enabled": "true"},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	_, err := helm.RenderTemplateE(s.T(), options, s.chartPath, s.release, []string{"charts/optimize/templates/horizontalpodautoscaler.yaml"})
+
+	// then
+	s.Require().ErrorContains(err, `[camunda][constraint] The var "optimize.autoscaling.enabled" requires "CAMUNDA_OPTIMIZE_ZEEBE_ENABLED" set to "false" in "optimize.env",`)
+}
+
+func (s *horizontalPodAutoscalerTest) TestContainerShouldLeaveReplicasToAutoscaler() {
+	// given
+	options := &helm.Options{
+		SetValues:      map[string]string{"optimize.autoscaling.enabled": "true"},
+		SetStrValues:   autoscalingEnv(),
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, []string{"charts/optimize/templates/deployment.yaml"})
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Nil(deployment.Spec.Replicas)
+	for _, variable := range deployment.Spec.Template.Spec.Containers[0].Env {
+		if variable.Name == "CAMUNDA_OPTIMIZE_ZEEBE_ENABLED" {
+			s.Require().Equal("false", variable.Value)
+		}
+	}
+}
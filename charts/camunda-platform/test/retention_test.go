@@ -0,0 +1,297 @@
+package test
+
+import (
+	"camunda-platform-helm/charts/camunda-platform/test/golden"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGoldenRetentionSetupDefaults(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	templateNames := []string{"retention-setup-configmap", "retention-setup-job"}
+
+	for _, name := range templateNames {
+		suite.Run(t, &golden.TemplateGoldenTest{
+			ChartPath:      chartPath,
+			Release:        "camunda-platform-test",
+			Namespace:      "camunda-platform-" + strings.ToLower(random.UniqueId()),
+			GoldenFileName: name,
+			Templates:      []string{"templates/" + name + ".yaml"},
+			SetValues:      map[string]string{"retentionPolicy.enabled": "true", "retentionPolicy.mode": "ilm"},
+		})
+	}
+}
+
+func TestCuratorShouldFilterByConfiguredPrefixes(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"retentionPolicy.enabled":           "true",
+			"retentionPolicy.zeebeIndexMaxSize": "10",
+			"global.elasticsearch.prefix":       "custom-record",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+	}
+
+	// when
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", []string{"templates/curator-configmap.yaml"})
+	var configMap corev1.ConfigMap
+	helm.UnmarshalK8SYaml(t, output, &configMap)
+
+	// then
+	var actionFile struct {
+		Actions map[int]struct {
+			Filters []map[string]interface{} `json:"filters"`
+		} `json:"actions"`
+	}
+	helm.UnmarshalK8SYaml(t, configMap.Data["action_file.yml"], &actionFile)
+	prefixes := map[int]interface{}{}
+	for number, action := range actionFile.Actions {
+		for _, filter := range action.Filters {
+			if filter["filtertype"] == "pattern" {
+				prefixes[number] = filter["value"]
+			}
+		}
+	}
+	require.Equal(t, map[int]interface{}{1: "custom-record", 2: "operate-", 3: "tasklist-", 4: "custom-record"}, prefixes)
+}
+
+func TestRetentionPolicyModeShouldDeployEitherCuratorOrSetupJob(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{"curator", "ilm"} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+
+			// when
+			release := renderRelease(t, map[string]string{"retentionPolicy.enabled": "true", "retentionPolicy.mode": mode})
+
+			// then
+			_, curator := release.podSpecs["camunda-platform-curator"]
+			_, setupJob := release.podSpecs["camunda-platform-retention-setup-1"]
+			require.Equal(t, mode == "curator", curator)
+			require.Equal(t, mode == "ilm", setupJob)
+		})
+	}
+}
+
+func TestRetentionSetupShouldCreateIsmPoliciesWithOpensearchPrefix(t *testing.T) {
+	t.Parallel()
+
+	// when
+	release := renderRelease(t, map[string]string{
+		"elasticsearch.enabled":         "false",
+		"global.opensearch.enabled":     "true",
+		"global.opensearch.host":        "search.example.com",
+		"global.opensearch.prefix":      "custom-record",
+		"retentionPolicy.enabled":       "true",
+		"retentionPolicy.mode":          "ilm",
+		"retentionPolicy.zeebeIndexTTL": "7",
+		"operate.enabled":               "false",
+	})
+
+	// then
+	require.Contains(t, release.podSpecs["camunda-platform-retention-setup-1"].Containers[0].Env, corev1.EnvVar{Name: "SEARCH_ENGINE", Value: "opensearch"})
+	require.Contains(t, release.podSpecs["camunda-platform-retention-setup-1"].Containers[0].Env, corev1.EnvVar{Name: "SEARCH_ENGINE_URL", Value: "https://search.example.com:443"})
+	var families map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(release.configMaps["camunda-platform-retention-setup-config"].Data["families.json"]), &families))
+	require.Equal(t, map[string]map[string]interface{}{
+		"zeebe":    {"prefix": "custom-record", "pattern": "custom-record*", "archivedOnly": false, "ttl": float64(7)},
+		"tasklist": {"prefix": "tasklist-", "pattern": "tasklist-*_2*", "archivedOnly": true, "ttl": float64(30)},
+	}, families)
+}
+
+// searchEngineRequest is a request of the retention setup script to the search engine stand-in.
+type searchEngineRequest struct {
+	method        string
+	path          string
+	query         string
+	authorization string
+	body          map[string]interface{}
+}
+
+// runRetentionSetup runs the setup script of the rendered config map against a search engine stand-in, which answers
+// the GET requests with the responses by path and acknowledges all other requests, and returns the requests of the script.
+func runRetentionSetup(t *testing.T, configMap corev1.ConfigMap, env []string, responses map[string]interface{}) []searchEngineRequest {
+	var lock sync.Mutex
+	var requests []searchEngineRequest
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received := searchEngineRequest{
+			method:        request.Method,
+			path:          request.URL.Path,
+			query:         request.URL.RawQuery,
+			authorization: request.Header.Get("Authorization"),
+		}
+		if request.ContentLength > 0 {
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&received.body))
+		}
+		lock.Lock()
+		requests = append(requests, received)
+		lock.Unlock()
+
+		response, found := responses[request.URL.Path]
+		if request.Method != http.MethodGet {
+			response, found = map[string]bool{"acknowledged": true}, true
+		}
+		if !found {
+			writer.WriteHeader(http.StatusNotFound)
+			response = map[string]string{"error": "not found"}
+		}
+		require.NoError(t, json.NewEncoder(writer).Encode(response))
+	}))
+	defer server.Close()
+
+	directory := t.TempDir()
+	for _, file := range []string{"setup.py", "families.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(directory, file), []byte(configMap.Data[file]), 0o644))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	command := exec.CommandContext(ctx, "python3", "-u", filepath.Join(directory, "setup.py"))
+	command.Env = append([]string{"SEARCH_ENGINE_URL=" + server.URL}, env...)
+	output, err := command.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	lock.Lock()
+	defer lock.Unlock()
+	return requests
+}
+
+// searchEngineRequestsTo returns the requests with the method to the path.
+func searchEngineRequestsTo(requests []searchEngineRequest, method string, path string) []searchEngineRequest {
+	var found []searchEngineRequest
+	for _, request := range requests {
+		if request.method == method && request.path == path {
+			found = append(found, request)
+		}
+	}
+	return found
+}
+
+func TestRetentionSetupShouldApplyIlmPoliciesToIndexTemplatesAndIndices(t *testing.T) {
+	t.Parallel()
+
+	// given
+	release := renderRelease(t, map[string]string{
+		"retentionPolicy.enabled": "true",
+		"retentionPolicy.mode":    "ilm",
+	})
+	responses := map[string]interface{}{
+		"/_index_template": map[string]interface{}{"index_templates": []interface{}{
+			map[string]interface{}{"name": "operate-list-view-8.1.0_template", "index_template": map[string]interface{}{
+				"index_patterns": []string{"operate-list-view-8.1.0_*"}, "template": map[string]interface{}{"settings": map[string]interface{}{"index": map[string]interface{}{"number_of_shards": "1"}}}}},
+			map[string]interface{}{"name": "operate-list-view-8.1.0_template-retention", "index_template": map[string]interface{}{
+				"index_patterns": []string{"operate-list-view-8.1.0_2*"}, "priority": 1}},
+			map[string]interface{}{"name": "tasklist-task-8.1.0_template", "index_template": map[string]interface{}{
+				"index_patterns": []string{"tasklist-task-8.1.0_*"}, "priority": 5}},
+			map[string]interface{}{"name": "other_template", "index_template": map[string]interface{}{
+				"index_patterns": []string{"other-*"}}},
+		}},
+		"/_template": map[string]interface{}{
+			"zeebe-record_job_template": map[string]interface{}{"index_patterns": []string{"zeebe-record_job_*"}, "order": 1},
+		},
+	}
+	env := []string{"SEARCH_ENGINE=elasticsearch", "SEARCH_ENGINE_USERNAME=elastic", "SEARCH_ENGINE_PASSWORD=secret"}
+
+	// when
+	requests := runRetentionSetup(t, release.configMaps["camunda-platform-retention-setup-config"], env, responses)
+
+	// then
+	for _, request := range requests {
+		require.Equal(t, "Basic ZWxhc3RpYzpzZWNyZXQ=", request.authorization, request.path)
+	}
+	for family, ttl := range map[string]string{"zeebe": "1d", "operate": "30d", "tasklist": "30d"} {
+		policies := searchEngineRequestsTo(requests, http.MethodPut, "/_ilm/policy/camunda-"+family+"-retention")
+		require.Len(t, policies, 1, family)
+		require.Equal(t, map[string]interface{}{"policy": map[string]interface{}{"phases": map[string]interface{}{"delete": map[string]interface{}{
+			"min_age": ttl, "actions": map[string]interface{}{"delete": map[string]interface{}{}}}}}}, policies[0].body)
+	}
+
+	operateTemplates := searchEngineRequestsTo(requests, http.MethodPut, "/_index_template/operate-list-view-8.1.0_template-retention")
+	require.Len(t, operateTemplates, 1)
+	require.Equal(t, map[string]interface{}{
+		"index_patterns": []interface{}{"operate-list-view-8.1.0_2*"},
+		"priority":       float64(1),
+		"template": map[string]interface{}{"settings": map[string]interface{}{"index": map[string]interface{}{
+			"number_of_shards": "1", "lifecycle": map[string]interface{}{"name": "camunda-operate-retention"}}}},
+	}, operateTemplates[0].body)
+	tasklistTemplates := searchEngineRequestsTo(requests, http.MethodPut, "/_index_template/tasklist-task-8.1.0_template-retention")
+	require.Len(t, tasklistTemplates, 1)
+	require.Equal(t, float64(6), tasklistTemplates[0].body["priority"])
+	zeebeTemplates := searchEngineRequestsTo(requests, http.MethodPut, "/_template/zeebe-record_job_template-retention")
+	require.Len(t, zeebeTemplates, 1)
+	require.Equal(t, map[string]interface{}{
+		"index_patterns": []interface{}{"zeebe-record_job_*"},
+		"order":          float64(2),
+		"settings":       map[string]interface{}{"index.lifecycle.name": "camunda-zeebe-retention"},
+	}, zeebeTemplates[0].body)
+	require.Empty(t, searchEngineRequestsTo(requests, http.MethodPut, "/_index_template/operate-list-view-8.1.0_template-retention-retention"))
+	require.Empty(t, searchEngineRequestsTo(requests, http.MethodPut, "/_index_template/other_template-retention"))
+
+	for family, pattern := range map[string]string{"zeebe": "zeebe-record*", "operate": "operate-*_2*", "tasklist": "tasklist-*_2*"} {
+		settings := searchEngineRequestsTo(requests, http.MethodPut, "/"+pattern+"/_settings")
+		require.Len(t, settings, 1, family)
+		require.Equal(t, map[string]interface{}{"index.lifecycle.name": "camunda-" + family + "-retention"}, settings[0].body)
+	}
+}
+
+func TestRetentionSetupShouldApplyIsmPoliciesToIndices(t *testing.T) {
+	t.Parallel()
+
+	// given
+	release := renderRelease(t, map[string]string{
+		"elasticsearch.enabled":     "false",
+		"global.opensearch.enabled": "true",
+		"global.opensearch.host":    "search.example.com",
+		"retentionPolicy.enabled":   "true",
+		"retentionPolicy.mode":      "ilm",
+	})
+	responses := map[string]interface{}{
+		"/_plugins/_ism/policies/camunda-operate-retention": map[string]interface{}{"_id": "camunda-operate-retention", "_seq_no": 7, "_primary_term": 2},
+	}
+
+	// when
+	requests := runRetentionSetup(t, release.configMaps["camunda-platform-retention-setup-config"], []string{"SEARCH_ENGINE=opensearch"}, responses)
+
+	// then
+	for family, pattern := range map[string]string{"zeebe": "zeebe-record*", "operate": "operate-*_2*", "tasklist": "tasklist-*_2*"} {
+		policies := searchEngineRequestsTo(requests, http.MethodPut, "/_plugins/_ism/policies/camunda-"+family+"-retention")
+		require.Len(t, policies, 1, family)
+		policy := policies[0].body["policy"].(map[string]interface{})
+		require.Equal(t, []interface{}{map[string]interface{}{"index_patterns": []interface{}{pattern}, "priority": float64(100)}}, policy["ism_template"], family)
+		require.Equal(t, "retain", policy["default_state"], family)
+		require.Empty(t, policies[0].authorization, family)
+
+		added := searchEngineRequestsTo(requests, http.MethodPost, "/_plugins/_ism/add/"+pattern)
+		require.Len(t, added, 1, family)
+		require.Equal(t, map[string]interface{}{"policy_id": "camunda-" + family + "-retention"}, added[0].body)
+	}
+	require.Equal(t, "if_seq_no=7&if_primary_term=2", searchEngineRequestsTo(requests, http.MethodPut, "/_plugins/_ism/policies/camunda-operate-retention")[0].query)
+	require.Empty(t, searchEngineRequestsTo(requests, http.MethodPut, "/_plugins/_ism/policies/camunda-zeebe-retention")[0].query)
+	require.Empty(t, searchEngineRequestsTo(requests, http.MethodGet, "/_index_template"))
+}
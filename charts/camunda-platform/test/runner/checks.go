@@ -0,0 +1,206 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/zbc"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// getJSON requests the URL and decodes the JSON response into value, it returns the status code of the response.
+// The body of responses without JSON content isn't decoded.
+func getJSON(ctx context.Context, url string, value interface{}) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if !strings.Contains(response.Header.Get("Content-Type"), "json") {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return response.StatusCode, nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(value); err != nil && err != io.EOF {
+		return response.StatusCode, fmt.Errorf("decoding the response of %s: %w", url, err)
+	}
+	return response.StatusCode, nil
+}
+
+// checkHealth verifies a health or readiness endpoint responds successfully. Spring Boot actuator endpoints also have
+// to report the status UP.
+func checkHealth(ctx context.Context, url string) error {
+	var health struct {
+		Status string `json:"status"`
+	}
+	status, err := getJSON(ctx, url, &health)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("responded with status code %d", status)
+	}
+	if health.Status != "" && health.Status != "UP" {
+		return fmt.Errorf("reports the status %s", health.Status)
+	}
+	return nil
+}
+
+// openIDConfiguration is the part of the OpenID Connect discovery document, which the components depend on.
+type openIDConfiguration struct {
+	Issuer        string `json:"issuer"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JwksURI       string `json:"jwks_uri"`
+}
+
+// checkOIDCDiscovery verifies the OpenID configuration of the issuer can be discovered, which the components need to
+// validate the tokens of their users and clients.
+func checkOIDCDiscovery(ctx context.Context, discoveryURL string) (openIDConfiguration, error) {
+	var configuration openIDConfiguration
+	status, err := getJSON(ctx, discoveryURL, &configuration)
+	if err != nil {
+		return configuration, err
+	}
+	if status != http.StatusOK {
+		return configuration, fmt.Errorf("discovery responded with status code %d", status)
+	}
+	if configuration.Issuer == "" || configuration.TokenEndpoint == "" || configuration.JwksURI == "" {
+		return configuration, fmt.Errorf("discovery misses the issuer, token endpoint or JWKS URI: %+v", configuration)
+	}
+	return configuration, nil
+}
+
+// checkKeycloakRealm verifies the OpenID configuration of the Keycloak realm can be discovered and belongs to the realm.
+func checkKeycloakRealm(ctx context.Context, realmURL string) error {
+	configuration, err := checkOIDCDiscovery(ctx, strings.TrimSuffix(realmURL, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return err
+	}
+
+	// The issuer depends on the frontend URL of Keycloak, only the realm has to match.
+	parsed, err := url.Parse(realmURL)
+	if err != nil {
+		return err
+	}
+	realm := "/realms/" + path.Base(parsed.Path)
+	if !strings.HasSuffix(strings.TrimSuffix(configuration.Issuer, "/"), realm) {
+		return fmt.Errorf("the issuer %s isn't the realm %s", configuration.Issuer, realm)
+	}
+	return nil
+}
+
+// checkIndexTemplates verifies Elasticsearch has index templates matching the pattern, which the components create
+// on start. Composable templates are checked first, then legacy templates as used by the Zeebe exporter.
+func checkIndexTemplates(ctx context.Context, elasticsearchURL string, pattern string) error {
+	elasticsearchURL = strings.TrimSuffix(elasticsearchURL, "/")
+
+	var composable struct {
+		IndexTemplates []struct {
+			Name string `json:"name"`
+		} `json:"index_templates"`
+	}
+	status, err := getJSON(ctx, elasticsearchURL+"/_index_template/"+pattern, &composable)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK && len(composable.IndexTemplates) > 0 {
+		return nil
+	}
+	if status != http.StatusOK && status != http.StatusNotFound {
+		return fmt.Errorf("listing the index templates responded with status code %d", status)
+	}
+
+	legacy := map[string]interface{}{}
+	status, err = getJSON(ctx, elasticsearchURL+"/_template/"+pattern, &legacy)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK && len(legacy) > 0 {
+		return nil
+	}
+	if status != http.StatusOK && status != http.StatusNotFound {
+		return fmt.Errorf("listing the legacy index templates responded with status code %d", status)
+	}
+	return fmt.Errorf("no index templates match %s", pattern)
+}
+
+// expectedTopology is the size of the Zeebe cluster, zero values aren't verified.
+type expectedTopology struct {
+	brokers           int
+	partitions        int
+	replicationFactor int
+}
+
+// checkZeebeTopology requests the topology from the gateway and verifies all brokers joined the cluster and every
+// partition has a healthy leader.
+func checkZeebeTopology(ctx context.Context, gatewayAddress string, expected expectedTopology) error {
+	client, err := zbc.NewClient(&zbc.ClientConfig{GatewayAddress: gatewayAddress, UsePlaintextConnection: true})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	topology, err := client.NewTopologyCommand().Send(ctx)
+	if err != nil {
+		return err
+	}
+	return verifyTopology(topology, expected)
+}
+
+func verifyTopology(topology *pb.TopologyResponse, expected expectedTopology) error {
+	if expected.brokers > 0 && len(topology.Brokers) != expected.brokers {
+		return fmt.Errorf("%d of %d brokers are part of the topology", len(topology.Brokers), expected.brokers)
+	}
+
+	partitions := int(topology.PartitionsCount)
+	if expected.partitions > 0 {
+		partitions = expected.partitions
+	}
+	leaders := map[int32]bool{}
+	replicas := 0
+	for _, broker := range topology.Brokers {
+		for _, partition := range broker.Partitions {
+			replicas++
+			if partition.Role == pb.Partition_LEADER && partition.Health == pb.Partition_HEALTHY {
+				leaders[partition.PartitionId] = true
+			}
+		}
+	}
+	for id := int32(1); id <= int32(partitions); id++ {
+		if !leaders[id] {
+			return fmt.Errorf("partition %d has no healthy leader", id)
+		}
+	}
+	if expected.replicationFactor > 0 && replicas != partitions*expected.replicationFactor {
+		return fmt.Errorf("%d partition replicas are part of the topology, expected %d", replicas, partitions*expected.replicationFactor)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/camunda-cloud/zeebe/clients/go/pkg/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newJSONServer starts a server which responds to the paths with the status code and JSON body, other paths are
+// answered with 404.
+func newJSONServer(t *testing.T, responses map[string]string, statusCode int) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestCheckHealthShouldAcceptStatusUp(t *testing.T) {
+	t.Parallel()
+
+	// given
+	url := newJSONServer(t, map[string]string{"/actuator/health/readiness": `{"status":"UP"}`}, http.StatusOK)
+
+	// when
+	err := checkHealth(context.Background(), url+"/actuator/health/readiness")
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestCheckHealthShouldAcceptResponsesWithoutJSON(t *testing.T) {
+	t.Parallel()
+
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	// when
+	err := checkHealth(context.Background(), server.URL+"/ready")
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestCheckHealthShouldRejectUnhealthyComponents(t *testing.T) {
+	t.Parallel()
+
+	// given
+	down := newJSONServer(t, map[string]string{"/health": `{"status":"DOWN"}`}, http.StatusOK)
+	unavailable := newJSONServer(t, map[string]string{"/health": `{"status":"OUT_OF_SERVICE"}`}, http.StatusServiceUnavailable)
+
+	// then
+	require.ErrorContains(t, checkHealth(context.Background(), down+"/health"), "status DOWN")
+	require.ErrorContains(t, checkHealth(context.Background(), unavailable+"/health"), "status code 503")
+	require.ErrorContains(t, checkHealth(context.Background(), down+"/missing"), "status code 404")
+}
+
+func TestCheckKeycloakRealmShouldAcceptDiscoverableRealm(t *testing.T) {
+	t.Parallel()
+
+	// given
+	url := newJSONServer(t, map[string]string{
+		"/auth/realms/camunda-platform/.well-known/openid-configuration": `{
+			"issuer": "https://camunda.example.com/auth/realms/camunda-platform",
+			"token_endpoint": "https://camunda.example.com/auth/realms/camunda-platform/protocol/openid-connect/token",
+			"jwks_uri": "https://camunda.example.com/auth/realms/camunda-platform/protocol/openid-connect/certs"
+		}`,
+	}, http.StatusOK)
+
+	// when
+	err := checkKeycloakRealm(context.Background(), url+"/auth/realms/camunda-platform")
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestCheckKeycloakRealmShouldRejectIncompleteOrMissingRealm(t *testing.T) {
+	t.Parallel()
+
+	// given
+	url := newJSONServer(t, map[string]string{
+		"/auth/realms/other/.well-known/openid-configuration":      `{"issuer": "http://keycloak/auth/realms/master", "token_endpoint": "t", "jwks_uri": "j"}`,
+		"/auth/realms/incomplete/.well-known/openid-configuration": `{"issuer": "http://keycloak/auth/realms/incomplete"}`,
+	}, http.StatusOK)
+
+	// then
+	require.ErrorContains(t, checkKeycloakRealm(context.Background(), url+"/auth/realms/other"), "isn't the realm /realms/other")
+	require.ErrorContains(t, checkKeycloakRealm(context.Background(), url+"/auth/realms/incomplete"), "misses the issuer")
+	require.ErrorContains(t, checkKeycloakRealm(context.Background(), url+"/auth/realms/camunda-platform"), "status code 404")
+}
+
+func TestCheckOIDCDiscoveryShouldRequireCompleteConfiguration(t *testing.T) {
+	t.Parallel()
+
+	// given
+	url := newJSONServer(t, map[string]string{
+		"/.well-known/openid-configuration": `{
+			"issuer": "https://login.example.com/",
+			"token_endpoint": "https://login.example.com/oauth/token",
+			"jwks_uri": "https://login.example.com/.well-known/jwks.json"
+		}`,
+		"/incomplete/.well-known/openid-configuration": `{"issuer": "https://login.example.com/", "jwks_uri": "j"}`,
+	}, http.StatusOK)
+
+	// when
+	configuration, err := checkOIDCDiscovery(context.Background(), url+"/.well-known/openid-configuration")
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, "https://login.example.com/oauth/token", configuration.TokenEndpoint)
+	_, err = checkOIDCDiscovery(context.Background(), url+"/incomplete/.well-known/openid-configuration")
+	require.ErrorContains(t, err, "misses the issuer")
+}
+
+func TestCheckIndexTemplatesShouldFindComposableAndLegacyTemplates(t *testing.T) {
+	t.Parallel()
+
+	// given
+	url := newJSONServer(t, map[string]string{
+		"/_index_template/operate-*": `{"index_templates":[{"name":"operate-list-view-1.3.0_template"}]}`,
+		"/_template/zeebe-record*":   `{"zeebe-record_job_8.1.7":{"index_patterns":["zeebe-record_job_8.1.7_*"]}}`,
+	}, http.StatusOK)
+
+	// then
+	require.NoError(t, checkIndexTemplates(context.Background(), url, "operate-*"))
+	require.NoError(t, checkIndexTemplates(context.Background(), url+"/", "zeebe-record*"))
+	require.ErrorContains(t, checkIndexTemplates(context.Background(), url, "tasklist-*"), "no index templates match tasklist-*")
+}
+
+func TestCheckIndexTemplatesShouldFailIfElasticsearchIsUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	url := newJSONServer(t, map[string]string{"/_index_template/operate-*": `{"error":"cluster_block_exception"}`}, http.StatusServiceUnavailable)
+
+	// when
+	err := checkIndexTemplates(context.Background(), url, "operate-*")
+
+	// then
+	require.ErrorContains(t, err, "status code 503")
+}
+
+// topologyGateway is a Zeebe gateway which responds with a fixed topology.
+type topologyGateway struct {
+	pb.UnimplementedGatewayServer
+	topology *pb.TopologyResponse
+}
+
+func (g *topologyGateway) Topology(context.Context, *pb.TopologyRequest) (*pb.TopologyResponse, error) {
+	return g.topology, nil
+}
+
+// newGateway starts a plaintext gRPC gateway on localhost and returns its address.
+func newGateway(t *testing.T, topology *pb.TopologyResponse) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterGatewayServer(server, &topologyGateway{topology: topology})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// balancedTopology returns the topology of a healthy cluster, which distributes the partitions round-robin.
+func balancedTopology(brokers int32, partitions int32, replicationFactor int32) *pb.TopologyResponse {
+	topology := &pb.TopologyResponse{ClusterSize: brokers, PartitionsCount: partitions, ReplicationFactor: replicationFactor}
+	for id := int32(0); id < brokers; id++ {
+		topology.Brokers = append(topology.Brokers, &pb.BrokerInfo{NodeId: id})
+	}
+	for partition := int32(1); partition <= partitions; partition++ {
+		for replica := int32(0); replica < replicationFactor; replica++ {
+			role := pb.Partition_FOLLOWER
+			if replica == 0 {
+				role = pb.Partition_LEADER
+			}
+			broker := topology.Brokers[(partition+replica)%brokers]
+			broker.Partitions = append(broker.Partitions, &pb.Partition{PartitionId: partition, Role: role, Health: pb.Partition_HEALTHY})
+		}
+	}
+	return topology
+}
+
+func TestCheckZeebeTopologyShouldAcceptCompleteCluster(t *testing.T) {
+	t.Parallel()
+
+	// given
+	address := newGateway(t, balancedTopology(3, 3, 3))
+
+	// when
+	err := checkZeebeTopology(context.Background(), address, expectedTopology{brokers: 3, partitions: 3, replicationFactor: 3})
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestVerifyTopologyShouldRejectIncompleteCluster(t *testing.T) {
+	t.Parallel()
+
+	// given
+	missingBroker := balancedTopology(3, 3, 3)
+	missingBroker.Brokers = missingBroker.Brokers[:2]
+	unhealthyLeader := balancedTopology(3, 3, 1)
+	unhealthyLeader.Brokers[2].Partitions[0].Health = pb.Partition_UNHEALTHY
+	expected := expectedTopology{brokers: 3, partitions: 3, replicationFactor: 3}
+
+	// then
+	require.ErrorContains(t, verifyTopology(missingBroker, expected), "2 of 3 brokers")
+	require.ErrorContains(t, verifyTopology(unhealthyLeader, expectedTopology{brokers: 3}), "partition 2 has no healthy leader")
+	require.ErrorContains(t, verifyTopology(balancedTopology(3, 3, 1), expected), "3 partition replicas")
+	require.ErrorContains(t, verifyTopology(balancedTopology(3, 2, 3), expected), "partition 3 has no healthy leader")
+	require.NoError(t, verifyTopology(balancedTopology(3, 2, 3), expectedTopology{}))
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command runner verifies a Camunda Platform release from inside the cluster. The "helm test" hooks of the chart run
+// it with the checks of their component, so a release only passes if the components actually work together and not
+// only if their ports are open.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stringsFlag is a flag which can be given multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+type config struct {
+	timeout  time.Duration
+	interval time.Duration
+
+	health stringsFlag
+
+	zeebeGateway           string
+	zeebeBrokers           int
+	zeebePartitions        int
+	zeebeReplicationFactor int
+
+	keycloakRealm string
+	oidcDiscovery string
+
+	elasticsearch  string
+	indexTemplates stringsFlag
+}
+
+func parseConfig(args []string, output io.Writer) (config, error) {
+	var c config
+	flags := flag.NewFlagSet("runner", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.DurationVar(&c.timeout, "timeout", 5*time.Minute, "how long failing checks are retried")
+	flags.DurationVar(&c.interval, "interval", 5*time.Second, "the pause between two attempts of a failing check")
+	flags.Var(&c.health, "health", "URL of a health endpoint which has to respond successfully and with status UP, can be repeated")
+	flags.StringVar(&c.zeebeGateway, "zeebe-gateway", "", "address of the Zeebe gateway, whose topology has to be complete and healthy")
+	flags.IntVar(&c.zeebeBrokers, "zeebe-brokers", 0, "expected number of Zeebe brokers")
+	flags.IntVar(&c.zeebePartitions, "zeebe-partitions", 0, "expected number of Zeebe partitions")
+	flags.IntVar(&c.zeebeReplicationFactor, "zeebe-replication-factor", 0, "expected replication factor of the Zeebe partitions")
+	flags.StringVar(&c.keycloakRealm, "keycloak-realm", "", "URL of the Keycloak realm, whose OpenID configuration has to be discoverable")
+	flags.StringVar(&c.oidcDiscovery, "oidc-discovery", "", "OpenID Connect discovery URL of an external issuer, whose configuration has to be complete")
+	flags.StringVar(&c.elasticsearch, "elasticsearch", "", "URL of Elasticsearch, which has to contain the index templates")
+	flags.Var(&c.indexTemplates, "index-template", "name pattern of index templates which have to exist in Elasticsearch, can be repeated")
+	if err := flags.Parse(args); err != nil {
+		return c, err
+	}
+
+	if flags.NArg() > 0 {
+		return c, fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+	if len(c.indexTemplates) > 0 && c.elasticsearch == "" {
+		return c, errors.New("-index-template requires -elasticsearch")
+	}
+	if c.zeebeGateway == "" && (c.zeebeBrokers > 0 || c.zeebePartitions > 0 || c.zeebeReplicationFactor > 0) {
+		return c, errors.New("the expected Zeebe topology requires -zeebe-gateway")
+	}
+	if len(c.checks()) == 0 {
+		return c, errors.New("no checks configured")
+	}
+	return c, nil
+}
+
+// check is one verification of the release, which is retried until it succeeds.
+type check struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (c config) checks() []check {
+	var checks []check
+	for _, url := range c.health {
+		url := url
+		checks = append(checks, check{name: "health " + url, run: func(ctx context.Context) error {
+			return checkHealth(ctx, url)
+		}})
+	}
+	if c.zeebeGateway != "" {
+		expected := expectedTopology{brokers: c.zeebeBrokers, partitions: c.zeebePartitions, replicationFactor: c.zeebeReplicationFactor}
+		checks = append(checks, check{name: "zeebe topology " + c.zeebeGateway, run: func(ctx context.Context) error {
+			return checkZeebeTopology(ctx, c.zeebeGateway, expected)
+		}})
+	}
+	if c.keycloakRealm != "" {
+		checks = append(checks, check{name: "keycloak realm " + c.keycloakRealm, run: func(ctx context.Context) error {
+			return checkKeycloakRealm(ctx, c.keycloakRealm)
+		}})
+	}
+	if c.oidcDiscovery != "" {
+		checks = append(checks, check{name: "oidc discovery " + c.oidcDiscovery, run: func(ctx context.Context) error {
+			_, err := checkOIDCDiscovery(ctx, c.oidcDiscovery)
+			return err
+		}})
+	}
+	for _, pattern := range c.indexTemplates {
+		pattern := pattern
+		checks = append(checks, check{name: "index templates " + pattern, run: func(ctx context.Context) error {
+			return checkIndexTemplates(ctx, c.elasticsearch, pattern)
+		}})
+	}
+	return checks
+}
+
+// runChecks runs all checks concurrently and retries each failing check until it succeeds or the context is done.
+// It returns an error naming every check which didn't succeed with its last failure.
+func runChecks(ctx context.Context, checks []check, interval time.Duration, output io.Writer) error {
+	var mutex sync.Mutex
+	logf := func(format string, args ...interface{}) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		fmt.Fprintf(output, format+"\n", args...)
+	}
+
+	failures := make([]error, len(checks))
+	var wait sync.WaitGroup
+	for i, c := range checks {
+		wait.Add(1)
+		go func(i int, c check) {
+			defer wait.Done()
+			for attempt := 1; ; attempt++ {
+				err := c.run(ctx)
+				if err == nil {
+					logf("PASS %s", c.name)
+					return
+				}
+				logf("attempt %d of %s failed: %v", attempt, c.name, err)
+				select {
+				case <-ctx.Done():
+					failures[i] = fmt.Errorf("FAIL %s: %w", c.name, err)
+					logf("%v", failures[i])
+					return
+				case <-time.After(interval):
+				}
+			}
+		}(i, c)
+	}
+	wait.Wait()
+
+	var messages []string
+	for _, failure := range failures {
+		if failure != nil {
+			messages = append(messages, failure.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%d of %d checks failed:\n%s", len(messages), len(checks), strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+func main() {
+	c, err := parseConfig(os.Args[1:], os.Stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := runChecks(ctx, c.checks(), c.interval, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
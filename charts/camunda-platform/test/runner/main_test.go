@@ -0,0 +1,120 @@
+// Copyright 2022 Camunda Services GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigShouldCreateCheckPerFlag(t *testing.T) {
+	t.Parallel()
+
+	// given
+	args := []string{
+		"-timeout", "1m",
+		"-health", "http://release-operate:80/actuator/health/readiness",
+		"-health", "http://release-operate:80/actuator/health/liveness",
+		"-zeebe-gateway", "release-zeebe-gateway:26500", "-zeebe-brokers", "3",
+		"-keycloak-realm", "http://release-keycloak:80/auth/realms/camunda-platform",
+		"-oidc-discovery", "https://login.example.com/.well-known/openid-configuration",
+		"-elasticsearch", "http://elasticsearch-master:9200", "-index-template", "operate-*",
+	}
+
+	// when
+	c, err := parseConfig(args, io.Discard)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, c.timeout)
+	var names []string
+	for _, check := range c.checks() {
+		names = append(names, check.name)
+	}
+	require.Equal(t, []string{
+		"health http://release-operate:80/actuator/health/readiness",
+		"health http://release-operate:80/actuator/health/liveness",
+		"zeebe topology release-zeebe-gateway:26500",
+		"keycloak realm http://release-keycloak:80/auth/realms/camunda-platform",
+		"oidc discovery https://login.example.com/.well-known/openid-configuration",
+		"index templates operate-*",
+	}, names)
+}
+
+func TestParseConfigShouldRejectIncompleteArguments(t *testing.T) {
+	t.Parallel()
+
+	for message, args := range map[string][]string{
+		"no checks configured":                 {"-timeout", "1m"},
+		"-index-template requires":             {"-index-template", "operate-*"},
+		"expected Zeebe topology requires":     {"-zeebe-brokers", "3", "-health", "http://release-zeebe:9600/ready"},
+		"unexpected arguments [release-zeebe]": {"-health", "http://release-zeebe:9600/ready", "release-zeebe"},
+	} {
+		_, err := parseConfig(args, io.Discard)
+		require.ErrorContains(t, err, message)
+	}
+}
+
+func TestRunChecksShouldRetryUntilChecksSucceed(t *testing.T) {
+	t.Parallel()
+
+	// given
+	attempts := 0
+	checks := []check{
+		{name: "eventually", run: func(context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("not yet")
+			}
+			return nil
+		}},
+		{name: "immediately", run: func(context.Context) error { return nil }},
+	}
+	var output bytes.Buffer
+
+	// when
+	err := runChecks(context.Background(), checks, time.Millisecond, &output)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.Contains(t, output.String(), "attempt 2 of eventually failed: not yet")
+	require.Contains(t, output.String(), "PASS eventually")
+	require.Contains(t, output.String(), "PASS immediately")
+}
+
+func TestRunChecksShouldReportChecksFailingUntilTimeout(t *testing.T) {
+	t.Parallel()
+
+	// given
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	checks := []check{
+		{name: "broken", run: func(context.Context) error { return errors.New("connection refused") }},
+		{name: "working", run: func(context.Context) error { return nil }},
+	}
+
+	// when
+	err := runChecks(ctx, checks, 10*time.Millisecond, io.Discard)
+
+	// then
+	require.EqualError(t, err, "1 of 2 checks failed:\nFAIL broken: connection refused")
+}
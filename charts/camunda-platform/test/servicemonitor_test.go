@@ -6,9 +6,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func TestGoldenServiceMonitorDefaults(t *testing.T) {
@@ -26,3 +31,143 @@ func TestGoldenServiceMonitorDefaults(t *testing.T) {
 		SetValues:      map[string]string{"prometheusServiceMonitor.enabled": "true"},
 	})
 }
+
+// serviceMonitor holds the fields of a prometheus-operator ServiceMonitor which decide what is scraped.
+type serviceMonitor struct {
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Selector  metav1.LabelSelector `json:"selector"`
+		Endpoints []struct {
+			Port string `json:"port"`
+			Path string `json:"path"`
+		} `json:"endpoints"`
+	} `json:"spec"`
+}
+
+// renderServicesAndMonitors renders the whole chart and returns its Services and ServiceMonitors.
+func renderServicesAndMonitors(t *testing.T, values map[string]string) ([]corev1.Service, []serviceMonitor) {
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	options := &helm.Options{
+		SetValues:      values,
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+	}
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", nil)
+
+	var services []corev1.Service
+	var monitors []serviceMonitor
+	for _, document := range strings.Split(output, "\n---\n") {
+		var meta metav1.TypeMeta
+		helm.UnmarshalK8SYaml(t, document, &meta)
+		switch meta.Kind {
+		case "Service":
+			var service corev1.Service
+			helm.UnmarshalK8SYaml(t, document, &service)
+			services = append(services, service)
+		case "ServiceMonitor":
+			var monitor serviceMonitor
+			helm.UnmarshalK8SYaml(t, document, &monitor)
+			monitors = append(monitors, monitor)
+		}
+	}
+	return services, monitors
+}
+
+// monitoredPorts returns for every ServiceMonitor the Service ports it scrapes, it fails if a ServiceMonitor
+// doesn't select exactly one Service or an endpoint port doesn't exist on it.
+func monitoredPorts(t *testing.T, services []corev1.Service, monitors []serviceMonitor) map[string]string {
+	ports := map[string]string{}
+	for _, monitor := range monitors {
+		selector, err := metav1.LabelSelectorAsSelector(&monitor.Spec.Selector)
+		require.NoError(t, err)
+
+		var selected []corev1.Service
+		for _, service := range services {
+			if selector.Matches(labels.Set(service.Labels)) {
+				selected = append(selected, service)
+			}
+		}
+		require.Len(t, selected, 1, "ServiceMonitor %s has to select exactly one Service", monitor.Metadata.Name)
+		service := selected[0]
+
+		require.Len(t, monitor.Spec.Endpoints, 1, "ServiceMonitor %s", monitor.Metadata.Name)
+		endpoint := monitor.Spec.Endpoints[0]
+		require.Equal(t, "/actuator/prometheus", endpoint.Path)
+		var portNames []string
+		for _, port := range service.Spec.Ports {
+			portNames = append(portNames, port.Name)
+		}
+		require.Contains(t, portNames, endpoint.Port, "ServiceMonitor %s scrapes a port Service %s doesn't have", monitor.Metadata.Name, service.Name)
+		ports[service.Name] = endpoint.Port
+	}
+	return ports
+}
+
+func TestServiceMonitorsShouldMatchServicePorts(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"prometheusServiceMonitor.enabled": "true",
+		"connectors.enabled":               "true",
+	}
+
+	// when
+	services, monitors := renderServicesAndMonitors(t, values)
+
+	// then
+	require.Equal(t, map[string]string{
+		"camunda-platform-test-zeebe":         "http",
+		"camunda-platform-test-zeebe-gateway": "http",
+		"camunda-platform-test-operate":       "http",
+		"camunda-platform-test-tasklist":      "http",
+		"camunda-platform-test-optimize":      "management",
+		"camunda-platform-test-identity":      "metrics",
+		"camunda-platform-test-connectors":    "http",
+	}, monitoredPorts(t, services, monitors))
+}
+
+func TestServiceMonitorsShouldFollowCustomPortNames(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"prometheusServiceMonitor.enabled": "true",
+		"connectors.enabled":               "true",
+		"connectors.service.serverName":    "connectors-http",
+		"zeebe.service.httpName":           "broker-http",
+		"zeebe-gateway.service.httpName":   "gateway-http",
+		"identity.service.metricsName":     "identity-metrics",
+	}
+
+	// when
+	services, monitors := renderServicesAndMonitors(t, values)
+
+	// then
+	ports := monitoredPorts(t, services, monitors)
+	require.Equal(t, "broker-http", ports["camunda-platform-test-zeebe"])
+	require.Equal(t, "gateway-http", ports["camunda-platform-test-zeebe-gateway"])
+	require.Equal(t, "identity-metrics", ports["camunda-platform-test-identity"])
+	require.Equal(t, "connectors-http", ports["camunda-platform-test-connectors"])
+}
+
+func TestServiceMonitorsShouldSkipDisabledComponents(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"prometheusServiceMonitor.enabled": "true",
+		"operate.enabled":                  "false",
+		"optimize.enabled":                 "false",
+	}
+
+	// when
+	services, monitors := renderServicesAndMonitors(t, values)
+
+	// then
+	ports := monitoredPorts(t, services, monitors)
+	require.NotContains(t, ports, "camunda-platform-test-operate")
+	require.NotContains(t, ports, "camunda-platform-test-optimize")
+	require.NotContains(t, ports, "camunda-platform-test-connectors")
+	require.Contains(t, ports, "camunda-platform-test-tasklist")
+}
@@ -71,3 +71,66 @@ func (s *configMapTemplateTest) TestConfigMapElasticsearchURL() {
 	// then
 	s.Require().Equal("elasticsearch-master-test", elasticsearchURL)
 }
+
+func (s *configMapTemplateTest) TestConfigMapElasticsearchByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var configmap corev1.ConfigMap
+	var configmapApplication map[string]interface{}
+	helm.UnmarshalK8SYaml(s.T(), output, &configmap)
+	helm.UnmarshalK8SYaml(s.T(), configmap.Data["application.yml"], &configmapApplication)
+
+	// then
+	tasklistConfig := configmapApplication["camunda.tasklist"].(map[string]interface{})
+	s.Require().NotContains(tasklistConfig, "database")
+	s.Require().NotContains(tasklistConfig, "opensearch")
+	s.Require().NotContains(tasklistConfig, "zeebeOpensearch")
+	s.Require().Equal("elasticsearch-master", tasklistConfig["elasticsearch"].(map[string]interface{})["host"])
+	s.Require().Equal("zeebe-record", tasklistConfig["zeebeElasticsearch"].(map[string]interface{})["prefix"])
+}
+
+func (s *configMapTemplateTest) TestConfigMapOpensearch() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"elasticsearch.enabled":                 "false",
+			"global.opensearch.enabled":             "true",
+			"global.opensearch.host":                "search.example.com",
+			"global.opensearch.prefix":              "camunda-record",
+			"global.opensearch.auth.username":       "camunda",
+			"global.opensearch.auth.existingSecret": "opensearch-credentials",
+			"global.opensearch.aws.enabled":         "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var configmap corev1.ConfigMap
+	var configmapApplication map[string]interface{}
+	helm.UnmarshalK8SYaml(s.T(), output, &configmap)
+	helm.UnmarshalK8SYaml(s.T(), configmap.Data["application.yml"], &configmapApplication)
+
+	// then
+	tasklistConfig := configmapApplication["camunda.tasklist"].(map[string]interface{})
+	s.Require().Equal("opensearch", tasklistConfig["database"])
+	s.Require().NotContains(tasklistConfig, "elasticsearch")
+	s.Require().NotContains(tasklistConfig, "zeebeElasticsearch")
+	s.Require().Equal(map[string]interface{}{
+		"url":        "https://search.example.com:443",
+		"username":   "camunda",
+		"awsEnabled": true,
+	}, tasklistConfig["opensearch"])
+	s.Require().Equal(map[string]interface{}{
+		"url":        "https://search.example.com:443",
+		"prefix":     "camunda-record",
+		"username":   "camunda",
+		"awsEnabled": true,
+	}, tasklistConfig["zeebeOpensearch"])
+	s.Require().Contains(tasklistConfig, "zeebe")
+}
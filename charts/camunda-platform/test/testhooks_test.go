@@ -0,0 +1,187 @@
+package test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// renderTestHooks renders the whole chart and returns the "helm test" hook pods by their component label. The hooks run
+// the test runner, unless the values disable it.
+func renderTestHooks(t *testing.T, values map[string]string) map[string]corev1.Pod {
+	chartPath, err := filepath.Abs("../")
+	require.NoError(t, err)
+	setValues := map[string]string{"global.testRunner.enabled": "true"}
+	for key, value := range values {
+		setValues[key] = value
+	}
+	options := &helm.Options{
+		SetValues:      setValues,
+		KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+	}
+	output := helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", nil)
+
+	hooks := map[string]corev1.Pod{}
+	for _, document := range strings.Split(output, "\n---\n") {
+		var pod corev1.Pod
+		helm.UnmarshalK8SYaml(t, document, &pod)
+		if pod.Kind != "Pod" || pod.Annotations["helm.sh/hook"] != "test-success" {
+			continue
+		}
+		component := pod.Labels["app.kubernetes.io/component"]
+		require.NotContains(t, hooks, component, "only one test hook per component")
+		hooks[component] = pod
+	}
+	return hooks
+}
+
+// testRunnerArgs returns the arguments of the test runner container of the hook.
+func testRunnerArgs(t *testing.T, pod corev1.Pod) []string {
+	require.Len(t, pod.Spec.Containers, 1)
+	container := pod.Spec.Containers[0]
+	require.Equal(t, "test-runner", container.Name)
+	return container.Args
+}
+
+func TestTestHooksShouldRequestComponentsWithWgetByDefault(t *testing.T) {
+	t.Parallel()
+
+	// when
+	hooks := renderTestHooks(t, map[string]string{"global.testRunner.enabled": "false"})
+
+	// then
+	require.ElementsMatch(t, []string{"zeebe-broker", "operate", "tasklist", "optimize", "identity"}, hookComponents(hooks))
+	for component, pod := range hooks {
+		require.Len(t, pod.Spec.Containers, 1, component)
+		require.Equal(t, "busybox", pod.Spec.Containers[0].Image, component)
+		require.Equal(t, []string{"wget"}, pod.Spec.Containers[0].Command, component)
+		require.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy, component)
+	}
+	require.Equal(t, []string{"camunda-platform-test-zeebe:9600"}, hooks["zeebe-broker"].Spec.Containers[0].Args)
+	require.Equal(t, []string{"camunda-platform-test-operate:80"}, hooks["operate"].Spec.Containers[0].Args)
+	require.Equal(t, []string{"camunda-platform-test-identity:80"}, hooks["identity"].Spec.Containers[0].Args)
+}
+
+func TestTestHooksShouldRunTestRunnerForDefaultComponents(t *testing.T) {
+	t.Parallel()
+
+	// given
+	chart, err := chartutil.LoadChartfile("../Chart.yaml")
+	require.NoError(t, err)
+
+	// when
+	hooks := renderTestHooks(t, map[string]string{})
+
+	// then
+	require.ElementsMatch(t, []string{"zeebe-broker", "zeebe-gateway", "operate", "tasklist", "optimize", "identity"}, hookComponents(hooks))
+	for component, pod := range hooks {
+		require.Equal(t, "camunda/camunda-platform-helm-test-runner:"+chart.Version, pod.Spec.Containers[0].Image, component)
+		require.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy, component)
+		require.Equal(t, "-timeout=5m", testRunnerArgs(t, pod)[0], component)
+	}
+	require.Equal(t, []string{
+		"-timeout=5m",
+		"-health=http://camunda-platform-test-zeebe:9600/ready",
+		"-zeebe-gateway=camunda-platform-test-zeebe-gateway:26500",
+		"-zeebe-brokers=3",
+		"-zeebe-partitions=3",
+		"-zeebe-replication-factor=3",
+		"-elasticsearch=http://elasticsearch-master:9200",
+		"-index-template=zeebe-record*",
+	}, testRunnerArgs(t, hooks["zeebe-broker"]))
+	require.Equal(t, []string{
+		"-timeout=5m",
+		"-health=http://camunda-platform-test-operate:80/actuator/health/readiness",
+		"-health=http://camunda-platform-test-operate:80/actuator/health/liveness",
+		"-elasticsearch=http://elasticsearch-master:9200",
+		"-index-template=operate-*",
+	}, testRunnerArgs(t, hooks["operate"]))
+	require.Equal(t, []string{
+		"-timeout=5m",
+		"-health=http://camunda-platform-test-identity:82/actuator/health",
+		"-keycloak-realm=http://camunda-platform-tes:80/auth/realms/camunda-platform",
+	}, testRunnerArgs(t, hooks["identity"]))
+	require.Contains(t, testRunnerArgs(t, hooks["zeebe-gateway"]), "-health=http://camunda-platform-test-zeebe-gateway:9600/actuator/health")
+	require.Contains(t, testRunnerArgs(t, hooks["tasklist"]), "-index-template=tasklist-*")
+	require.Contains(t, testRunnerArgs(t, hooks["optimize"]), "-health=http://camunda-platform-test-optimize:80/api/readyz")
+}
+
+func TestTestHooksShouldFollowEnabledComponents(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"operate.enabled":    "false",
+		"optimize.enabled":   "false",
+		"connectors.enabled": "true",
+	}
+
+	// when
+	hooks := renderTestHooks(t, values)
+
+	// then
+	require.ElementsMatch(t, []string{"zeebe-broker", "zeebe-gateway", "tasklist", "identity", "connectors"}, hookComponents(hooks))
+	require.Equal(t, []string{
+		"-timeout=5m",
+		"-health=http://camunda-platform-test-connectors:8080/actuator/health",
+	}, testRunnerArgs(t, hooks["connectors"]))
+}
+
+func TestTestHooksShouldFollowComponentConfiguration(t *testing.T) {
+	t.Parallel()
+
+	// given
+	values := map[string]string{
+		"global.elasticsearch.disableExporter":   "true",
+		"global.elasticsearch.url":               "https://elastic.example.com:9243",
+		"global.identity.keycloak.url.protocol":  "https",
+		"global.identity.keycloak.url.host":      "keycloak.example.com",
+		"global.identity.keycloak.url.port":      "8443",
+		"global.testRunner.image.registry":       "registry.example.com",
+		"global.testRunner.image.tag":            "snapshot",
+		"global.testRunner.timeout":              "10m",
+		"zeebe.clusterSize":                      "5",
+		"zeebe.partitionCount":                   "7",
+		"zeebe.replicationFactor":                "2",
+		"zeebe-gateway.readinessProbe.probePath": "/actuator/health/readiness",
+		"zeebe-gateway.livenessProbe.probePath":  "/actuator/health/readiness",
+		"tasklist.service.port":                  "8080",
+	}
+
+	// when
+	hooks := renderTestHooks(t, values)
+
+	// then
+	require.Equal(t, "registry.example.com/camunda/camunda-platform-helm-test-runner:snapshot", hooks["operate"].Spec.Containers[0].Image)
+	require.Equal(t, []string{
+		"-timeout=10m",
+		"-health=http://camunda-platform-test-zeebe:9600/ready",
+		"-zeebe-gateway=camunda-platform-test-zeebe-gateway:26500",
+		"-zeebe-brokers=5",
+		"-zeebe-partitions=7",
+		"-zeebe-replication-factor=2",
+	}, testRunnerArgs(t, hooks["zeebe-broker"]))
+	require.Equal(t, []string{
+		"-timeout=10m",
+		"-health=http://camunda-platform-test-zeebe-gateway:9600/actuator/health/readiness",
+	}, testRunnerArgs(t, hooks["zeebe-gateway"]))
+	require.Contains(t, testRunnerArgs(t, hooks["operate"]), "-elasticsearch=https://elastic.example.com:9243")
+	require.Contains(t, testRunnerArgs(t, hooks["tasklist"]), "-health=http://camunda-platform-test-tasklist:8080/actuator/health/readiness")
+	require.Contains(t, testRunnerArgs(t, hooks["identity"]), "-keycloak-realm=https://keycloak.example.com:8443/auth/realms/camunda-platform")
+}
+
+// hookComponents returns the components which have a test hook.
+func hookComponents(hooks map[string]corev1.Pod) []string {
+	var components []string
+	for component := range hooks {
+		components = append(components, component)
+	}
+	return components
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type deploymentTemplateTest struct {
@@ -688,3 +689,55 @@ func (s *deploymentTemplateTest) TestContainerLivenessProbe() {
 	s.Require().EqualValues(5, probe.FailureThreshold)
 	s.Require().EqualValues(1, probe.TimeoutSeconds)
 }
+
+// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+func (s *deploymentTemplateTest) TestContainerSetTopologySpreadConstraints() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"zeebe-gateway.topologySpreadConstraints[0].maxSkew":           "2",
+			"zeebe-gateway.topologySpreadConstraints[0].topologyKey":       "kubernetes.io/hostname",
+			"zeebe-gateway.topologySpreadConstraints[0].whenUnsatisfiable": "ScheduleAnyway",
+			"zeebe-gateway.zoneAwareness.enabled":                          "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Equal([]corev1.TopologySpreadConstraint{
+		{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.ScheduleAnyway},
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				"app":                          "camunda-platform",
+				"app.kubernetes.io/name":       "zeebe-gateway",
+				"app.kubernetes.io/instance":   "camunda-platform-test",
+				"app.kubernetes.io/managed-by": "Helm",
+				"app.kubernetes.io/part-of":    "camunda-platform",
+				"app.kubernetes.io/component":  "zeebe-gateway",
+			}},
+		},
+	}, deployment.Spec.Template.Spec.TopologySpreadConstraints)
+}
+
+func (s *deploymentTemplateTest) TestContainerShouldNotSetTopologySpreadConstraintsByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var deployment appsv1.Deployment
+	helm.UnmarshalK8SYaml(s.T(), output, &deployment)
+
+	// then
+	s.Require().Empty(deployment.Spec.Template.Spec.TopologySpreadConstraints)
+}
@@ -16,13 +16,18 @@ package zeebe
 
 import (
 	"camunda-platform-helm/charts/camunda-platform/test/golden"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGoldenConfigmapWithLog4j2(t *testing.T) {
@@ -40,3 +45,92 @@ func TestGoldenConfigmapWithLog4j2(t *testing.T) {
 		SetValues:      map[string]string{"zeebe.log4j2": "<xml>\n</xml>"},
 	})
 }
+
+func TestGoldenConfigmapWithZoneAwareness(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../../")
+	require.NoError(t, err)
+
+	suite.Run(t, &golden.TemplateGoldenTest{
+		ChartPath:      chartPath,
+		Release:        "camunda-platform-test",
+		Namespace:      "camunda-platform-" + strings.ToLower(random.UniqueId()),
+		GoldenFileName: "configmap-zone-awareness",
+		Templates:      []string{"charts/zeebe/templates/configmap.yaml"},
+		SetValues:      map[string]string{"zeebe.zoneAwareness.enabled": "true"},
+	})
+}
+
+func TestGoldenZoneRBACWithZoneAwareness(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../../")
+	require.NoError(t, err)
+
+	suite.Run(t, &golden.TemplateGoldenTest{
+		ChartPath:      chartPath,
+		Release:        "camunda-platform-test",
+		Namespace:      "camunda-platform-test",
+		GoldenFileName: "zone-rbac",
+		Templates:      []string{"charts/zeebe/templates/zone-rbac.yaml"},
+		SetValues:      map[string]string{"zeebe.zoneAwareness.enabled": "true"},
+	})
+}
+
+func TestStartupScriptShouldExportZoneOfNode(t *testing.T) {
+	t.Parallel()
+
+	chartPath, err := filepath.Abs("../../")
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		name          string
+		zone          string
+		expectedError bool
+		expected      string
+	}{
+		{
+			name:     "zone of the node",
+			zone:     "eu-west-1a",
+			expected: "eu-west-1a",
+		},
+		{
+			name:          "node without zone",
+			zone:          "",
+			expectedError: true,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// given
+			options := &helm.Options{
+				SetValues:      map[string]string{"zeebe.zoneAwareness.enabled": "true"},
+				KubectlOptions: k8s.NewKubectlOptions("", "", "camunda-platform-"+strings.ToLower(random.UniqueId())),
+			}
+			var configMap corev1.ConfigMap
+			helm.UnmarshalK8SYaml(t, helm.RenderTemplate(t, options, chartPath, "camunda-platform-test", []string{"charts/zeebe/templates/configmap.yaml"}), &configMap)
+			// the zone file, which is written by the init container "zone"
+			zoneFile := filepath.Join(t.TempDir(), "zone")
+			require.NoError(t, os.WriteFile(zoneFile, []byte(testCase.zone), 0o644))
+			script, _, found := strings.Cut(configMap.Data["startup.sh"], `if [ "$(ls -A /exporters/)" ]; then`)
+			require.True(t, found)
+			script = strings.ReplaceAll(script, "/usr/local/zeebe/zone/zone", zoneFile)
+
+			// when
+			command := exec.Command("bash", "-c", script+"\necho -n \"${K8S_ZONE}|${ZEEBE_BROKER_CLUSTER_ZONE-unset}\"")
+			command.Env = []string{"K8S_NAME=camunda-platform-test-zeebe-0"}
+			output, err := command.Output()
+
+			// then
+			if testCase.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected+"|unset", string(output))
+		})
+	}
+}
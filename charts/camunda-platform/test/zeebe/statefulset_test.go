@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type statefulSetTest struct {
@@ -792,3 +793,292 @@ func (s *statefulSetTest) TestContainerLivenessProbe() {
 	s.Require().EqualValues(5, probe.FailureThreshold)
 	s.Require().EqualValues(1, probe.TimeoutSeconds)
 }
+
+func (s *statefulSetTest) TestContainerShouldSetElasticsearchExporterByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	env := statefulSet.Spec.Template.Spec.Containers[0].Env
+	s.Require().Contains(env, corev1.EnvVar{Name: "ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_URL", Value: "http://elasticsearch-master:9200"})
+	for _, envVar := range env {
+		s.Require().NotContains(envVar.Name, "OPENSEARCH")
+	}
+}
+
+func (s *statefulSetTest) TestContainerShouldSetOpensearchExporter() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"elasticsearch.enabled":                 "false",
+			"global.opensearch.enabled":             "true",
+			"global.opensearch.url":                 "https://search.example.com",
+			"global.opensearch.prefix":              "camunda-record",
+			"global.opensearch.auth.username":       "camunda",
+			"global.opensearch.auth.existingSecret": "opensearch-credentials",
+			"global.opensearch.aws.enabled":         "true",
+			"global.opensearch.aws.region":          "eu-west-1",
+			"global.opensearch.aws.serviceName":     "aoss",
+			"optimize.enabled":                      "false",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	env := statefulSet.Spec.Template.Spec.Containers[0].Env
+	for _, expected := range []corev1.EnvVar{
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_CLASSNAME", Value: "io.camunda.zeebe.exporter.opensearch.OpensearchExporter"},
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_URL", Value: "https://search.example.com"},
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_INDEX_PREFIX", Value: "camunda-record"},
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_AUTHENTICATION_USERNAME", Value: "camunda"},
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_AWS_ENABLED", Value: "true"},
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_AWS_SERVICENAME", Value: "aoss"},
+		{Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_AWS_REGION", Value: "eu-west-1"},
+		{Name: "AWS_REGION", Value: "eu-west-1"},
+		{
+			Name: "ZEEBE_BROKER_EXPORTERS_OPENSEARCH_ARGS_AUTHENTICATION_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "opensearch-credentials"},
+					Key:                  "password",
+				},
+			},
+		},
+	} {
+		s.Require().Contains(env, expected)
+	}
+	for _, envVar := range env {
+		s.Require().NotContains(envVar.Name, "ELASTICSEARCH")
+	}
+}
+
+// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+func (s *statefulSetTest) TestContainerSetTopologySpreadConstraints() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"zeebe.topologySpreadConstraints[0].maxSkew":           "2",
+			"zeebe.topologySpreadConstraints[0].topologyKey":       "kubernetes.io/hostname",
+			"zeebe.topologySpreadConstraints[0].whenUnsatisfiable": "ScheduleAnyway",
+			"zeebe.zoneAwareness.enabled":                          "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	s.Require().Equal([]corev1.TopologySpreadConstraint{
+		{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.ScheduleAnyway},
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+				"app":                          "camunda-platform",
+				"app.kubernetes.io/name":       "zeebe",
+				"app.kubernetes.io/instance":   "camunda-platform-test",
+				"app.kubernetes.io/managed-by": "Helm",
+				"app.kubernetes.io/part-of":    "camunda-platform",
+				"app.kubernetes.io/component":  "zeebe-broker",
+			}},
+		},
+	}, statefulSet.Spec.Template.Spec.TopologySpreadConstraints)
+}
+
+func (s *statefulSetTest) TestContainerShouldNotSetTopologySpreadConstraintsByDefault() {
+	// given
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	s.Require().Empty(statefulSet.Spec.Template.Spec.TopologySpreadConstraints)
+}
+
+func (s *statefulSetTest) TestContainerShouldReadZoneOfNodeWithZoneAwareness() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"zeebe.zoneAwareness.enabled": "true",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	podSpec := statefulSet.Spec.Template.Spec
+	s.Require().Equal("camunda-platform-test-zeebe", podSpec.ServiceAccountName)
+	s.Require().Len(podSpec.InitContainers, 1)
+	initContainer := podSpec.InitContainers[0]
+	s.Require().Equal("zone", initContainer.Name)
+	s.Require().Equal("bitnami/kubectl:1.26.1", initContainer.Image)
+	s.Require().Len(initContainer.Args, 1)
+	s.Require().Contains(initContainer.Args[0], `kubectl get node "${K8S_NODE_NAME}"`)
+	s.Require().Contains(initContainer.Args[0], "> /usr/local/zeebe/zone/zone")
+	s.Require().Contains(initContainer.Env, corev1.EnvVar{
+		Name:      "K8S_NODE_NAME",
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+	})
+	s.Require().Contains(initContainer.VolumeMounts, corev1.VolumeMount{Name: "zone", MountPath: "/usr/local/zeebe/zone"})
+	s.Require().Contains(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "zone", MountPath: "/usr/local/zeebe/zone", ReadOnly: true})
+	s.Require().Contains(podSpec.Volumes, corev1.Volume{Name: "zone", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+}
+
+func (s *statefulSetTest) TestContainerShouldRequireDedicatedServiceAccountWithZoneAwareness() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"zeebe.zoneAwareness.enabled":  "true",
+			"zeebe.serviceAccount.enabled": "false",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	_, err := helm.RenderTemplateE(s.T(), options, s.chartPath, s.release, s.templates)
+
+	// then
+	s.Require().ErrorContains(err, `[zeebe][constraint] The broker service account has to be enabled or named when "zeebe.zoneAwareness.enabled" is true,`)
+}
+
+func (s *statefulSetTest) TestContainerShouldSetS3BackupStore() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"zeebe.backup.store":             "S3",
+			"zeebe.backup.s3.bucketName":     "camunda-backups",
+			"zeebe.backup.s3.basePath":       "region0",
+			"zeebe.backup.s3.region":         "eu-west-1",
+			"zeebe.backup.s3.existingSecret": "backup-credentials",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	env := statefulSet.Spec.Template.Spec.Containers[0].Env
+	for _, expected := range []corev1.EnvVar{
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_STORE", Value: "S3"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_S3_BUCKETNAME", Value: "camunda-backups"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_S3_BASEPATH", Value: "region0"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_S3_REGION", Value: "eu-west-1"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_S3_FORCEPATHSTYLEACCESS", Value: "false"},
+		{
+			Name: "ZEEBE_BROKER_DATA_BACKUP_S3_ACCESSKEY",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "backup-credentials"},
+					Key:                  "access-key",
+				},
+			},
+		},
+		{
+			Name: "ZEEBE_BROKER_DATA_BACKUP_S3_SECRETKEY",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "backup-credentials"},
+					Key:                  "secret-key",
+				},
+			},
+		},
+	} {
+		s.Require().Contains(env, expected)
+	}
+	for _, envVar := range env {
+		s.Require().NotContains(envVar.Name, "BACKUP_GCS")
+	}
+}
+
+func (s *statefulSetTest) TestContainerShouldSetGcsBackupStoreWithCredentials() {
+	// given
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"zeebe.backup.store":              "GCS",
+			"zeebe.backup.gcs.bucketName":     "camunda-backups",
+			"zeebe.backup.gcs.host":           "http://fake-gcs:4443",
+			"zeebe.backup.gcs.existingSecret": "backup-credentials",
+			"zeebe.image.tag":                 "8.2.0",
+		},
+		KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+	}
+
+	// when
+	output := helm.RenderTemplate(s.T(), options, s.chartPath, s.release, s.templates)
+	var statefulSet appsv1.StatefulSet
+	helm.UnmarshalK8SYaml(s.T(), output, &statefulSet)
+
+	// then
+	podSpec := statefulSet.Spec.Template.Spec
+	env := podSpec.Containers[0].Env
+	for _, expected := range []corev1.EnvVar{
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_STORE", Value: "GCS"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_GCS_BUCKETNAME", Value: "camunda-backups"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_GCS_HOST", Value: "http://fake-gcs:4443"},
+		{Name: "ZEEBE_BROKER_DATA_BACKUP_GCS_AUTH", Value: "auto"},
+		{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: "/usr/local/zeebe/backup-credentials/credentials.json"},
+	} {
+		s.Require().Contains(env, expected)
+	}
+	s.Require().Contains(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "backup-credentials", MountPath: "/usr/local/zeebe/backup-credentials", ReadOnly: true})
+	s.Require().Contains(podSpec.Volumes, corev1.Volume{Name: "backup-credentials", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "backup-credentials"}}})
+}
+
+func (s *statefulSetTest) TestContainerShouldRequireZeebe82ForGcsBackupStore() {
+	for _, testCase := range []struct {
+		tag      string
+		expected string
+	}{
+		{tag: "8.1.8", expected: `[zeebe][constraint] The var "zeebe.backup.store" can only be "GCS" with Zeebe 8.2 or later, but the image tag is "8.1.8".`},
+		{tag: "8.2.0-alpha3"},
+		{tag: "SNAPSHOT"},
+	} {
+		// given
+		options := &helm.Options{
+			SetValues: map[string]string{
+				"zeebe.backup.store":          "GCS",
+				"zeebe.backup.gcs.bucketName": "camunda-backups",
+				"zeebe.image.tag":             testCase.tag,
+			},
+			KubectlOptions: k8s.NewKubectlOptions("", "", s.namespace),
+		}
+
+		// when
+		_, err := helm.RenderTemplateE(s.T(), options, s.chartPath, s.release, s.templates)
+
+		// then
+		if testCase.expected == "" {
+			s.Require().NoError(err, testCase.tag)
+		} else {
+			s.Require().ErrorContains(err, testCase.expected, testCase.tag)
+		}
+	}
+}